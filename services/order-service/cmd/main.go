@@ -9,8 +9,13 @@ import (
 	"time"
 
 	apphttp "github.com/defan6/market/services/order-service/internal/app/http"
+	"github.com/defan6/market/services/order-service/internal/auth"
+	"github.com/defan6/market/services/order-service/internal/carriers"
 	"github.com/defan6/market/services/order-service/internal/config"
 	"github.com/defan6/market/services/order-service/internal/handler"
+	"github.com/defan6/market/services/order-service/internal/outbox"
+	"github.com/defan6/market/services/order-service/internal/payments"
+	"github.com/defan6/market/services/order-service/internal/pricing"
 	"github.com/defan6/market/services/order-service/internal/service"
 	"github.com/defan6/market/services/order-service/internal/storage"
 	database "github.com/defan6/market/services/order-service/storage"
@@ -24,6 +29,20 @@ const (
 	envProd  = "prod"
 )
 
+const (
+	productClientStub = "stub"
+	productClientHTTP = "http"
+)
+
+const paymentProviderFake = "fake"
+
+const carrierFake = "fake"
+
+const (
+	outboxPublisherFile   = "file"
+	outboxPublisherMemory = "memory"
+)
+
 func main() {
 	// load config
 	cfg := config.MustLoad()
@@ -38,17 +57,55 @@ func main() {
 
 	// init layers
 	orderStorage := storage.NewDefaultOrderStorage(db.GetDB())
-	txManager := storage.NewTxManager(db.GetDB())
-	productClient := service.NewStubProductClient() // заглушка до создания product-service
-	orderService := service.NewDefaultOrderService(log, orderStorage, txManager, productClient)
+	txManager := storage.NewTxManager(db.GetDB(), cfg.DB.TxRetry)
+	productClient, statusProviders := setupProductClient(log, cfg.ProductService)
+	inventoryClient := service.NewMemoryInventoryClient(cfg.Inventory) // склад в памяти до появления inventory-service
+	statusProviders = append(statusProviders, inventoryClient)
+	paymentRegistry := setupPaymentProviders(cfg.Payments)
+	carrierRegistry := setupCarriers(cfg.Shipping)
+	pricingEngine, err := pricing.NewRuleEngine(cfg.Pricing)
+	if err != nil {
+		panic(err)
+	}
+	orderService := service.NewDefaultOrderService(log, orderStorage, txManager, productClient, inventoryClient, paymentRegistry, carrierRegistry, pricingEngine, cfg.Idempotency)
 	orderHandler := handler.NewDefaultOrderHandler(log, orderService)
+	cartHandler := handler.NewCartHandler(log, orderService)
+	addressHandler := handler.NewAddressHandler(log, orderService)
+	statusHandler := handler.NewStatusHandler(log, statusProviders...)
 
 	// init router
-	router := handler.NewRouter(orderHandler, log)
+	authenticate := auth.Authenticate(log, auth.NewTokenVerifier(cfg.Auth))
+	router := handler.NewRouter(orderHandler, cartHandler, addressHandler, statusHandler, authenticate, log)
 
 	// init app
 	app := apphttp.New(log, cfg.Server, router)
 
+	// run outbox relay
+	publisher := setupOutboxPublisher(cfg.Outbox)
+	relay := outbox.NewRelay(log, orderStorage, txManager, publisher, cfg.Outbox)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Run(relayCtx)
+	}()
+
+	// run saga recovery: доводит саги, брошенные упавшими процессами
+	sagaCtx, stopSagaRecovery := context.WithCancel(context.Background())
+	sagaDone := make(chan struct{})
+	go func() {
+		defer close(sagaDone)
+		orderService.RunSagaRecovery(sagaCtx, cfg.Saga)
+	}()
+
+	// run shipment tracking: опрашивает перевозчиков и переводит заказы в delivered
+	trackingCtx, stopTracking := context.WithCancel(context.Background())
+	trackingDone := make(chan struct{})
+	go func() {
+		defer close(trackingDone)
+		orderService.RunShipmentTracking(trackingCtx, cfg.Shipping)
+	}()
+
 	// run app
 	go app.MustRun()
 
@@ -66,6 +123,15 @@ func main() {
 		log.Error("failed to stop app", slog.String("err", err.Error()))
 	}
 
+	stopRelay()
+	<-relayDone
+
+	stopSagaRecovery()
+	<-sagaDone
+
+	stopTracking()
+	<-trackingDone
+
 	log.Info("app stopped")
 }
 
@@ -83,6 +149,83 @@ func setupLogger(env string) *slog.Logger {
 	return log
 }
 
+func setupProductClient(log *slog.Logger, cfg config.ProductServiceConfig) (service.ProductClient, []handler.StatusProvider) {
+	var client service.ProductClient
+	var providers []handler.StatusProvider
+
+	switch cfg.Client {
+	case productClientHTTP:
+		resilient := service.NewResilientProductClient(log, service.NewHTTPProductClient(cfg, nil), cfg.Resilience)
+		client = resilient
+		providers = append(providers, resilient.Policy())
+	case productClientStub:
+		client = service.NewStubProductClient() // заглушка до создания product-service
+	default:
+		panic("unknown product client: " + cfg.Client)
+	}
+
+	if cfg.Cache.Enabled {
+		cached := service.NewCachedProductClient(client, cfg.Cache, cfg.Timeout)
+		client = cached
+		providers = append(providers, cached)
+	}
+
+	return client, providers
+}
+
+func setupPaymentProviders(cfg config.PaymentsConfig) *payments.Registry {
+	providers := make([]payments.PaymentProvider, 0, len(cfg.Providers))
+
+	for _, p := range cfg.Providers {
+		switch p.Type {
+		case paymentProviderFake:
+			providers = append(providers, payments.NewFakeProvider(p.Name, p.Methods, p.DeclineAbove))
+		default:
+			panic("unknown payment provider type: " + p.Type)
+		}
+	}
+
+	registry, err := payments.NewRegistry(providers...)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func setupCarriers(cfg config.ShippingConfig) *carriers.Registry {
+	list := make([]carriers.Carrier, 0, len(cfg.Carriers))
+
+	for _, c := range cfg.Carriers {
+		switch c.Type {
+		case carrierFake:
+			list = append(list, carriers.NewFakeCarrier(c.Name, c.InTransitAfter, c.DeliverAfter))
+		default:
+			panic("unknown carrier type: " + c.Type)
+		}
+	}
+
+	registry, err := carriers.NewRegistry(list...)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func setupOutboxPublisher(cfg config.OutboxConfig) outbox.Publisher {
+	switch cfg.Publisher {
+	case outboxPublisherFile:
+		publisher, err := outbox.NewFilePublisher(cfg.FilePath)
+		if err != nil {
+			panic(err)
+		}
+		return publisher
+	case outboxPublisherMemory:
+		return outbox.NewMemoryPublisher()
+	default:
+		panic("unknown outbox publisher: " + cfg.Publisher)
+	}
+}
+
 func setupPrettySlog() *slog.Logger {
 	opts := slogpretty.PrettyHandlerOptions{
 		SlogOptions: &slog.HandlerOptions{Level: slog.LevelDebug},
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS order_price_lines (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    rule VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00
+);
+
+CREATE INDEX idx_order_price_lines_order_id ON order_price_lines(order_id);
@@ -0,0 +1,17 @@
+-- order_id без внешнего ключа: оплата авторизуется до записи заказа под заранее выделенный ID,
+-- и отклонённые попытки остаются, даже если заказ так и не был создан
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    attempt INTEGER NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    method VARCHAR(50) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    refunded DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    status VARCHAR(20) NOT NULL,
+    authorization_id VARCHAR(100),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, attempt)
+);
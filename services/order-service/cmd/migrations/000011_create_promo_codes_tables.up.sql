@@ -0,0 +1,32 @@
+-- kind: percent (rate в базисных пунктах), fixed (amount), free_shipping.
+-- Пустые min_order_total, max_uses, max_uses_per_user и ends_at — без ограничения,
+-- пустой product_ids — код действует на все товары
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    kind VARCHAR(20) NOT NULL,
+    rate INTEGER NOT NULL DEFAULT 0,
+    amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    min_order_total DECIMAL(10, 2),
+    product_ids BIGINT[] NOT NULL DEFAULT '{}',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    max_uses INTEGER,
+    max_uses_per_user INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id),
+    order_id BIGINT NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    discount DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_promo_redemptions_code_user ON promo_redemptions(promo_code_id, user_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_price DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code VARCHAR(50);
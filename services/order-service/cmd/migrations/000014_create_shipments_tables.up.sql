@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier VARCHAR(50) NOT NULL,
+    tracking_number VARCHAR(100) NOT NULL,
+    label_url TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    tracked_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (carrier, tracking_number)
+);
+
+CREATE INDEX idx_shipments_order_id ON shipments(order_id);
+-- опрос перевозчиков идёт только по недоставленным отправлениям
+CREATE INDEX idx_shipments_active ON shipments(tracked_at NULLS FIRST) WHERE status <> 'delivered';
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL
+);
+
+CREATE INDEX idx_shipment_items_shipment_id ON shipment_items(shipment_id);
@@ -0,0 +1,2 @@
+-- версия заказа для оптимистической блокировки: увеличивается при каждом изменении заказа
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- налоговое правило позиции: по нему возврат находит строку налога в order_price_lines
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_rule VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_shipments_pending;
+DELETE FROM shipments WHERE status = 'pending';
+
+DROP INDEX IF EXISTS idx_shipments_tracking_number;
+ALTER TABLE shipments ADD CONSTRAINT shipments_carrier_tracking_number_key UNIQUE (carrier, tracking_number);
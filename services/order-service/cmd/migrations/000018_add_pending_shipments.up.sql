@@ -0,0 +1,8 @@
+-- отправление создаётся в статусе pending без трек-номера, поэтому уникальность трек-номера —
+-- только у отправлений с этикеткой
+ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_carrier_tracking_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipments_tracking_number ON shipments(carrier, tracking_number)
+    WHERE status <> 'pending';
+
+-- фоновый опрос достраивает отправления, застрявшие в pending
+CREATE INDEX IF NOT EXISTS idx_shipments_pending ON shipments(created_at) WHERE status = 'pending';
@@ -4,6 +4,7 @@ go 1.25.6
 
 require (
 	github.com/defan6/market/services/shared v0.0.0-20260217075420-8ae4232a8f25
+	github.com/golang-jwt/jwt/v5 v5.3.1
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 )
 
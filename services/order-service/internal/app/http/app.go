@@ -11,12 +11,12 @@ import (
 
 type App struct {
 	log        *slog.Logger
-	httpServer http.Server
+	httpServer *http.Server
 	port       int
 }
 
 func New(log *slog.Logger, cfg config.HttpConfig, handler http.Handler) *App {
-	httpServer := http.Server{
+	httpServer := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
 		Handler:      handler,
 		ReadTimeout:  cfg.ReadTimeout,
@@ -0,0 +1,12 @@
+package auth
+
+import "github.com/golang-jwt/jwt/v5"
+
+// AccessClaims повторяет claims.AccessClaims из auth-server: имена полей задают ключи в JWT,
+// поэтому менять их можно только вместе с auth-server
+type AccessClaims struct {
+	UserID int64
+	Email  string
+	Role   string
+	jwt.RegisteredClaims
+}
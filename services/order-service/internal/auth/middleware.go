@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+type Verifier interface {
+	Verify(token string) (*AccessClaims, error)
+}
+
+var ErrMissingToken = apperror.New(apperror.CodeUnauthenticated, "missing bearer token")
+
+type actorKey struct{}
+
+// Authenticate пропускает дальше только запросы с действительным bearer-токеном
+// и кладёт пользователя из токена в контекст запроса
+func Authenticate(log *slog.Logger, verifier Verifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "auth.Authenticate"
+
+			token, ok := bearerToken(r)
+			if !ok {
+				apperror.WriteProblem(w, r, ErrMissingToken)
+				return
+			}
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				log.Warn(op, slog.String("error", err.Error()))
+				apperror.WriteProblem(w, r, ErrInvalidToken)
+				return
+			}
+
+			role := claims.Role
+			if role == "" {
+				role = domain.RoleUser
+			}
+
+			ctx := WithActor(r.Context(), domain.Actor{UserID: claims.UserID, Role: role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func WithActor(ctx context.Context, actor domain.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext пользователь, проставленный Authenticate
+func ActorFromContext(ctx context.Context) (domain.Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(domain.Actor)
+	return actor, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
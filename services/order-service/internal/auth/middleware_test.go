@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAuthConfig = config.AuthConfig{Secret: "test-secret", Issuer: "sso-auth-server", AppID: "1"}
+
+// signToken выпускает токен так же, как DefaultTokenGenerator в auth-server
+func signToken(t *testing.T, secret string, mutate func(c *AccessClaims)) string {
+	t.Helper()
+
+	now := time.Now()
+	c := AccessClaims{
+		UserID: 42,
+		Email:  "user@example.com",
+		Role:   domain.RoleUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "sso-auth-server",
+			Subject:   strconv.Itoa(42),
+			Audience:  jwt.ClaimStrings{"1"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(10 * time.Minute)),
+		},
+	}
+	if mutate != nil {
+		mutate(&c)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	var got domain.Actor
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ActorFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := Authenticate(slogdiscard.NewDiscardLogger(), NewTokenVerifier(testAuthConfig))(next)
+
+	tests := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{name: "valid token", header: "Bearer " + signToken(t, "test-secret", nil), code: http.StatusNoContent},
+		{name: "missing header", header: "", code: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic " + signToken(t, "test-secret", nil), code: http.StatusUnauthorized},
+		{name: "wrong secret", header: "Bearer " + signToken(t, "other-secret", nil), code: http.StatusUnauthorized},
+		{
+			name: "wrong issuer",
+			header: "Bearer " + signToken(t, "test-secret", func(c *AccessClaims) {
+				c.Issuer = "someone-else"
+			}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "other app",
+			header: "Bearer " + signToken(t, "test-secret", func(c *AccessClaims) {
+				c.Audience = jwt.ClaimStrings{"2"}
+			}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "expired",
+			header: "Bearer " + signToken(t, "test-secret", func(c *AccessClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			}),
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "no expiry",
+			header: "Bearer " + signToken(t, "test-secret", func(c *AccessClaims) {
+				c.ExpiresAt = nil
+			}),
+			code: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/1", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			if tt.code == http.StatusUnauthorized {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+
+	assert.Equal(t, domain.Actor{UserID: 42, Role: domain.RoleUser}, got)
+}
+
+func TestTokenVerifier_RejectsNoneAlgorithm(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, AccessClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "sso-auth-server",
+			Audience:  jwt.ClaimStrings{"1"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = NewTokenVerifier(testAuthConfig).Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
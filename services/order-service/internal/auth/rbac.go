@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var ErrInsufficientRole = apperror.New(apperror.CodePermissionDenied, "permission denied: insufficient role")
+
+// RoutePolicy роли, которым разрешён маршрут, по ключу "METHOD шаблон" (полный шаблон chi,
+// например "PATCH /api/v1/orders/{id}/status"). Маршрут без записи доступен любому
+// аутентифицированному пользователю.
+type RoutePolicy map[string][]string
+
+// For middleware для маршрута method + pattern
+func (p RoutePolicy) For(method, pattern string) func(next http.Handler) http.Handler {
+	return RequireRoles(p[method+" "+pattern]...)
+}
+
+// RequireRoles пропускает пользователя с одной из ролей; без ролей — любого аутентифицированного.
+// Должен стоять после Authenticate.
+func RequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor, ok := ActorFromContext(r.Context())
+			if !ok {
+				apperror.WriteProblem(w, r, ErrMissingToken)
+				return
+			}
+
+			if len(roles) > 0 && !slices.Contains(roles, actor.Role) {
+				apperror.WriteProblem(w, r, ErrInsufficientRole)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
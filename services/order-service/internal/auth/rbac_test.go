@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutePolicy(t *testing.T) {
+	policy := RoutePolicy{
+		"PATCH /orders/{id}/status": {domain.RoleManager, domain.RoleAdmin},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		pattern string
+		actor   *domain.Actor
+		code    int
+	}{
+		{"user on staff route", http.MethodPatch, "/orders/{id}/status", &domain.Actor{UserID: 1, Role: domain.RoleUser}, http.StatusForbidden},
+		{"manager on staff route", http.MethodPatch, "/orders/{id}/status", &domain.Actor{UserID: 1, Role: domain.RoleManager}, http.StatusNoContent},
+		{"user on open route", http.MethodGet, "/orders/{id}", &domain.Actor{UserID: 1, Role: domain.RoleUser}, http.StatusNoContent},
+		{"anonymous", http.MethodGet, "/orders/{id}", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.actor != nil {
+				req = req.WithContext(WithActor(req.Context(), *tt.actor))
+			}
+			rec := httptest.NewRecorder()
+
+			policy.For(tt.method, tt.pattern)(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			if tt.code != http.StatusNoContent {
+				assert.Contains(t, rec.Body.String(), `"error"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = apperror.New(apperror.CodeUnauthenticated, "invalid token")
+
+// TokenVerifier проверяет access-токены, выпущенные auth-server (HS256):
+// подпись, издателя, аудиторию (ID приложения) и срок действия
+type TokenVerifier struct {
+	secret []byte
+	parser *jwt.Parser
+}
+
+func NewTokenVerifier(cfg config.AuthConfig) *TokenVerifier {
+	return &TokenVerifier{
+		secret: []byte(cfg.Secret),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.AppID),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(cfg.Leeway),
+		),
+	}
+}
+
+func (v *TokenVerifier) Verify(token string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		return v.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if claims.UserID <= 0 {
+		return nil, fmt.Errorf("%w: missing user id", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,47 @@
+package carriers
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var (
+	ErrUnknownCarrier  = apperror.New(apperror.CodeInvalidArgument, "unknown carrier")
+	ErrUnknownShipment = apperror.New(apperror.CodeNotFound, "shipment not found at carrier")
+)
+
+// Carrier служба доставки. CreateLabel регистрирует отправление и возвращает трек-номер;
+// повтор с тем же IdempotencyKey возвращает ту же этикетку. Track возвращает текущий статус
+// отправления (domain.ShipmentStatus*).
+type Carrier interface {
+	Name() string
+	CreateLabel(ctx context.Context, req LabelRequest) (*Label, error)
+	Track(ctx context.Context, trackingNumber string) (*Tracking, error)
+}
+
+type LabelRequest struct {
+	OrderID        int64
+	Address        domain.Address
+	Items          []LabelItem
+	IdempotencyKey string
+}
+
+type LabelItem struct {
+	ProductID int64
+	Name      string
+	Quantity  int64
+}
+
+type Label struct {
+	TrackingNumber string
+	LabelURL       string
+}
+
+// Tracking UpdatedAt — когда перевозчик зафиксировал статус
+type Tracking struct {
+	Status    string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,77 @@
+package carriers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// FakeCarrier перевозчик в памяти для локального запуска и тестов: отправление уходит в путь
+// через inTransitAfter и доставляется через deliverAfter после создания этикетки
+type FakeCarrier struct {
+	name           string
+	inTransitAfter time.Duration
+	deliverAfter   time.Duration
+	now            func() time.Time
+
+	mu            sync.Mutex
+	labels        map[string]time.Time
+	byIdempotency map[string]string
+}
+
+func NewFakeCarrier(name string, inTransitAfter, deliverAfter time.Duration) *FakeCarrier {
+	return &FakeCarrier{
+		name:           name,
+		inTransitAfter: inTransitAfter,
+		deliverAfter:   deliverAfter,
+		now:            time.Now,
+		labels:         make(map[string]time.Time),
+		byIdempotency:  make(map[string]string),
+	}
+}
+
+func (c *FakeCarrier) Name() string {
+	return c.name
+}
+
+func (c *FakeCarrier) CreateLabel(ctx context.Context, req LabelRequest) (*Label, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	number, ok := c.byIdempotency[req.IdempotencyKey]
+	if !ok {
+		number = fmt.Sprintf("%s-%06d", c.name, len(c.labels)+1)
+		c.labels[number] = c.now()
+		c.byIdempotency[req.IdempotencyKey] = number
+	}
+
+	return &Label{
+		TrackingNumber: number,
+		LabelURL:       fmt.Sprintf("https://labels.invalid/%s.pdf", number),
+	}, nil
+}
+
+func (c *FakeCarrier) Track(ctx context.Context, trackingNumber string) (*Tracking, error) {
+	const op = "carriers.FakeCarrier.Track"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	createdAt, ok := c.labels[trackingNumber]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownShipment, trackingNumber)
+	}
+
+	elapsed := c.now().Sub(createdAt)
+	switch {
+	case elapsed >= c.deliverAfter:
+		return &Tracking{Status: domain.ShipmentStatusDelivered, UpdatedAt: createdAt.Add(c.deliverAfter)}, nil
+	case elapsed >= c.inTransitAfter:
+		return &Tracking{Status: domain.ShipmentStatusInTransit, UpdatedAt: createdAt.Add(c.inTransitAfter)}, nil
+	default:
+		return &Tracking{Status: domain.ShipmentStatusLabelCreated, UpdatedAt: createdAt}, nil
+	}
+}
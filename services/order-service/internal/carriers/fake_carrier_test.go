@@ -0,0 +1,59 @@
+package carriers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCarrier_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	c := NewFakeCarrier("fake", time.Hour, 24*time.Hour)
+	c.now = func() time.Time { return now }
+
+	label, err := c.CreateLabel(ctx, LabelRequest{OrderID: 1, IdempotencyKey: "1"})
+	require.NoError(t, err)
+
+	// повтор с тем же ключом возвращает ту же этикетку
+	again, err := c.CreateLabel(ctx, LabelRequest{OrderID: 1, IdempotencyKey: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, label, again)
+
+	for _, step := range []struct {
+		after  time.Duration
+		status string
+	}{
+		{0, domain.ShipmentStatusLabelCreated},
+		{time.Hour, domain.ShipmentStatusInTransit},
+		{24 * time.Hour, domain.ShipmentStatusDelivered},
+	} {
+		now = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC).Add(step.after)
+		tracking, err := c.Track(ctx, label.TrackingNumber)
+		require.NoError(t, err)
+		assert.Equal(t, step.status, tracking.Status)
+	}
+
+	_, err = c.Track(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownShipment)
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(NewFakeCarrier("b", 0, 0), NewFakeCarrier("a", 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, r.Names())
+
+	carrier, err := r.Carrier("a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", carrier.Name())
+
+	_, err = r.Carrier("c")
+	assert.ErrorIs(t, err, ErrUnknownCarrier)
+
+	_, err = NewRegistry(NewFakeCarrier("a", 0, 0), NewFakeCarrier("a", 0, 0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package carriers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+// Registry выбирает перевозчика по имени
+type Registry struct {
+	byName map[string]Carrier
+}
+
+func NewRegistry(carriers ...Carrier) (*Registry, error) {
+	r := &Registry{byName: make(map[string]Carrier, len(carriers))}
+
+	for _, carrier := range carriers {
+		if _, ok := r.byName[carrier.Name()]; ok {
+			return nil, fmt.Errorf("carrier %q is configured twice", carrier.Name())
+		}
+		r.byName[carrier.Name()] = carrier
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Carrier(name string) (Carrier, error) {
+	carrier, ok := r.byName[name]
+	if !ok {
+		return nil, ErrUnknownCarrier.WithFields(apperror.Field(
+			"carrier", fmt.Sprintf("supported carriers: %v", r.Names()),
+		))
+	}
+	return carrier, nil
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
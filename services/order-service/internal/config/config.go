@@ -5,14 +5,24 @@ import (
 	"os"
 	"time"
 
+	"github.com/defan6/market/services/order-service/internal/lib/money"
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env     string        `yaml:"env" env-default:"local"`
-	Server  HttpConfig    `yaml:"server"`
-	DB      DBConfig      `yaml:"db"`
-	Logging LoggingConfig `yaml:"logging"`
+	Env            string               `yaml:"env" env-default:"local"`
+	Server         HttpConfig           `yaml:"server"`
+	DB             DBConfig             `yaml:"db"`
+	Logging        LoggingConfig        `yaml:"logging"`
+	Pricing        PricingConfig        `yaml:"pricing"`
+	ProductService ProductServiceConfig `yaml:"product_service"`
+	Idempotency    IdempotencyConfig    `yaml:"idempotency"`
+	Outbox         OutboxConfig         `yaml:"outbox"`
+	Auth           AuthConfig           `yaml:"auth"`
+	Inventory      InventoryConfig      `yaml:"inventory"`
+	Saga           SagaConfig           `yaml:"saga"`
+	Payments       PaymentsConfig       `yaml:"payments"`
+	Shipping       ShippingConfig       `yaml:"shipping"`
 }
 
 type HttpConfig struct {
@@ -30,12 +40,154 @@ type DBConfig struct {
 	User     string `yaml:"user" env-default:"postgres"`
 	Password string `yaml:"password" env-default:"postgres"`
 	SSLMode  string `yaml:"sslmode" env-default:"disable"`
+
+	TxRetry TxRetryConfig `yaml:"tx_retry"`
+}
+
+// TxRetryConfig транзакция, прерванная конфликтом сериализации или взаимоблокировкой,
+// повторяется до MaxAttempts раз с задержкой от BaseDelay, растущей до MaxDelay
+type TxRetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" env-default:"3"`
+	BaseDelay   time.Duration `yaml:"base_delay" env-default:"10ms"`
+	MaxDelay    time.Duration `yaml:"max_delay" env-default:"200ms"`
 }
 
 type LoggingConfig struct {
 	Level string `yaml:"level" env-default:"info"`
 }
 
+// AuthConfig проверка access-токенов auth-server: Secret и Issuer должны совпадать с его настройками token,
+// AppID — ID приложения order-service, который auth-server кладёт в audience
+type AuthConfig struct {
+	Secret string        `yaml:"secret" env-required:"true"`
+	Issuer string        `yaml:"issuer" env-default:"sso-auth-server"`
+	AppID  string        `yaml:"app_id" env-required:"true"`
+	Leeway time.Duration `yaml:"leeway" env-default:"30s"`
+}
+
+// IdempotencyConfig TTL: сколько хранится ответ на запрос с Idempotency-Key
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env-default:"24h"`
+}
+
+// InventoryConfig склад в памяти: Stock — остатки по ID товара, для остальных товаров DefaultStock.
+// Неподтверждённый резерв снимается через ReservationTTL.
+type InventoryConfig struct {
+	ReservationTTL time.Duration   `yaml:"reservation_ttl" env-default:"15m"`
+	DefaultStock   int64           `yaml:"default_stock" env-default:"100"`
+	Stock          map[int64]int64 `yaml:"stock"`
+}
+
+// PaymentsConfig принимаются только способы оплаты, перечисленные у провайдеров
+type PaymentsConfig struct {
+	Providers []PaymentProviderConfig `yaml:"providers"`
+}
+
+// PaymentProviderConfig Type: "fake" — провайдер в памяти, одобряет платежи не больше DeclineAbove
+type PaymentProviderConfig struct {
+	Name         string       `yaml:"name"`
+	Type         string       `yaml:"type"`
+	Methods      []string     `yaml:"methods"`
+	DeclineAbove *money.Money `yaml:"decline_above"`
+}
+
+// ShippingConfig недоставленные отправления опрашиваются у перевозчиков каждые PollInterval пачками по BatchSize.
+// Отправление, оставшееся без этикетки дольше PendingTimeout (процесс упал между резервированием позиций
+// и записью этикетки), достраивается тем же опросом.
+type ShippingConfig struct {
+	Carriers       []CarrierConfig `yaml:"carriers"`
+	PollInterval   time.Duration   `yaml:"poll_interval" env-default:"1m"`
+	BatchSize      int             `yaml:"batch_size" env-default:"100"`
+	PendingTimeout time.Duration   `yaml:"pending_timeout" env-default:"5m"`
+}
+
+// CarrierConfig Type: "fake" — перевозчик в памяти, отправление уходит в путь через InTransitAfter
+// и доставляется через DeliverAfter после создания этикетки
+type CarrierConfig struct {
+	Name           string        `yaml:"name"`
+	Type           string        `yaml:"type"`
+	InTransitAfter time.Duration `yaml:"in_transit_after"`
+	DeliverAfter   time.Duration `yaml:"deliver_after"`
+}
+
+// SagaConfig незавершённая сага, не обновлявшаяся StaleAfter, считается брошенной упавшим процессом;
+// такие саги ищутся каждые RecoveryInterval пачками по BatchSize
+type SagaConfig struct {
+	StaleAfter       time.Duration `yaml:"stale_after" env-default:"5m"`
+	RecoveryInterval time.Duration `yaml:"recovery_interval" env-default:"1m"`
+	BatchSize        int           `yaml:"batch_size" env-default:"100"`
+}
+
+// OutboxConfig Publisher: "file" — события пишутся в FilePath по одному JSON на строку,
+// "memory" — события остаются в памяти процесса (только для локального запуска).
+// Неудачная отправка повторяется с задержкой от RetryBaseDelay, удваивающейся до RetryMaxDelay;
+// после MaxAttempts попыток событие помечается мёртвым.
+type OutboxConfig struct {
+	Publisher      string        `yaml:"publisher" env-default:"file"`
+	FilePath       string        `yaml:"file_path" env-default:"outbox_events.jsonl"`
+	PollInterval   time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize      int           `yaml:"batch_size" env-default:"100"`
+	MaxAttempts    int           `yaml:"max_attempts" env-default:"10"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" env-default:"1s"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay" env-default:"5m"`
+}
+
+// ProductServiceConfig Client: "stub" — фейковые товары для локальной разработки, "http" — product-service
+type ProductServiceConfig struct {
+	Client    string        `yaml:"client" env-default:"stub"`
+	BaseURL   string        `yaml:"base_url"`
+	Timeout   time.Duration `yaml:"timeout" env-default:"3s"`
+	BatchSize int           `yaml:"batch_size" env-default:"50"`
+
+	Resilience ResilienceConfig   `yaml:"resilience"`
+	Cache      ProductCacheConfig `yaml:"cache"`
+}
+
+// ProductCacheConfig CacheStock: кэшировать ли остатки; если нет — из кэша берутся только название, картинка и цена
+type ProductCacheConfig struct {
+	Enabled    bool          `yaml:"enabled" env-default:"false"`
+	TTL        time.Duration `yaml:"ttl" env-default:"1m"`
+	MaxSize    int           `yaml:"max_size" env-default:"10000"`
+	CacheStock bool          `yaml:"cache_stock" env-default:"false"`
+}
+
+// ResilienceConfig настройки защиты исходящих вызовов: повторы, предохранитель, ограничение параллелизма
+type ResilienceConfig struct {
+	MaxAttempts      int           `yaml:"max_attempts" env-default:"3"`
+	BaseDelay        time.Duration `yaml:"base_delay" env-default:"100ms"`
+	MaxDelay         time.Duration `yaml:"max_delay" env-default:"1s"`
+	FailureThreshold int           `yaml:"failure_threshold" env-default:"5"`
+	OpenTimeout      time.Duration `yaml:"open_timeout" env-default:"30s"`
+	HalfOpenProbes   int           `yaml:"half_open_probes" env-default:"1"`
+	MaxConcurrent    int           `yaml:"max_concurrent" env-default:"20"`
+	WaitTimeout      time.Duration `yaml:"wait_timeout" env-default:"100ms"`
+}
+
+type PricingConfig struct {
+	DefaultRegion string               `yaml:"default_region" env-default:"RU"`
+	TaxRounding   string               `yaml:"tax_rounding" env-default:"per_order"`
+	TaxRules      []TaxRuleConfig      `yaml:"tax_rules"`
+	ShippingRules []ShippingRuleConfig `yaml:"shipping_rules"`
+}
+
+// TaxRuleConfig пустые Region/Category означают «любой»; выбирается самое точное правило
+type TaxRuleConfig struct {
+	Name     string     `yaml:"name"`
+	Region   string     `yaml:"region"`
+	Category string     `yaml:"category"`
+	Rate     money.Rate `yaml:"rate"`
+}
+
+// ShippingRuleConfig применяется первое подходящее правило в порядке объявления
+type ShippingRuleConfig struct {
+	Name           string       `yaml:"name"`
+	Region         string       `yaml:"region"`
+	MaxWeightGrams int64        `yaml:"max_weight_grams"`
+	MinItemsPrice  *money.Money `yaml:"min_items_price"`
+	Price          money.Money  `yaml:"price"`
+	FreeFrom       *money.Money `yaml:"free_from"`
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 
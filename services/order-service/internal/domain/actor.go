@@ -0,0 +1,23 @@
+package domain
+
+const (
+	RoleUser    = "user"
+	RoleManager = "manager"
+	RoleAdmin   = "admin"
+)
+
+// Actor пользователь, от имени которого выполняется операция
+type Actor struct {
+	UserID int64
+	Role   string
+}
+
+// IsStaff сотрудники могут работать с чужими заказами
+func (a Actor) IsStaff() bool {
+	return a.Role == RoleManager || a.Role == RoleAdmin
+}
+
+// CanAccessOrder владелец заказа или сотрудник
+func (a Actor) CanAccessOrder(order *Order) bool {
+	return a.IsStaff() || a.UserID == order.UserID
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var ErrInvalidAddress = apperror.New(apperror.CodeInvalidArgument, "invalid address")
+
+// maxAddressFieldLength ограничение длины каждого поля адреса в символах
+const maxAddressFieldLength = 200
+
+// Address адрес доставки или оплаты. Country — код страны ISO 3166-1 alpha-2, по нему
+// выбираются налоги и тарифы доставки. В БД хранится одним JSONB-значением.
+type Address struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone,omitempty"`
+	Country    string `json:"country"`
+	Region     string `json:"region,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+}
+
+// Normalize убирает пробелы по краям полей и приводит код страны к верхнему регистру
+func (a *Address) Normalize() {
+	for _, field := range []*string{&a.Recipient, &a.Phone, &a.Country, &a.Region, &a.City, &a.PostalCode, &a.Line1, &a.Line2} {
+		*field = strings.TrimSpace(*field)
+	}
+	a.Country = strings.ToUpper(a.Country)
+}
+
+// Validate проверяет обязательные поля; ошибки полей получают префикс prefix (например, shipping_address)
+func (a Address) Validate(prefix string) error {
+	var fields []apperror.FieldError
+
+	for _, f := range []struct {
+		name     string
+		value    string
+		required bool
+	}{
+		{"recipient", a.Recipient, true},
+		{"phone", a.Phone, false},
+		{"country", a.Country, true},
+		{"region", a.Region, false},
+		{"city", a.City, true},
+		{"postal_code", a.PostalCode, true},
+		{"line1", a.Line1, true},
+		{"line2", a.Line2, false},
+	} {
+		switch {
+		case f.required && f.value == "":
+			fields = append(fields, apperror.Field(prefix+"."+f.name, "must not be empty"))
+		case utf8.RuneCountInString(f.value) > maxAddressFieldLength:
+			fields = append(fields, apperror.Field(prefix+"."+f.name, fmt.Sprintf("must be at most %d characters", maxAddressFieldLength)))
+		}
+	}
+
+	if a.Country != "" && !isCountryCode(a.Country) {
+		fields = append(fields, apperror.Field(prefix+".country", "must be an ISO 3166-1 alpha-2 code"))
+	}
+
+	if len(fields) > 0 {
+		return ErrInvalidAddress.WithFields(fields...)
+	}
+	return nil
+}
+
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// Value сохраняет адрес в JSONB-колонку
+func (a Address) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan читает адрес из JSONB-колонки
+func (a *Address) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("address: unsupported type %T", src)
+	}
+}
+
+// SavedAddress адрес из адресной книги пользователя; Label — название для выбора («Дом», «Работа»)
+type SavedAddress struct {
+	ID        int64     `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Label     string    `db:"label"`
+	Address   Address   `db:"address"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// CartItem товар в корзине пользователя. Price — цена на момент последнего добавления или
+// проверки: по ней видно, что цена в product-service с тех пор изменилась.
+type CartItem struct {
+	UserID    int64       `db:"user_id"`
+	ProductID int64       `db:"product_id"`
+	Quantity  int64       `db:"quantity"`
+	Price     money.Money `db:"price"`
+	CreatedAt time.Time   `db:"created_at"`
+	UpdatedAt time.Time   `db:"updated_at"`
+}
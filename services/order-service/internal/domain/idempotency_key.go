@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// IdempotencyKey сохранённый ответ на первый запрос с заголовком Idempotency-Key.
+// Ключ уникален в пределах пользователя и действует до ExpiresAt.
+type IdempotencyKey struct {
+	UserID       int64     `db:"user_id"`
+	Key          string    `db:"key"`
+	RequestHash  string    `db:"request_hash"`
+	OrderID      int64     `db:"order_id"`
+	StatusCode   int       `db:"status_code"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
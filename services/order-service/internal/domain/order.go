@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
 
 const (
 	OrderStatusPending    = "pending"
@@ -10,15 +15,26 @@ const (
 	OrderStatusCancelled  = "cancelled"
 )
 
+// ErrOrderVersionMismatch заказ изменили после того, как клиент его прочитал
+var ErrOrderVersionMismatch = apperror.New(apperror.CodeFailedPrecondition, "order was modified by another request")
+
+// Order Version увеличивается при каждом изменении заказа и служит его ETag
 type Order struct {
-	ID            int64      `db:"id"`
-	PaymentMethod string     `db:"payment_method"`
-	TaxPrice      float64    `db:"tax_price"`
-	ShippingPrice float64    `db:"shipping_price"`
-	TotalPrice    float64    `db:"total_price"`
-	UserID        int64      `db:"user_id"`
-	Status        string     `db:"status"`
-	CreatedAt     time.Time  `db:"created_at"`
-	UpdatedAt     *time.Time `db:"updated_at"`
-	Items         []*OrderItem
+	ID                 int64       `db:"id"`
+	PaymentMethod      string      `db:"payment_method"`
+	DiscountPrice      money.Money `db:"discount_price"`
+	TaxPrice           money.Money `db:"tax_price"`
+	ShippingPrice      money.Money `db:"shipping_price"`
+	TotalPrice         money.Money `db:"total_price"`
+	UserID             int64       `db:"user_id"`
+	Status             string      `db:"status"`
+	CreatedAt          time.Time   `db:"created_at"`
+	UpdatedAt          *time.Time  `db:"updated_at"`
+	CancellationReason *string     `db:"cancellation_reason"`
+	PromoCode          *string     `db:"promo_code"`
+	ShippingAddress    *Address    `db:"shipping_address"`
+	BillingAddress     *Address    `db:"billing_address"`
+	Version            int64       `db:"version"`
+	Items              []*OrderItem
+	PriceLines         []*OrderPriceLine
 }
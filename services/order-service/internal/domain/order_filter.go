@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+const (
+	OrderSortByCreatedAt  = "created_at"
+	OrderSortByTotalPrice = "total_price"
+)
+
+// OrderFilter условия выборки списка заказов с keyset-пагинацией
+type OrderFilter struct {
+	UserID      *int64
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	Desc        bool
+	Limit       int
+	After       *OrderCursor
+}
+
+// OrderCursor позиция последнего заказа на предыдущей странице.
+// Заполняется только значение поля, по которому идёт сортировка, и ID.
+type OrderCursor struct {
+	CreatedAt  time.Time
+	TotalPrice money.Money
+	ID         int64
+}
@@ -1,11 +1,15 @@
 package domain
 
+import "github.com/defan6/market/services/order-service/internal/lib/money"
+
 type OrderItem struct {
-	ID        int64   `db:"id"`
-	Name      string  `db:"name"`
-	Quantity  int64   `db:"quantity"`
-	Image     string  `db:"image"`
-	Price     float64 `db:"price"`
-	ProductID int64   `db:"product_id"`
-	OrderID   int64   `db:"order_id"`
+	ID        int64       `db:"id"`
+	Name      string      `db:"name"`
+	Quantity  int64       `db:"quantity"`
+	Image     string      `db:"image"`
+	Price     money.Money `db:"price"`
+	ProductID int64       `db:"product_id"`
+	OrderID   int64       `db:"order_id"`
+	// TaxRule налоговое правило, применённое к позиции (Rule строки налога); пусто — без налога
+	TaxRule string `db:"tax_rule"`
 }
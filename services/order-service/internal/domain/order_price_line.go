@@ -0,0 +1,20 @@
+package domain
+
+import "github.com/defan6/market/services/order-service/internal/lib/money"
+
+const (
+	PriceLineKindTax      = "tax"
+	PriceLineKindShipping = "shipping"
+	PriceLineKindDiscount = "discount"
+)
+
+// OrderPriceLine строка расчёта цены заказа: какое правило применено и на какую сумму.
+// Сохраняется вместе с заказом, чтобы изменение правил не меняло цены старых заказов.
+type OrderPriceLine struct {
+	ID          int64       `db:"id"`
+	OrderID     int64       `db:"order_id"`
+	Kind        string      `db:"kind"`
+	Rule        string      `db:"rule"`
+	Description string      `db:"description"`
+	Amount      money.Money `db:"amount"`
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var (
+	ErrUnknownOrderStatus         = apperror.New(apperror.CodeInvalidArgument, "unknown order status")
+	ErrInvalidStatusTransition    = apperror.New(apperror.CodeConflict, "invalid order status transition")
+	ErrOrderAccessDenied          = apperror.New(apperror.CodePermissionDenied, "access to order denied")
+	ErrCancellationReasonRequired = apperror.New(apperror.CodeInvalidArgument, "cancellation reason is required")
+)
+
+// orderTransitions допустимые переходы статусов заказа.
+// delivered и cancelled — конечные состояния.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered},
+	OrderStatusDelivered:  {},
+	OrderStatusCancelled:  {},
+}
+
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+func CanTransition(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo переводит заказ в новый статус и возвращает запись для истории.
+// changedBy равен nil для системных переходов.
+func (o *Order) TransitionTo(status string, changedBy *int64, comment string, at time.Time) (*OrderStatusHistory, error) {
+	if !IsValidOrderStatus(status) {
+		return nil, ErrUnknownOrderStatus.WithFields(apperror.Field("status", fmt.Sprintf("unknown status %q", status)))
+	}
+
+	if !CanTransition(o.Status, status) {
+		return nil, ErrInvalidStatusTransition.WithFields(
+			apperror.Field("status", fmt.Sprintf("cannot change status from %s to %s", o.Status, status)),
+		)
+	}
+
+	// при отмене комментарий обязателен и сохраняется как причина отмены
+	if status == OrderStatusCancelled {
+		if comment == "" {
+			return nil, ErrCancellationReasonRequired
+		}
+		o.CancellationReason = &comment
+	}
+
+	history := &OrderStatusHistory{
+		OrderID:    o.ID,
+		FromStatus: o.Status,
+		ToStatus:   status,
+		ChangedBy:  changedBy,
+		Comment:    comment,
+		ChangedAt:  at,
+	}
+
+	o.Status = status
+	o.UpdatedAt = &at
+
+	return history, nil
+}
+
+// Cancel отменяет заказ от имени actor. Отменить можно только свой заказ,
+// сотрудники могут отменять любые; допустимые исходные статусы задаёт orderTransitions.
+func (o *Order) Cancel(actor Actor, reason string, at time.Time) (*OrderStatusHistory, error) {
+	if !actor.CanAccessOrder(o) {
+		return nil, ErrOrderAccessDenied
+	}
+
+	return o.TransitionTo(OrderStatusCancelled, &actor.UserID, reason, at)
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type OrderStatusHistory struct {
+	ID         int64     `db:"id"`
+	OrderID    int64     `db:"order_id"`
+	FromStatus string    `db:"from_status"`
+	ToStatus   string    `db:"to_status"`
+	ChangedBy  *int64    `db:"changed_by"`
+	Comment    string    `db:"comment"`
+	ChangedAt  time.Time `db:"changed_at"`
+}
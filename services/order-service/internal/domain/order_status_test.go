@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrder_TransitionTo(t *testing.T) {
+	changedBy := int64(7)
+	at := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+
+	order := &Order{ID: 1, Status: OrderStatusPending}
+
+	history, err := order.TransitionTo(OrderStatusProcessing, &changedBy, "paid", at)
+	require.NoError(t, err)
+
+	assert.Equal(t, OrderStatusProcessing, order.Status)
+	require.NotNil(t, order.UpdatedAt)
+	assert.Equal(t, at, *order.UpdatedAt)
+	assert.Equal(t, &OrderStatusHistory{
+		OrderID:    1,
+		FromStatus: OrderStatusPending,
+		ToStatus:   OrderStatusProcessing,
+		ChangedBy:  &changedBy,
+		Comment:    "paid",
+		ChangedAt:  at,
+	}, history)
+}
+
+func TestOrder_TransitionTo_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{name: "delivered to pending", from: OrderStatusDelivered, to: OrderStatusPending, wantErr: ErrInvalidStatusTransition},
+		{name: "cancelled is final", from: OrderStatusCancelled, to: OrderStatusProcessing, wantErr: ErrInvalidStatusTransition},
+		{name: "skip processing", from: OrderStatusPending, to: OrderStatusShipped, wantErr: ErrInvalidStatusTransition},
+		{name: "shipped cannot be cancelled", from: OrderStatusShipped, to: OrderStatusCancelled, wantErr: ErrInvalidStatusTransition},
+		{name: "same status", from: OrderStatusPending, to: OrderStatusPending, wantErr: ErrInvalidStatusTransition},
+		{name: "unknown status", from: OrderStatusPending, to: "lost", wantErr: ErrUnknownOrderStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{Status: tt.from}
+
+			_, err := order.TransitionTo(tt.to, nil, "", time.Now())
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.from, order.Status)
+			assert.Nil(t, order.UpdatedAt)
+		})
+	}
+}
+
+func TestOrder_Cancel(t *testing.T) {
+	owner := Actor{UserID: 1, Role: RoleUser}
+	stranger := Actor{UserID: 2, Role: RoleUser}
+	manager := Actor{UserID: 3, Role: RoleManager}
+
+	tests := []struct {
+		name    string
+		status  string
+		actor   Actor
+		reason  string
+		wantErr error
+	}{
+		{name: "owner cancels pending", status: OrderStatusPending, actor: owner, reason: "changed my mind"},
+		{name: "manager cancels processing", status: OrderStatusProcessing, actor: manager, reason: "out of stock"},
+		{name: "stranger", status: OrderStatusPending, actor: stranger, reason: "x", wantErr: ErrOrderAccessDenied},
+		{name: "shipped", status: OrderStatusShipped, actor: owner, reason: "late", wantErr: ErrInvalidStatusTransition},
+		{name: "no reason", status: OrderStatusPending, actor: owner, wantErr: ErrCancellationReasonRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &Order{ID: 10, UserID: owner.UserID, Status: tt.status}
+
+			history, err := order.Cancel(tt.actor, tt.reason, time.Now())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.status, order.Status)
+				assert.Nil(t, order.CancellationReason)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, OrderStatusCancelled, order.Status)
+			require.NotNil(t, order.CancellationReason)
+			assert.Equal(t, tt.reason, *order.CancellationReason)
+			assert.Equal(t, tt.actor.UserID, *history.ChangedBy)
+		})
+	}
+}
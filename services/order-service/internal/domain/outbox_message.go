@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+const AggregateTypeOrder = "order"
+
+const (
+	EventOrderCreated       = "OrderCreated"
+	EventOrderStatusChanged = "OrderStatusChanged"
+	EventOrderCancelled     = "OrderCancelled"
+)
+
+// OutboxMessage событие, записанное в той же транзакции, что и изменение заказа.
+// Публикуется фоновым релеем; PublishedAt пуст, пока событие не доставлено. После неудачной
+// отправки событие ждёт NextAttemptAt, а исчерпав попытки, получает DeadAt и больше не отправляется.
+type OutboxMessage struct {
+	ID            int64      `db:"id"`
+	AggregateType string     `db:"aggregate_type"`
+	AggregateID   int64      `db:"aggregate_id"`
+	EventType     string     `db:"event_type"`
+	Payload       []byte     `db:"payload"`
+	CreatedAt     time.Time  `db:"created_at"`
+	PublishedAt   *time.Time `db:"published_at"`
+	Attempts      int        `db:"attempts"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt *time.Time `db:"next_attempt_at"`
+	DeadAt        *time.Time `db:"dead_at"`
+}
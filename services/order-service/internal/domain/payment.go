@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusRefunded   = "refunded"
+	PaymentStatusFailed     = "failed"
+)
+
+var (
+	ErrOrderNotPaid       = apperror.New(apperror.CodeConflict, "order payment is not authorized")
+	ErrPaymentNotCaptured = apperror.New(apperror.CodeConflict, "order payment is not captured")
+)
+
+// Payment попытка оплаты заказа. Каждая попытка — отдельная запись с номером Attempt;
+// AuthorizationID — ID авторизации у провайдера, пуст, пока провайдер не одобрил платёж.
+type Payment struct {
+	ID              int64       `db:"id"`
+	OrderID         int64       `db:"order_id"`
+	Attempt         int         `db:"attempt"`
+	Provider        string      `db:"provider"`
+	Method          string      `db:"method"`
+	Amount          money.Money `db:"amount"`
+	Refunded        money.Money `db:"refunded"`
+	Status          string      `db:"status"`
+	AuthorizationID *string     `db:"authorization_id"`
+	LastError       *string     `db:"last_error"`
+	CreatedAt       time.Time   `db:"created_at"`
+	UpdatedAt       time.Time   `db:"updated_at"`
+}
+
+// IsAuthorized деньги заблокированы или уже списаны
+func (p *Payment) IsAuthorized() bool {
+	return p.Status == PaymentStatusAuthorized || p.Status == PaymentStatusCaptured
+}
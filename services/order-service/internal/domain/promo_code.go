@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+const (
+	PromoKindPercent      = "percent"
+	PromoKindFixed        = "fixed"
+	PromoKindFreeShipping = "free_shipping"
+)
+
+var (
+	ErrPromoCodeNotFound      = apperror.New(apperror.CodeUnprocessable, "promo code not found")
+	ErrPromoCodeInactive      = apperror.New(apperror.CodeUnprocessable, "promo code is not active")
+	ErrPromoCodeNotApplicable = apperror.New(apperror.CodeUnprocessable, "promo code does not apply to order")
+	ErrPromoCodeExhausted     = apperror.New(apperror.CodeConflict, "promo code usage limit reached")
+)
+
+// PromoCode код скидки. Rate — скидка kind=percent, Amount — kind=fixed; kind=free_shipping
+// обнуляет доставку. ProductIDs ограничивает скидку товарами (пусто — все товары),
+// MinOrderTotal сравнивается со стоимостью позиций до скидки. Uses — число погашений.
+type PromoCode struct {
+	ID             int64        `db:"id"`
+	Code           string       `db:"code"`
+	Kind           string       `db:"kind"`
+	Rate           money.Rate   `db:"rate"`
+	Amount         money.Money  `db:"amount"`
+	MinOrderTotal  *money.Money `db:"min_order_total"`
+	ProductIDs     []int64      `db:"-"`
+	StartsAt       time.Time    `db:"starts_at"`
+	EndsAt         *time.Time   `db:"ends_at"`
+	MaxUses        *int64       `db:"max_uses"`
+	MaxUsesPerUser *int64       `db:"max_uses_per_user"`
+	Uses           int64        `db:"uses"`
+	CreatedAt      time.Time    `db:"created_at"`
+}
+
+// PromoRedemption погашение кода заказом; у заказа не больше одного погашения
+type PromoRedemption struct {
+	ID          int64       `db:"id"`
+	PromoCodeID int64       `db:"promo_code_id"`
+	OrderID     int64       `db:"order_id"`
+	UserID      int64       `db:"user_id"`
+	Discount    money.Money `db:"discount"`
+	CreatedAt   time.Time   `db:"created_at"`
+}
+
+// AppliesTo действует ли код на товар
+func (p *PromoCode) AppliesTo(productID int64) bool {
+	return len(p.ProductIDs) == 0 || slices.Contains(p.ProductIDs, productID)
+}
+
+// CheckOrder проверяет срок действия кода, минимальную сумму и что в заказе есть товар, на который он действует
+func (p *PromoCode) CheckOrder(at time.Time, itemsPrice money.Money, productIDs []int64) error {
+	if at.Before(p.StartsAt) || p.EndsAt != nil && !at.Before(*p.EndsAt) {
+		return ErrPromoCodeInactive.WithFields(apperror.Field("promo_code", fmt.Sprintf("code %s is not active now", p.Code)))
+	}
+
+	if p.MinOrderTotal != nil && itemsPrice.Cmp(*p.MinOrderTotal) < 0 {
+		return ErrPromoCodeNotApplicable.WithFields(
+			apperror.Field("promo_code", fmt.Sprintf("order total must be at least %s", p.MinOrderTotal)),
+		)
+	}
+
+	if !slices.ContainsFunc(productIDs, p.AppliesTo) {
+		return ErrPromoCodeNotApplicable.WithFields(
+			apperror.Field("promo_code", "no order items are eligible for this code"),
+		)
+	}
+
+	return nil
+}
+
+// CheckUsage проверяет лимиты погашений: всего и для пользователя, который уже погасил код userUses раз
+func (p *PromoCode) CheckUsage(userUses int64) error {
+	if p.MaxUses != nil && p.Uses >= *p.MaxUses {
+		return ErrPromoCodeExhausted.WithFields(apperror.Field("promo_code", fmt.Sprintf("code %s is used up", p.Code)))
+	}
+	if p.MaxUsesPerUser != nil && userUses >= *p.MaxUsesPerUser {
+		return ErrPromoCodeExhausted.WithFields(
+			apperror.Field("promo_code", fmt.Sprintf("code %s can be used %d times per user", p.Code, *p.MaxUsesPerUser)),
+		)
+	}
+	return nil
+}
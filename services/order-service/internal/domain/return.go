@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunded  = "refunded"
+)
+
+var (
+	ErrUnknownReturnStatus     = apperror.New(apperror.CodeInvalidArgument, "unknown return status")
+	ErrInvalidReturnTransition = apperror.New(apperror.CodeConflict, "invalid return status transition")
+	ErrOrderNotReturnable      = apperror.New(apperror.CodeConflict, "only delivered orders can be returned")
+	ErrInvalidReturnItems      = apperror.New(apperror.CodeInvalidArgument, "invalid return items")
+)
+
+// returnTransitions возврат проходит статусы строго по порядку; refunded — конечный
+var returnTransitions = map[string][]string{
+	ReturnStatusRequested: {ReturnStatusApproved},
+	ReturnStatusApproved:  {ReturnStatusReceived},
+	ReturnStatusReceived:  {ReturnStatusRefunded},
+	ReturnStatusRefunded:  {},
+}
+
+// Return возврат части позиций доставленного заказа. Суммы рассчитываются при создании
+// и не меняются: к возврату денег уходит TotalAmount.
+type Return struct {
+	ID             int64       `db:"id"`
+	OrderID        int64       `db:"order_id"`
+	Status         string      `db:"status"`
+	Reason         string      `db:"reason"`
+	RequestedBy    int64       `db:"requested_by"`
+	ItemsAmount    money.Money `db:"items_amount"`
+	TaxAmount      money.Money `db:"tax_amount"`
+	ShippingAmount money.Money `db:"shipping_amount"`
+	TotalAmount    money.Money `db:"total_amount"`
+	CreatedAt      time.Time   `db:"created_at"`
+	UpdatedAt      time.Time   `db:"updated_at"`
+	Items          []*ReturnItem
+}
+
+// ReturnItem возвращаемое количество позиции заказа и его стоимость по цене заказа
+type ReturnItem struct {
+	ID          int64       `db:"id"`
+	ReturnID    int64       `db:"return_id"`
+	OrderItemID int64       `db:"order_item_id"`
+	Quantity    int64       `db:"quantity"`
+	Amount      money.Money `db:"amount"`
+}
+
+// TransitionTo переводит возврат в следующий статус
+func (r *Return) TransitionTo(status string, at time.Time) error {
+	if _, ok := returnTransitions[status]; !ok {
+		return ErrUnknownReturnStatus.WithFields(apperror.Field("status", fmt.Sprintf("unknown status %q", status)))
+	}
+
+	for _, next := range returnTransitions[r.Status] {
+		if next == status {
+			r.Status = status
+			r.UpdatedAt = at
+			return nil
+		}
+	}
+
+	return ErrInvalidReturnTransition.WithFields(
+		apperror.Field("status", fmt.Sprintf("cannot change return status from %s to %s", r.Status, status)),
+	)
+}
+
+// ReturnedQuantities сколько единиц каждой позиции заказа (по ID позиции) уже оформлено в возвраты
+func ReturnedQuantities(returns []*Return) map[int64]int64 {
+	returned := make(map[int64]int64)
+	for _, ret := range returns {
+		for _, item := range ret.Items {
+			returned[item.OrderItemID] += item.Quantity
+		}
+	}
+	return returned
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+const (
+	SagaStatusRunning      = "running"
+	SagaStatusCompensating = "compensating"
+	SagaStatusCompleted    = "completed"
+	SagaStatusCompensated  = "compensated"
+)
+
+// Saga сохранённое состояние экземпляра саги. Step — шаг, который выполняется
+// (или откатывается) сейчас; Data — данные саги в JSON после последнего завершённого шага.
+type Saga struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	Status    string    `db:"status"`
+	Step      int       `db:"step"`
+	Data      []byte    `db:"data"`
+	LastError *string   `db:"last_error"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Finished завершена ли сага: выполнена целиком или полностью откачена
+func (s *Saga) Finished() bool {
+	return s.Status == SagaStatusCompleted || s.Status == SagaStatusCompensated
+}
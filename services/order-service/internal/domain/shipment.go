@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+const (
+	// ShipmentStatusPending позиции зарезервированы за отправлением, этикетка ещё не создана
+	ShipmentStatusPending      = "pending"
+	ShipmentStatusLabelCreated = "label_created"
+	ShipmentStatusInTransit    = "in_transit"
+	ShipmentStatusDelivered    = "delivered"
+)
+
+var (
+	ErrOrderNotShippable     = apperror.New(apperror.CodeConflict, "only processing or shipped orders can be shipped")
+	ErrInvalidShipmentItems  = apperror.New(apperror.CodeInvalidArgument, "invalid shipment items")
+	ErrUnknownShipmentStatus = apperror.New(apperror.CodeUnprocessable, "unknown shipment status")
+	ErrShipmentInProgress    = apperror.New(apperror.CodeConflict, "order has a shipment in progress")
+)
+
+// shipmentStatusOrder статусы отправления по порядку: статус только продвигается вперёд
+var shipmentStatusOrder = []string{
+	ShipmentStatusPending, ShipmentStatusLabelCreated, ShipmentStatusInTransit, ShipmentStatusDelivered,
+}
+
+// Shipment отправление части позиций заказа у перевозчика. TrackedAt — когда статус
+// последний раз запрашивался у перевозчика.
+type Shipment struct {
+	ID             int64      `db:"id"`
+	OrderID        int64      `db:"order_id"`
+	Carrier        string     `db:"carrier"`
+	TrackingNumber string     `db:"tracking_number"`
+	LabelURL       string     `db:"label_url"`
+	Status         string     `db:"status"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+	TrackedAt      *time.Time `db:"tracked_at"`
+	Items          []*ShipmentItem
+}
+
+// ShipmentItem сколько единиц позиции заказа ушло в отправлении
+type ShipmentItem struct {
+	ID          int64 `db:"id"`
+	ShipmentID  int64 `db:"shipment_id"`
+	OrderItemID int64 `db:"order_item_id"`
+	Quantity    int64 `db:"quantity"`
+}
+
+// Advance применяет статус, полученный от перевозчика. Перевозчик может вернуть устаревший
+// статус, поэтому откат назад игнорируется; false — статус не изменился.
+func (s *Shipment) Advance(status string, at time.Time) (bool, error) {
+	next := slices.Index(shipmentStatusOrder, status)
+	if next < 0 {
+		return false, ErrUnknownShipmentStatus.WithFields(apperror.Field("status", status))
+	}
+	if next <= slices.Index(shipmentStatusOrder, s.Status) {
+		return false, nil
+	}
+
+	s.Status = status
+	s.UpdatedAt = at
+	if status == ShipmentStatusDelivered {
+		s.DeliveredAt = &at
+	}
+	return true, nil
+}
+
+// HasPendingShipment есть ли отправление, для которого ещё создаётся этикетка
+func HasPendingShipment(shipments []*Shipment) bool {
+	for _, shipment := range shipments {
+		if shipment.Status == ShipmentStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// ShippedQuantities сколько единиц каждой позиции заказа (по ID позиции) уже отправлено
+// или зарезервировано за отправлением в статусе pending
+func ShippedQuantities(shipments []*Shipment) map[int64]int64 {
+	shipped := make(map[int64]int64)
+	for _, shipment := range shipments {
+		for _, item := range shipment.Items {
+			shipped[item.OrderItemID] += item.Quantity
+		}
+	}
+	return shipped
+}
+
+// OrderDelivered все позиции заказа отправлены и все отправления доставлены
+func OrderDelivered(order *Order, shipments []*Shipment) bool {
+	for _, shipment := range shipments {
+		if shipment.Status != ShipmentStatusDelivered {
+			return false
+		}
+	}
+
+	shipped := ShippedQuantities(shipments)
+	for _, item := range order.Items {
+		if shipped[item.ID] < item.Quantity {
+			return false
+		}
+	}
+	return len(shipments) > 0
+}
@@ -1,11 +1,42 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
 
 type CreateOrderRequest struct {
 	PaymentMethod string `json:"payment_method"`
-	UserID        int64  `json:"user_id"`
-	Items         []*CreateOrderItemRequest
+	// UserID берётся из access-токена, значение из тела запроса игнорируется
+	UserID int64 `json:"-"`
+	// Region регион доставки для налогов и тарифов; пустой — регион по умолчанию из конфига
+	Region string `json:"region"`
+	// PromoCode код скидки; пустой — без скидки
+	PromoCode string `json:"promo_code,omitempty"`
+	OrderAddressesRequest
+	Items []*CreateOrderItemRequest
+}
+
+// OrderAddressesRequest адреса заказа. Каждый задаётся целиком или ID из адресной книги;
+// адрес доставки обязателен, без адреса оплаты используется адрес доставки.
+type OrderAddressesRequest struct {
+	ShippingAddress   *Address `json:"shipping_address,omitempty"`
+	ShippingAddressID *int64   `json:"shipping_address_id,omitempty"`
+	BillingAddress    *Address `json:"billing_address,omitempty"`
+	BillingAddressID  *int64   `json:"billing_address_id,omitempty"`
+}
+
+// Address Country — код страны ISO 3166-1 alpha-2
+type Address struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone,omitempty"`
+	Country    string `json:"country"`
+	Region     string `json:"region,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
 }
 
 type CreateOrderItemRequest struct {
@@ -14,47 +45,225 @@ type CreateOrderItemRequest struct {
 }
 
 type CreateOrderResponse struct {
-	ID            int64      `json:"id"`
-	PaymentMethod string     `json:"payment_method"`
-	TaxPrice      float64    `json:"tax_price"`
-	ShippingPrice float64    `json:"shipping_price"`
-	TotalPrice    float64    `json:"total_price"`
-	UserID        int64      `json:"user_id"`
-	Status        string     `json:"status"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     *time.Time `json:"updated_at"`
+	ID            int64       `json:"id"`
+	PaymentMethod string      `json:"payment_method"`
+	TaxPrice      money.Money `json:"tax_price"`
+	ShippingPrice money.Money `json:"shipping_price"`
+	TotalPrice    money.Money `json:"total_price"`
+	UserID        int64       `json:"user_id"`
+	Status        string      `json:"status"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     *time.Time  `json:"updated_at"`
 	Items         []*CreateOrderItemResponse
 }
 
 type CreateOrderItemResponse struct {
-	ID        int64   `json:"id"`
-	Name      string  `json:"name"`
-	Quantity  int64   `json:"quantity"`
-	Image     string  `json:"image"`
-	Price     float64 `json:"price"`
-	ProductID int64   `json:"product_id"`
-	OrderID   int64   `json:"order_id"`
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Quantity  int64       `json:"quantity"`
+	Image     string      `json:"image"`
+	Price     money.Money `json:"price"`
+	ProductID int64       `json:"product_id"`
+	OrderID   int64       `json:"order_id"`
 }
 
 type OrderItemResponse struct {
-	ID        int64   `db:"id"`
-	Name      string  `db:"name"`
-	Quantity  int64   `db:"quantity"`
-	Image     string  `db:"image"`
-	Price     float64 `db:"price"`
-	ProductID int64   `db:"product_id"`
-	OrderID   int64   `db:"order_id"`
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Quantity  int64       `json:"quantity"`
+	Image     string      `json:"image"`
+	Price     money.Money `json:"price"`
+	ProductID int64       `json:"product_id"`
+	OrderID   int64       `json:"order_id"`
 }
 
 type OrderResponse struct {
-	ID            int64      `db:"id"`
-	PaymentMethod string     `db:"payment_method"`
-	TaxPrice      float64    `db:"tax_price"`
-	ShippingPrice float64    `db:"shipping_price"`
-	TotalPrice    float64    `db:"total_price"`
-	UserID        int64      `db:"user_id"`
-	Status        string     `db:"status"`
-	CreatedAt     time.Time  `db:"created_at"`
-	UpdatedAt     *time.Time `db:"updated_at"`
-	Items         []*OrderItemResponse
+	ID                 int64                `json:"id"`
+	PaymentMethod      string               `json:"payment_method"`
+	PromoCode          *string              `json:"promo_code,omitempty"`
+	DiscountPrice      money.Money          `json:"discount_price"`
+	TaxPrice           money.Money          `json:"tax_price"`
+	ShippingPrice      money.Money          `json:"shipping_price"`
+	TotalPrice         money.Money          `json:"total_price"`
+	Currency           string               `json:"currency"`
+	UserID             int64                `json:"user_id"`
+	Status             string               `json:"status"`
+	CreatedAt          time.Time            `json:"created_at"`
+	UpdatedAt          *time.Time           `json:"updated_at"`
+	CancellationReason *string              `json:"cancellation_reason,omitempty"`
+	ShippingAddress    *Address             `json:"shipping_address,omitempty"`
+	BillingAddress     *Address             `json:"billing_address,omitempty"`
+	Version            int64                `json:"version"`
+	Items              []*OrderItemResponse `json:"items"`
+	PriceLines         []*PriceLineResponse `json:"price_lines"`
+}
+
+type PriceLineResponse struct {
+	Kind        string      `json:"kind"`
+	Rule        string      `json:"rule"`
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount"`
+}
+
+// UpdateOrderStatusRequest запрос на смену статуса заказа.
+// ChangedBy проставляется обработчиком из данных о пользователе, а Version — из If-Match, а не из тела запроса.
+type UpdateOrderStatusRequest struct {
+	Status    string `json:"status"`
+	Comment   string `json:"comment"`
+	ChangedBy *int64 `json:"-"`
+	Version   int64  `json:"-"`
+}
+
+// CancelOrderRequest Version — ожидаемая версия заказа из If-Match
+type CancelOrderRequest struct {
+	Reason  string `json:"reason"`
+	Version int64  `json:"-"`
+}
+
+// CreateReturnRequest позиции заказа (ID из order_items) и количество, которое возвращается
+type CreateReturnRequest struct {
+	Reason string               `json:"reason"`
+	Items  []*ReturnItemRequest `json:"items"`
+}
+
+type ReturnItemRequest struct {
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int64 `json:"quantity"`
+}
+
+type UpdateReturnStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type ReturnResponse struct {
+	ID             int64                 `json:"id"`
+	OrderID        int64                 `json:"order_id"`
+	Status         string                `json:"status"`
+	Reason         string                `json:"reason"`
+	RequestedBy    int64                 `json:"requested_by"`
+	ItemsAmount    money.Money           `json:"items_amount"`
+	TaxAmount      money.Money           `json:"tax_amount"`
+	ShippingAmount money.Money           `json:"shipping_amount"`
+	TotalAmount    money.Money           `json:"total_amount"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	Items          []*ReturnItemResponse `json:"items"`
+}
+
+type ReturnItemResponse struct {
+	ID          int64       `json:"id"`
+	OrderItemID int64       `json:"order_item_id"`
+	Quantity    int64       `json:"quantity"`
+	Amount      money.Money `json:"amount"`
+}
+
+// CreateShipmentRequest позиции заказа (ID из order_items) и количество, которое уходит в отправлении.
+// Version — ожидаемая версия заказа из If-Match: первое отправление переводит заказ в shipped.
+type CreateShipmentRequest struct {
+	Carrier string                 `json:"carrier"`
+	Items   []*ShipmentItemRequest `json:"items"`
+	Version int64                  `json:"-"`
+}
+
+type ShipmentItemRequest struct {
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int64 `json:"quantity"`
+}
+
+type ShipmentResponse struct {
+	ID             int64                   `json:"id"`
+	OrderID        int64                   `json:"order_id"`
+	Carrier        string                  `json:"carrier"`
+	TrackingNumber string                  `json:"tracking_number"`
+	LabelURL       string                  `json:"label_url"`
+	Status         string                  `json:"status"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+	DeliveredAt    *time.Time              `json:"delivered_at,omitempty"`
+	Items          []*ShipmentItemResponse `json:"items"`
+	// OrderVersion версия заказа после создания отправления, для ETag
+	OrderVersion int64 `json:"-"`
+}
+
+type ShipmentItemResponse struct {
+	ID          int64 `json:"id"`
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int64 `json:"quantity"`
+}
+
+type AddCartItemRequest struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int64 `json:"quantity"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int64 `json:"quantity"`
+}
+
+// CheckoutRequest параметры заказа из корзины: позиции берутся из корзины, пользователь — из access-токена
+type CheckoutRequest struct {
+	PaymentMethod string `json:"payment_method"`
+	Region        string `json:"region"`
+	PromoCode     string `json:"promo_code,omitempty"`
+	OrderAddressesRequest
+}
+
+// CartResponse корзина по текущим ценам product-service; ItemsPrice — стоимость товаров без
+// налогов, доставки и скидок
+type CartResponse struct {
+	Items      []*CartItemResponse `json:"items"`
+	ItemsPrice money.Money         `json:"items_price"`
+	Currency   string              `json:"currency"`
+}
+
+// CartItemResponse PreviousPrice задана, если цена изменилась после добавления товара в корзину;
+// Available — товара на складе хватает (или остаток неизвестен)
+type CartItemResponse struct {
+	ProductID     int64        `json:"product_id"`
+	Name          string       `json:"name"`
+	Image         string       `json:"image"`
+	Quantity      int64        `json:"quantity"`
+	Price         money.Money  `json:"price"`
+	PreviousPrice *money.Money `json:"previous_price,omitempty"`
+	Total         money.Money  `json:"total"`
+	Available     bool         `json:"available"`
+}
+
+// SaveAddressRequest создание или замена адреса в адресной книге
+type SaveAddressRequest struct {
+	Label   string  `json:"label"`
+	Address Address `json:"address"`
+}
+
+type AddressResponse struct {
+	ID        int64     `json:"id"`
+	Label     string    `json:"label"`
+	Address   Address   `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListOrdersRequest параметры выборки списка заказов
+type ListOrdersRequest struct {
+	UserID      *int64
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	SortOrder   string
+	Limit       int
+	Cursor      string
+}
+
+// ListOrdersResponse страница заказов; NextCursor пуст, если страница последняя
+type ListOrdersResponse struct {
+	Orders     []*OrderResponse `json:"orders"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// IdempotentResponse ответ на запрос с Idempotency-Key; Replayed — ответ взят из сохранённого
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+	Replayed   bool
 }
@@ -1,11 +1,18 @@
 package dto
 
+import "github.com/defan6/market/services/order-service/internal/lib/money"
+
+// StockUnknown значение CountInStock, когда остаток не известен (например, товар взят из кэша)
+const StockUnknown int64 = -1
+
 // ExternalProduct DTO для получения данных о продукте из product-service
 type ExternalProduct struct {
-	ID           int64   `json:"id"`
-	Name         string  `json:"name"`
-	Price        float64 `json:"price"`
-	CountInStock int64   `json:"count_in_stock"`
-	Image        string  `json:"image"`
-	Description  string  `json:"description"`
+	ID           int64       `json:"id"`
+	Name         string      `json:"name"`
+	Price        money.Money `json:"price"`
+	CountInStock int64       `json:"count_in_stock"`
+	Image        string      `json:"image"`
+	Description  string      `json:"description"`
+	Category     string      `json:"category"`
+	WeightGrams  int64       `json:"weight_grams"`
 }
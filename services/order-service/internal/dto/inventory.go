@@ -0,0 +1,7 @@
+package dto
+
+// ReservationItem сколько единиц товара резервируется под заказ
+type ReservationItem struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int64 `json:"quantity"`
+}
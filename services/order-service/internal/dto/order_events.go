@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// Полезная нагрузка событий заказа для внешних потребителей (уведомления, аналитика).
+// Поля только добавляются: потребители должны игнорировать незнакомые.
+
+type OrderCreatedEvent struct {
+	OrderID         int64            `json:"order_id"`
+	UserID          int64            `json:"user_id"`
+	Status          string           `json:"status"`
+	PaymentMethod   string           `json:"payment_method"`
+	TotalPrice      money.Money      `json:"total_price"`
+	Currency        string           `json:"currency"`
+	Items           []OrderEventItem `json:"items"`
+	ShippingAddress *Address         `json:"shipping_address,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+type OrderEventItem struct {
+	ProductID int64       `json:"product_id"`
+	Quantity  int64       `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
+
+type OrderStatusChangedEvent struct {
+	OrderID    int64     `json:"order_id"`
+	UserID     int64     `json:"user_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *int64    `json:"changed_by,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+type OrderCancelledEvent struct {
+	OrderID     int64     `json:"order_id"`
+	UserID      int64     `json:"user_id"`
+	FromStatus  string    `json:"from_status"`
+	Reason      string    `json:"reason"`
+	CancelledBy *int64    `json:"cancelled_by,omitempty"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
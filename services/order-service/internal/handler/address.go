@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/go-chi/chi/v5"
+)
+
+// AddressService адресная книга пользователя из access-токена
+type AddressService interface {
+	ListAddresses(ctx context.Context, userID int64) ([]*dto.AddressResponse, error)
+	GetAddress(ctx context.Context, userID, id int64) (*dto.AddressResponse, error)
+	CreateAddress(ctx context.Context, userID int64, request *dto.SaveAddressRequest) (*dto.AddressResponse, error)
+	UpdateAddress(ctx context.Context, userID, id int64, request *dto.SaveAddressRequest) (*dto.AddressResponse, error)
+	DeleteAddress(ctx context.Context, userID, id int64) error
+}
+
+var errInvalidAddressID = apperror.New(apperror.CodeInvalidArgument, "invalid address id")
+
+type addressHandler struct {
+	log     *slog.Logger
+	service AddressService
+}
+
+func NewAddressHandler(log *slog.Logger, service AddressService) *addressHandler {
+	return &addressHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+func (h *addressHandler) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListAddresses"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	resp, err := h.service.ListAddresses(r.Context(), actor.UserID)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *addressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetAddress"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(h.log, w, r, op, errInvalidAddressID)
+		return
+	}
+
+	resp, err := h.service.GetAddress(r.Context(), actor.UserID, id)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *addressHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateAddress"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	var req dto.SaveAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(h.log, w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.CreateAddress(r.Context(), actor.UserID, &req)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *addressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UpdateAddress"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(h.log, w, r, op, errInvalidAddressID)
+		return
+	}
+
+	var req dto.SaveAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(h.log, w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.UpdateAddress(r.Context(), actor.UserID, id, &req)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *addressHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.DeleteAddress"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(h.log, w, r, op, errInvalidAddressID)
+		return
+	}
+
+	if err := h.service.DeleteAddress(r.Context(), actor.UserID, id); err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
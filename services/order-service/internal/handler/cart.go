@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/go-chi/chi/v5"
+)
+
+// CartService корзина всегда принадлежит пользователю из access-токена
+type CartService interface {
+	GetCart(ctx context.Context, userID int64) (*dto.CartResponse, error)
+	AddCartItem(ctx context.Context, userID int64, request *dto.AddCartItemRequest) (*dto.CartResponse, error)
+	UpdateCartItem(ctx context.Context, userID, productID int64, request *dto.UpdateCartItemRequest) (*dto.CartResponse, error)
+	RemoveCartItem(ctx context.Context, userID, productID int64) (*dto.CartResponse, error)
+	Checkout(ctx context.Context, userID int64, request *dto.CheckoutRequest) (*dto.OrderResponse, error)
+}
+
+var errInvalidProductID = apperror.New(apperror.CodeInvalidArgument, "invalid product id")
+
+type cartHandler struct {
+	log     *slog.Logger
+	service CartService
+}
+
+func NewCartHandler(log *slog.Logger, service CartService) *cartHandler {
+	return &cartHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+func (h *cartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetCart"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	resp, err := h.service.GetCart(r.Context(), actor.UserID)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *cartHandler) AddCartItem(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.AddCartItem"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	var req dto.AddCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(h.log, w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.AddCartItem(r.Context(), actor.UserID, &req)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *cartHandler) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UpdateCartItem"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	productID, err := strconv.ParseInt(chi.URLParam(r, "productID"), 10, 64)
+	if err != nil {
+		writeError(h.log, w, r, op, errInvalidProductID)
+		return
+	}
+
+	var req dto.UpdateCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(h.log, w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.UpdateCartItem(r.Context(), actor.UserID, productID, &req)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *cartHandler) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.RemoveCartItem"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	productID, err := strconv.ParseInt(chi.URLParam(r, "productID"), 10, 64)
+	if err != nil {
+		writeError(h.log, w, r, op, errInvalidProductID)
+		return
+	}
+
+	resp, err := h.service.RemoveCartItem(r.Context(), actor.UserID, productID)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *cartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.Checkout"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		writeError(h.log, w, r, op, errUnauthenticated)
+		return
+	}
+
+	var req dto.CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(h.log, w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.Checkout(r.Context(), actor.UserID, &req)
+	if err != nil {
+		writeError(h.log, w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
@@ -5,9 +5,16 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/defan6/market/services/order-service/internal/auth"
+	"github.com/defan6/market/services/order-service/internal/domain"
 	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -25,23 +32,59 @@ func NewDefaultOrderHandler(log *slog.Logger, service OrderService) *defaultOrde
 
 type OrderService interface {
 	CreateOrder(ctx context.Context, request *dto.CreateOrderRequest) (*dto.OrderResponse, error)
-	GetOrder(ctx context.Context, id int64) (*dto.OrderResponse, error)
+	CreateOrderIdempotent(ctx context.Context, request *dto.CreateOrderRequest, key string) (*dto.IdempotentResponse, error)
+	GetOrder(ctx context.Context, id int64, actor domain.Actor) (*dto.OrderResponse, error)
+	ListOrders(ctx context.Context, request *dto.ListOrdersRequest, actor domain.Actor) (*dto.ListOrdersResponse, error)
+	UpdateOrderStatus(ctx context.Context, id int64, request *dto.UpdateOrderStatusRequest) (*dto.OrderResponse, error)
+	CancelOrder(ctx context.Context, id int64, actor domain.Actor, request *dto.CancelOrderRequest) (*dto.OrderResponse, error)
+	CreateReturn(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateReturnRequest) (*dto.ReturnResponse, error)
+	ListReturns(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ReturnResponse, error)
+	UpdateReturnStatus(ctx context.Context, orderID, returnID int64, request *dto.UpdateReturnStatusRequest) (*dto.ReturnResponse, error)
+	CreateShipment(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateShipmentRequest) (*dto.ShipmentResponse, error)
+	ListShipments(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ShipmentResponse, error)
 }
 
+var (
+	errUnauthenticated = apperror.New(apperror.CodeUnauthenticated, "unauthorized")
+	errInvalidBody     = apperror.New(apperror.CodeInvalidArgument, "invalid request body")
+	errInvalidOrderID  = apperror.New(apperror.CodeInvalidArgument, "invalid order id")
+	errInvalidReturnID = apperror.New(apperror.CodeInvalidArgument, "invalid return id")
+	errIfMatchRequired = apperror.New(apperror.CodePreconditionRequired, "If-Match header is required")
+	errInvalidIfMatch  = apperror.New(apperror.CodeInvalidArgument, "invalid If-Match header")
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	etagHeader               = "ETag"
+	ifMatchHeader            = "If-Match"
+)
+
 func (h *defaultOrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.CreateOrder"
 
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
 	var req dto.CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Error(op, slog.String("error", err.Error()))
-		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+	// заказ всегда оформляется на пользователя из токена
+	req.UserID = actor.UserID
+
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		h.createOrderIdempotent(w, r, &req, key)
 		return
 	}
 
 	resp, err := h.service.CreateOrder(r.Context(), &req)
 	if err != nil {
-		h.log.Error(op, slog.String("error", err.Error()))
-		http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+		h.writeError(w, r, op, err)
 		return
 	}
 
@@ -52,21 +95,251 @@ func (h *defaultOrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// createOrderIdempotent при повторе отдаёт сохранённый ответ первого запроса с исходным кодом
+func (h *defaultOrderHandler) createOrderIdempotent(w http.ResponseWriter, r *http.Request, req *dto.CreateOrderRequest, key string) {
+	const op = "handler.CreateOrder"
+
+	resp, err := h.service.CreateOrderIdempotent(r.Context(), req, key)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := w.Write(resp.Body); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
 func (h *defaultOrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.GetOrder"
 
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		h.log.Error(op, slog.String("error", "invalid order id"))
-		http.Error(w, `{"error":"invalid order id"}`, http.StatusBadRequest)
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	resp, err := h.service.GetOrder(r.Context(), id, actor)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set(etagHeader, orderETag(resp.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListOrders"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
+	req, err := parseListOrdersRequest(r.URL.Query())
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	resp, err := h.service.ListOrders(r.Context(), req, actor)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UpdateOrderStatus"
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	version, err := orderVersionFromIfMatch(r)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	var req dto.UpdateOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+	if actor, ok := actorFromRequest(r); ok {
+		req.ChangedBy = &actor.UserID
+	}
+	req.Version = version
+
+	resp, err := h.service.UpdateOrderStatus(r.Context(), id, &req)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set(etagHeader, orderETag(resp.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CancelOrder"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	version, err := orderVersionFromIfMatch(r)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	var req dto.CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+	req.Version = version
+
+	resp, err := h.service.CancelOrder(r.Context(), id, actor, &req)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set(etagHeader, orderETag(resp.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateReturn"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	var req dto.CreateReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.CreateReturn(r.Context(), id, actor, &req)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListReturns"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
 		return
 	}
 
-	resp, err := h.service.GetOrder(r.Context(), id)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	resp, err := h.service.ListReturns(r.Context(), id, actor)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.log.Error(op, slog.String("error", err.Error()))
-		http.Error(w, `{"error":"order not found"}`, http.StatusNotFound)
+	}
+}
+
+func (h *defaultOrderHandler) UpdateReturnStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UpdateReturnStatus"
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	returnID, err := strconv.ParseInt(chi.URLParam(r, "returnID"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidReturnID)
+		return
+	}
+
+	var req dto.UpdateReturnStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+
+	resp, err := h.service.UpdateReturnStatus(r.Context(), id, returnID, &req)
+	if err != nil {
+		h.writeError(w, r, op, err)
 		return
 	}
 
@@ -76,3 +349,165 @@ func (h *defaultOrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		h.log.Error(op, slog.String("error", err.Error()))
 	}
 }
+
+func (h *defaultOrderHandler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateShipment"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	version, err := orderVersionFromIfMatch(r)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	var req dto.CreateShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, op, errInvalidBody.WithFields(apperror.Field("body", err.Error())))
+		return
+	}
+	req.Version = version
+
+	resp, err := h.service.CreateShipment(r.Context(), id, actor, &req)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set(etagHeader, orderETag(resp.OrderVersion))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+func (h *defaultOrderHandler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListShipments"
+
+	actor, ok := actorFromRequest(r)
+	if !ok {
+		h.writeError(w, r, op, errUnauthenticated)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.writeError(w, r, op, errInvalidOrderID)
+		return
+	}
+
+	resp, err := h.service.ListShipments(r.Context(), id, actor)
+	if err != nil {
+		h.writeError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
+
+// actorFromRequest пользователь из access-токена, проверенного auth.Authenticate
+func actorFromRequest(r *http.Request) (domain.Actor, bool) {
+	return auth.ActorFromContext(r.Context())
+}
+
+// orderETag сильный ETag заказа — его версия в кавычках
+func orderETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// orderVersionFromIfMatch версия заказа из If-Match; заголовок обязателен для запросов, изменяющих заказ
+func orderVersionFromIfMatch(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get(ifMatchHeader))
+	if value == "" {
+		return 0, errIfMatchRequired.WithFields(apperror.Field(ifMatchHeader, "pass the ETag of the order"))
+	}
+
+	unquoted, ok := strings.CutPrefix(value, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, errInvalidIfMatch.WithFields(apperror.Field(ifMatchHeader, `expected the ETag of the order, e.g. "3"`))
+	}
+	return version, nil
+}
+
+func parseListOrdersRequest(query url.Values) (*dto.ListOrdersRequest, error) {
+	req := &dto.ListOrdersRequest{
+		Status:    query.Get("status"),
+		SortBy:    query.Get("sort"),
+		SortOrder: query.Get("order"),
+		Cursor:    query.Get("cursor"),
+	}
+
+	if v := query.Get("user_id"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, service.ErrInvalidListRequest.WithFields(apperror.Field("user_id", "must be an integer"))
+		}
+		req.UserID = &userID
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, service.ErrInvalidListRequest.WithFields(apperror.Field("limit", "must be an integer"))
+		}
+		req.Limit = limit
+	}
+
+	var err error
+	if req.CreatedFrom, err = parseTimeParam(query, "created_from"); err != nil {
+		return nil, err
+	}
+	if req.CreatedTo, err = parseTimeParam(query, "created_to"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func parseTimeParam(query url.Values, param string) (*time.Time, error) {
+	v := query.Get(param)
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, service.ErrInvalidListRequest.WithFields(apperror.Field(param, "expected RFC3339 timestamp"))
+	}
+	return &t, nil
+}
+
+func (h *defaultOrderHandler) writeError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	writeError(h.log, w, r, op, err)
+}
+
+// writeError единая точка ответа об ошибке: статус и тело (problem+json) определяются
+// по apperror.Error в цепочке, остальные ошибки отдаются клиенту как 500 без подробностей
+func writeError(log *slog.Logger, w http.ResponseWriter, r *http.Request, op string, err error) {
+	if appErr, ok := apperror.As(err); ok && apperror.HTTPStatus(appErr.Code) < http.StatusInternalServerError {
+		log.Warn(op, slog.String("error", err.Error()))
+	} else {
+		log.Error(op, slog.String("error", err.Error()))
+	}
+
+	apperror.WriteProblem(w, r, err)
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/auth"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/service"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderService возвращает заданную ошибку из любого метода; GetOrder и UpdateOrderStatus
+// возвращают order, а UpdateOrderStatus и CreateShipment запоминают запрошенную версию
+type fakeOrderService struct {
+	err     error
+	order   *dto.OrderResponse
+	version int64
+}
+
+func (f *fakeOrderService) CreateOrder(ctx context.Context, request *dto.CreateOrderRequest) (*dto.OrderResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) CreateOrderIdempotent(ctx context.Context, request *dto.CreateOrderRequest, key string) (*dto.IdempotentResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) GetOrder(ctx context.Context, id int64, actor domain.Actor) (*dto.OrderResponse, error) {
+	return f.order, f.err
+}
+
+func (f *fakeOrderService) ListOrders(ctx context.Context, request *dto.ListOrdersRequest, actor domain.Actor) (*dto.ListOrdersResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) UpdateOrderStatus(ctx context.Context, id int64, request *dto.UpdateOrderStatusRequest) (*dto.OrderResponse, error) {
+	f.version = request.Version
+	return f.order, f.err
+}
+
+func (f *fakeOrderService) CancelOrder(ctx context.Context, id int64, actor domain.Actor, request *dto.CancelOrderRequest) (*dto.OrderResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) CreateReturn(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateReturnRequest) (*dto.ReturnResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) ListReturns(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ReturnResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) UpdateReturnStatus(ctx context.Context, orderID, returnID int64, request *dto.UpdateReturnStatusRequest) (*dto.ReturnResponse, error) {
+	return nil, f.err
+}
+
+func (f *fakeOrderService) CreateShipment(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateShipmentRequest) (*dto.ShipmentResponse, error) {
+	f.version = request.Version
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dto.ShipmentResponse{ID: 1, OrderID: orderID, OrderVersion: request.Version + 1}, nil
+}
+
+func (f *fakeOrderService) ListShipments(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ShipmentResponse, error) {
+	return nil, f.err
+}
+
+func loadFixture(t *testing.T, name string) map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "fixtures", name))
+	require.NoError(t, err)
+
+	var fixture map[string]any
+	require.NoError(t, json.Unmarshal(data, &fixture))
+	return fixture
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		ifMatch string
+		err     error
+		status  int
+		fixture string
+	}{
+		{
+			name: "empty items", method: http.MethodPost, target: "/", body: `{"payment_method":"card","items":[]}`,
+			err: service.ErrEmptyOrderItems, status: http.StatusBadRequest, fixture: "error_empty_items.json",
+		},
+		{
+			name: "empty payment", method: http.MethodPost, target: "/", body: `{"items":[]}`,
+			err: service.ErrPaymentMethodEmpty, status: http.StatusBadRequest, fixture: "error_empty_payment.json",
+		},
+		{
+			name: "order not found", method: http.MethodGet, target: "/1",
+			err: service.ErrOrderNotFound, status: http.StatusNotFound, fixture: "error_order_not_found.json",
+		},
+		{
+			name: "out of stock", method: http.MethodPost, target: "/", body: `{}`,
+			err: service.ErrInsufficientStock, status: http.StatusConflict,
+		},
+		{
+			name: "product service down", method: http.MethodPost, target: "/", body: `{}`,
+			err: errors.Join(service.ErrProductServiceUnavailable, errors.New("dial tcp: timeout")), status: http.StatusServiceUnavailable,
+		},
+		{
+			name: "database outage", method: http.MethodGet, target: "/1",
+			err: errors.New("pq: connection refused"), status: http.StatusInternalServerError,
+		},
+		{
+			name: "invalid id", method: http.MethodGet, target: "/abc",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "missing if-match", method: http.MethodPatch, target: "/1/status", body: `{"status":"shipped"}`,
+			status: http.StatusPreconditionRequired,
+		},
+		{
+			name: "shipment without if-match", method: http.MethodPost, target: "/1/shipments", body: `{"carrier":"fake"}`,
+			status: http.StatusPreconditionRequired,
+		},
+		{
+			name: "malformed if-match", method: http.MethodDelete, target: "/1", body: `{"reason":"x"}`, ifMatch: "W/\"3\"",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "stale version", method: http.MethodPatch, target: "/1/status", body: `{"status":"shipped"}`, ifMatch: `"3"`,
+			err: domain.ErrOrderVersionMismatch, status: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewDefaultOrderHandler(slogdiscard.NewDiscardLogger(), &fakeOrderService{err: tt.err})
+			r := chi.NewRouter()
+			r.Post("/", h.CreateOrder)
+			r.Get("/{id}", h.GetOrder)
+			r.Patch("/{id}/status", h.UpdateOrderStatus)
+			r.Delete("/{id}", h.CancelOrder)
+			r.Post("/{id}/shipments", h.CreateShipment)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set(ifMatchHeader, tt.ifMatch)
+			}
+			req = req.WithContext(auth.WithActor(req.Context(), domain.Actor{UserID: 1, Role: domain.RoleUser}))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+			var problem map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.EqualValues(t, tt.status, problem["status"])
+			assert.NotEmpty(t, problem["error"])
+			assert.NotContains(t, problem["error"], "pq:")
+
+			// старые клиенты читают поля из фикстур — они должны остаться в ответе
+			if tt.fixture != "" {
+				for key, want := range loadFixture(t, tt.fixture) {
+					assert.Equal(t, want, problem[key], key)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderETag(t *testing.T) {
+	svc := &fakeOrderService{order: &dto.OrderResponse{ID: 1, Version: 4}}
+	h := NewDefaultOrderHandler(slogdiscard.NewDiscardLogger(), svc)
+	r := chi.NewRouter()
+	r.Get("/{id}", h.GetOrder)
+	r.Patch("/{id}/status", h.UpdateOrderStatus)
+	actor := domain.Actor{UserID: 1, Role: domain.RoleManager}
+
+	req := httptest.NewRequest(http.MethodGet, "/1", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req.WithContext(auth.WithActor(req.Context(), actor)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get(etagHeader)
+	assert.Equal(t, `"4"`, etag)
+
+	req = httptest.NewRequest(http.MethodPatch, "/1/status", strings.NewReader(`{"status":"shipped"}`))
+	req.Header.Set(ifMatchHeader, etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req.WithContext(auth.WithActor(req.Context(), actor)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(4), svc.version)
+
+	// отправление тоже меняет заказ: версия из If-Match, в ответе — новый ETag заказа
+	r.Post("/{id}/shipments", h.CreateShipment)
+	req = httptest.NewRequest(http.MethodPost, "/1/shipments", strings.NewReader(`{"carrier":"fake"}`))
+	req.Header.Set(ifMatchHeader, `"5"`)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req.WithContext(auth.WithActor(req.Context(), actor)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, int64(5), svc.version)
+	assert.Equal(t, `"6"`, rec.Header().Get(etagHeader))
+}
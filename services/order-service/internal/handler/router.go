@@ -6,11 +6,37 @@ import (
 	"time"
 
 	apphttp "github.com/defan6/market/services/order-service/internal/app/http"
+	"github.com/defan6/market/services/order-service/internal/auth"
+	"github.com/defan6/market/services/order-service/internal/domain"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func NewRouter(handler *defaultOrderHandler, log *slog.Logger) http.Handler {
+const (
+	statusPath      = "/internal/status"
+	ordersPrefix    = "/api/v1/orders"
+	cartPrefix      = "/api/v1/cart"
+	addressesPrefix = "/api/v1/addresses"
+)
+
+// routePolicy маршруты, доступные только сотрудникам. Остальные доступны любому
+// аутентифицированному пользователю, а доступ к чужим заказам проверяет сервис.
+var routePolicy = auth.RoutePolicy{
+	"PATCH " + ordersPrefix + "/{id}/status":                    {domain.RoleManager, domain.RoleAdmin},
+	"PATCH " + ordersPrefix + "/{id}/returns/{returnID}/status": {domain.RoleManager, domain.RoleAdmin},
+	"POST " + ordersPrefix + "/{id}/shipments":                  {domain.RoleManager, domain.RoleAdmin},
+	"GET " + statusPath:                                         {domain.RoleAdmin},
+}
+
+// NewRouter authenticate проверяет пользователя для всех маршрутов /api/v1 и /internal/status
+func NewRouter(
+	handler *defaultOrderHandler,
+	cart *cartHandler,
+	addresses *addressHandler,
+	status *statusHandler,
+	authenticate func(next http.Handler) http.Handler,
+	log *slog.Logger,
+) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Recoverer)
@@ -18,9 +44,43 @@ func NewRouter(handler *defaultOrderHandler, log *slog.Logger) http.Handler {
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(apphttp.Logging(log))
-	r.Route("/api/v1/orders", func(r chi.Router) {
-		r.Post("/", handler.CreateOrder)
-		r.Get("/", handler.GetOrder)
+	r.With(authenticate, routePolicy.For(http.MethodGet, statusPath)).Get(statusPath, status.Status)
+	r.Route(ordersPrefix, func(r chi.Router) {
+		r.Use(authenticate)
+
+		// handle применяет к маршруту роли из routePolicy
+		handle := func(method, pattern string, h http.HandlerFunc) {
+			r.With(routePolicy.For(method, ordersPrefix+pattern)).Method(method, pattern, h)
+		}
+
+		handle(http.MethodPost, "/", handler.CreateOrder)
+		handle(http.MethodGet, "/", handler.ListOrders)
+		handle(http.MethodGet, "/{id}", handler.GetOrder)
+		handle(http.MethodDelete, "/{id}", handler.CancelOrder)
+		handle(http.MethodPatch, "/{id}/status", handler.UpdateOrderStatus)
+		handle(http.MethodPost, "/{id}/returns", handler.CreateReturn)
+		handle(http.MethodGet, "/{id}/returns", handler.ListReturns)
+		handle(http.MethodPatch, "/{id}/returns/{returnID}/status", handler.UpdateReturnStatus)
+		handle(http.MethodPost, "/{id}/shipments", handler.CreateShipment)
+		handle(http.MethodGet, "/{id}/shipments", handler.ListShipments)
+	})
+	r.Route(cartPrefix, func(r chi.Router) {
+		r.Use(authenticate)
+
+		r.Get("/", cart.GetCart)
+		r.Post("/items", cart.AddCartItem)
+		r.Put("/items/{productID}", cart.UpdateCartItem)
+		r.Delete("/items/{productID}", cart.RemoveCartItem)
+		r.Post("/checkout", cart.Checkout)
+	})
+	r.Route(addressesPrefix, func(r chi.Router) {
+		r.Use(authenticate)
+
+		r.Get("/", addresses.ListAddresses)
+		r.Post("/", addresses.CreateAddress)
+		r.Get("/{id}", addresses.GetAddress)
+		r.Put("/{id}", addresses.UpdateAddress)
+		r.Delete("/{id}", addresses.DeleteAddress)
 	})
 
 	return r
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/auth"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoutePolicyMatchesRoutes опечатка в ключе routePolicy молча открыла бы маршрут всем
+func TestRoutePolicyMatchesRoutes(t *testing.T) {
+	passthrough := func(next http.Handler) http.Handler { return next }
+	router := NewRouter(&defaultOrderHandler{}, &cartHandler{}, &addressHandler{}, &statusHandler{}, passthrough, slogdiscard.NewDiscardLogger())
+
+	routes := make(map[string]bool)
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	for key := range routePolicy {
+		assert.True(t, routes[key], "policy key %q does not match any route", key)
+	}
+}
+
+func TestStatusRoute_AdminOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      *domain.Actor
+		wantStatus int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"manager", &domain.Actor{UserID: 1, Role: domain.RoleManager}, http.StatusForbidden},
+		{"admin", &domain.Actor{UserID: 1, Role: domain.RoleAdmin}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticate := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tt.actor != nil {
+						r = r.WithContext(auth.WithActor(r.Context(), *tt.actor))
+					}
+					next.ServeHTTP(w, r)
+				})
+			}
+			status := NewStatusHandler(slogdiscard.NewDiscardLogger())
+			router := NewRouter(&defaultOrderHandler{}, &cartHandler{}, &addressHandler{}, status, authenticate, slogdiscard.NewDiscardLogger())
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statusPath, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
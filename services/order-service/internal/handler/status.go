@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// StatusProvider компонент, чьё состояние показывается в /internal/status
+// (предохранители, кэши и т.п.)
+type StatusProvider interface {
+	Name() string
+	Status() any
+}
+
+type statusHandler struct {
+	log       *slog.Logger
+	providers []StatusProvider
+}
+
+func NewStatusHandler(log *slog.Logger, providers ...StatusProvider) *statusHandler {
+	return &statusHandler{
+		log:       log,
+		providers: providers,
+	}
+}
+
+func (h *statusHandler) Status(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.Status"
+
+	resp := make(map[string]any, len(h.providers))
+	for _, provider := range h.providers {
+		resp[provider.Name()] = provider.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error(op, slog.String("error", err.Error()))
+	}
+}
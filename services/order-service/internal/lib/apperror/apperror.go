@@ -0,0 +1,84 @@
+package apperror
+
+import (
+	"errors"
+	"strings"
+)
+
+// Code класс ошибки; по нему выбирается HTTP-статус
+type Code string
+
+const (
+	CodeInvalidArgument      Code = "invalid_argument"
+	CodeUnauthenticated      Code = "unauthenticated"
+	CodePermissionDenied     Code = "permission_denied"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodeUnprocessable        Code = "unprocessable"
+	CodeUnavailable          Code = "unavailable"
+	CodeInternal             Code = "internal"
+	CodeFailedPrecondition   Code = "failed_precondition"
+	CodePreconditionRequired Code = "precondition_required"
+)
+
+// FieldError ошибка конкретного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func Field(field, message string) FieldError {
+	return FieldError{Field: field, Message: message}
+}
+
+// Error ошибка, которую можно показать клиенту. Обычно объявляется как переменная-эталон
+// (var ErrX = apperror.New(...)) и сравнивается через errors.Is; подробности по полям
+// добавляет WithFields, не ломая сравнение.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	parent  *Error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WithFields копия ошибки с подробностями по полям; errors.Is(copy, e) остаётся истинным
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	return &Error{
+		Code:    e.Code,
+		Message: e.Message,
+		Fields:  append(append([]FieldError(nil), e.Fields...), fields...),
+		parent:  e,
+	}
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	details := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		details = append(details, f.Field+": "+f.Message)
+	}
+	return e.Message + " (" + strings.Join(details, "; ") + ")"
+}
+
+func (e *Error) Unwrap() error {
+	if e.parent == nil {
+		return nil
+	}
+	return e.parent
+}
+
+// As первая *Error в цепочке err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
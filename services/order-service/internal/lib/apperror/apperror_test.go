@@ -0,0 +1,79 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTest = New(CodeNotFound, "thing not found")
+
+func TestWithFields_KeepsIdentity(t *testing.T) {
+	err := fmt.Errorf("op: %w", errTest.WithFields(Field("id", "no such id")))
+
+	assert.ErrorIs(t, err, errTest)
+	assert.Empty(t, errTest.Fields, "sentinel must not be modified")
+
+	appErr, ok := As(err)
+	require.True(t, ok)
+	assert.Equal(t, CodeNotFound, appErr.Code)
+	assert.Equal(t, "thing not found (id: no such id)", appErr.Error())
+}
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "application error",
+			err:    fmt.Errorf("op: %w", errTest.WithFields(Field("id", "no such id"))),
+			status: http.StatusNotFound,
+			body: `{"type":"about:blank","title":"Not Found","status":404,"detail":"thing not found",
+				"instance":"/things/1","code":"not_found","error":"thing not found",
+				"errors":[{"field":"id","message":"no such id"}]}`,
+		},
+		{
+			name:   "unknown error hides details",
+			err:    errors.New("pq: connection refused"),
+			status: http.StatusInternalServerError,
+			body: `{"type":"about:blank","title":"Internal Server Error","status":500,
+				"detail":"internal server error","instance":"/things/1","code":"internal","error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteProblem(rec, httptest.NewRequest(http.MethodGet, "/things/1", nil), tt.err)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for code, status := range map[Code]int{
+		CodeInvalidArgument:      http.StatusBadRequest,
+		CodeUnauthenticated:      http.StatusUnauthorized,
+		CodePermissionDenied:     http.StatusForbidden,
+		CodeNotFound:             http.StatusNotFound,
+		CodeConflict:             http.StatusConflict,
+		CodeUnprocessable:        http.StatusUnprocessableEntity,
+		CodeUnavailable:          http.StatusServiceUnavailable,
+		CodeInternal:             http.StatusInternalServerError,
+		CodeFailedPrecondition:   http.StatusPreconditionFailed,
+		CodePreconditionRequired: http.StatusPreconditionRequired,
+	} {
+		assert.Equal(t, status, HTTPStatus(code), code)
+	}
+}
@@ -0,0 +1,81 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+var errInternal = New(CodeInternal, "internal server error")
+
+// Problem тело ответа об ошибке по RFC 7807. Поле Error дублирует Detail:
+// клиенты, написанные до перехода на problem+json, читают {"error": "..."}.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Error    string       `json:"error"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// HTTPStatus статус ответа для класса ошибки
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnprocessable:
+		return http.StatusUnprocessableEntity
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case CodePreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewProblem ошибки без *Error в цепочке считаются внутренними, их текст клиенту не показывается
+func NewProblem(err error, instance string) Problem {
+	appErr, ok := As(err)
+	if !ok {
+		appErr = errInternal
+	}
+
+	status := HTTPStatus(appErr.Code)
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   appErr.Message,
+		Instance: instance,
+		Code:     appErr.Code,
+		Error:    appErr.Message,
+		Errors:   appErr.Fields,
+	}
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(err, r.URL.Path)
+
+	if problem.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="order-service"`)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
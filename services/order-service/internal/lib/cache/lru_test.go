@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[int, string](2, time.Minute)
+
+	c.Set(1, "one")
+	c.Set(2, "two")
+	_, _ = c.Get(1) // 2 становится самым старым
+	c.Set(3, "three")
+
+	_, ok := c.Get(2)
+	assert.False(t, ok)
+
+	v, ok := c.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, "one", v)
+
+	assert.Equal(t, Stats{Size: 2, MaxSize: 2, Hits: 2, Misses: 1, Evictions: 1}, c.Stats())
+}
+
+func TestLRU_ExpiresEntries(t *testing.T) {
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	c := NewLRU[int, string](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(1, "one")
+	now = now.Add(59 * time.Second)
+	_, ok := c.Get(1)
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get(1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Stats().Size)
+}
+
+func TestLRU_Delete(t *testing.T) {
+	c := NewLRU[string, int](10, time.Minute)
+
+	c.Set("a", 1)
+	c.Delete("a")
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,219 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency валюта сумм в БД: колонки DECIMAL(10,2) не хранят код валюты
+const DefaultCurrency = "RUB"
+
+// scale количество минорных единиц в одной основной (копеек в рубле)
+const scale = 100
+
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+// Money денежная сумма в минорных единицах с кодом валюты.
+// Арифметика точная; округление происходит только при умножении на ставку и делении на доли.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Zero нулевая сумма в валюте по умолчанию
+func Zero() Money {
+	return Money{Currency: DefaultCurrency}
+}
+
+// Parse разбирает десятичную запись вида "123.45" без потери точности.
+// Больше двух знаков после точки не допускается.
+func Parse(s string, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || len(frac) > 2 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+
+	w, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	f, err := strconv.ParseUint(frac, 10, 63)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	amount := int64(w)*scale + int64(f)
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MustParse для констант и тестов
+func MustParse(s string, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// String десятичная запись без кода валюты, всегда с двумя знаками после точки
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/scale, amount%scale)
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add складывает суммы одной валюты. Разные валюты — ошибка программиста, поэтому panic.
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}
+}
+
+// Mul умножает на целое количество (цена × количество)
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRate умножает на ставку и округляет результат до минорных единиц
+func (m Money) MulRate(rate Rate, mode RoundingMode) Money {
+	return Money{Amount: divRound(m.Amount*int64(rate), rateScale, mode), Currency: m.Currency}
+}
+
+// Share доля part/whole суммы (whole > 0), округлённая до минорных единиц
+func (m Money) Share(part, whole int64, mode RoundingMode) Money {
+	return Money{Amount: divRound(m.Amount*part, whole, mode), Currency: m.Currency}
+}
+
+// Cmp возвращает -1, 0 или 1
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.Currency != other.Currency {
+		panic(fmt.Sprintf("money: currency mismatch %q vs %q", m.Currency, other.Currency))
+	}
+}
+
+// Sum складывает суммы; для пустого списка возвращает Zero
+func Sum(values ...Money) Money {
+	total := Zero()
+	if len(values) > 0 {
+		total.Currency = values[0].Currency
+	}
+	for _, v := range values {
+		total = total.Add(v)
+	}
+	return total
+}
+
+// Value сохраняет сумму в DECIMAL-колонку как точную десятичную строку
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan читает DECIMAL-колонку. Валюта в БД не хранится, поэтому проставляется DefaultCurrency.
+func (m *Money) Scan(src any) error {
+	var (
+		parsed Money
+		err    error
+	)
+
+	switch v := src.(type) {
+	case []byte:
+		parsed, err = Parse(string(v), DefaultCurrency)
+	case string:
+		parsed, err = Parse(v, DefaultCurrency)
+	case int64:
+		parsed = New(v*scale, DefaultCurrency)
+	case float64:
+		parsed, err = Parse(strconv.FormatFloat(v, 'f', 2, 64), DefaultCurrency)
+	case nil:
+		parsed = Zero()
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// MarshalJSON кодирует сумму JSON-числом с двумя знаками после точки ("630.00"),
+// чтобы клиенты, ожидающие число, продолжили работать
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку и разбирает их без float64
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	parsed, err := Parse(s, DefaultCurrency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// UnmarshalText позволяет задавать суммы строками в конфиге: "150.00"
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text), DefaultCurrency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,156 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{in: "0", want: 0},
+		{in: "12", want: 1200},
+		{in: "12.3", want: 1230},
+		{in: "12.34", want: 1234},
+		{in: "-0.05", want: -5},
+		{in: ".5", want: 50},
+		{in: "99999999.99", want: 9999999999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			m, err := Parse(tt.in, DefaultCurrency)
+			require.NoError(t, err)
+			assert.Equal(t, New(tt.want, DefaultCurrency), m)
+		})
+	}
+
+	for _, in := range []string{"", "-", "1.234", "abc", "1.2.3", "1e3"} {
+		_, err := Parse(in, DefaultCurrency)
+		assert.ErrorIs(t, err, ErrInvalidAmount, in)
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "0.00", Zero().String())
+	assert.Equal(t, "630.00", New(63000, DefaultCurrency).String())
+	assert.Equal(t, "-0.07", New(-7, DefaultCurrency).String())
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := MustParse("0.10", DefaultCurrency)
+	b := MustParse("0.20", DefaultCurrency)
+
+	// классический 0.1 + 0.2 без дрейфа float64
+	assert.Equal(t, MustParse("0.30", DefaultCurrency), a.Add(b))
+	assert.Equal(t, MustParse("-0.10", DefaultCurrency), a.Sub(b))
+	assert.Equal(t, MustParse("0.60", DefaultCurrency), b.Mul(3))
+	assert.Equal(t, -1, a.Cmp(b))
+
+	assert.Panics(t, func() { a.Add(New(10, "USD")) })
+}
+
+func TestMoney_MulRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		rate   Rate
+		mode   RoundingMode
+		want   int64
+	}{
+		{name: "exact", amount: 10000, rate: Percent(10), mode: RoundHalfUp, want: 1000},
+		{name: "half up", amount: 5, rate: Percent(10), mode: RoundHalfUp, want: 1},
+		{name: "half up negative", amount: -5, rate: Percent(10), mode: RoundHalfUp, want: -1},
+		{name: "half even to even", amount: 5, rate: Percent(10), mode: RoundHalfEven, want: 0},
+		{name: "half even to odd neighbour", amount: 15, rate: Percent(10), mode: RoundHalfEven, want: 2},
+		{name: "below half", amount: 14, rate: Percent(10), mode: RoundHalfUp, want: 1},
+		{name: "down", amount: 19, rate: Percent(10), mode: RoundDown, want: 1},
+		{name: "fractional rate", amount: 1999, rate: 750, mode: RoundHalfUp, want: 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, DefaultCurrency).MulRate(tt.rate, tt.mode)
+			assert.Equal(t, tt.want, got.Amount)
+		})
+	}
+}
+
+func TestMoney_Share(t *testing.T) {
+	tax := MustParse("45.56", DefaultCurrency)
+
+	assert.Equal(t, MustParse("22.78", DefaultCurrency), tax.Share(1, 2, RoundHalfUp))
+	// 45.56 × 1/3 = 15.1866…
+	assert.Equal(t, MustParse("15.19", DefaultCurrency), tax.Share(1, 3, RoundHalfUp))
+	assert.Equal(t, MustParse("15.18", DefaultCurrency), tax.Share(1, 3, RoundDown))
+	assert.Equal(t, tax, tax.Share(7, 7, RoundHalfUp))
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{in: "10%", want: 1000},
+		{in: "7.5%", want: 750},
+		{in: "0.2", want: 2000},
+		{in: "0.075", want: 750},
+		{in: "0.07", want: 700},
+		{in: "0.57", want: 5700},
+		{in: "0.0001", want: 1},
+		{in: "1", want: 10000},
+		{in: "0", want: 0},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, in := range []string{"", "-1%", "abc", "0.00001", "1.234%", "-0.1", "+0.1", ".", "0.1.2"} {
+		_, err := ParseRate(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{Price: New(63000, DefaultCurrency)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"price": 630.0}`, string(data))
+
+	var decoded struct {
+		Price Money `json:"price"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"price": 19.99}`), &decoded))
+	assert.Equal(t, New(1999, DefaultCurrency), decoded.Price)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"price": "0.10"}`), &decoded))
+	assert.Equal(t, New(10, DefaultCurrency), decoded.Price)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"price": 0.001}`), &decoded))
+}
+
+func TestMoney_Scan(t *testing.T) {
+	var m Money
+
+	require.NoError(t, m.Scan([]byte("1234.56")))
+	assert.Equal(t, New(123456, DefaultCurrency), m)
+
+	require.NoError(t, m.Scan(float64(0.3)))
+	assert.Equal(t, New(30, DefaultCurrency), m)
+
+	require.NoError(t, m.Scan(int64(5)))
+	assert.Equal(t, New(500, DefaultCurrency), m)
+
+	v, err := New(123456, DefaultCurrency).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "1234.56", v)
+}
@@ -0,0 +1,110 @@
+package money
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rateScale ставки хранятся в сотых долях процента (базисных пунктах)
+const rateScale = 10000
+
+// Rate ставка (налог, скидка) в базисных пунктах: 10% = 1000, 7.5% = 750
+type Rate int64
+
+func Percent(p int64) Rate {
+	return Rate(p * 100)
+}
+
+// ParseRate разбирает "10%", "7.5%" или долю "0.075"
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		// проценты с точностью до сотых — та же запись, что и у денег
+		m, err := Parse(p, "")
+		if err != nil || m.IsNegative() {
+			return 0, fmt.Errorf("invalid rate %q", s)
+		}
+		return Rate(m.Amount), nil
+	}
+
+	// доля разбирается по цифрам, как и деньги: через float64 "0.07" превращается в 699.99… б.п.
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > 4 {
+		return 0, fmt.Errorf("rate %q is more precise than a basis point", s)
+	}
+	frac += strings.Repeat("0", 4-len(frac))
+
+	w, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	f, err := strconv.ParseUint(frac, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return Rate(int64(w)*rateScale + int64(f)), nil
+}
+
+// String запись в процентах: "7.50%"
+func (r Rate) String() string {
+	return Money{Amount: int64(r)}.String() + "%"
+}
+
+// UnmarshalText позволяет задавать ставки строками в конфиге
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// RoundingMode правило округления до минорных единиц
+type RoundingMode int
+
+const (
+	// RoundHalfUp коммерческое округление: половина — от нуля
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven банковское округление: половина — к чётному
+	RoundHalfEven
+	// RoundDown отбрасывание дробной части (к нулю)
+	RoundDown
+)
+
+// divRound делит n на d (d > 0) с заданным правилом округления
+func divRound(n, d int64, mode RoundingMode) int64 {
+	q, r := n/d, n%d
+	if r == 0 {
+		return q
+	}
+
+	sign := int64(1)
+	if n < 0 {
+		sign, r = -1, -r
+	}
+
+	switch mode {
+	case RoundDown:
+		return q
+	case RoundHalfEven:
+		if 2*r > d || 2*r == d && q%2 != 0 {
+			return q + sign
+		}
+		return q
+	default:
+		if 2*r >= d {
+			return q + sign
+		}
+		return q
+	}
+}
@@ -0,0 +1,68 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// Bulkhead ограничивает число одновременных вызовов зависимости, чтобы медленная
+// зависимость не заняла все горутины обработчиков
+type Bulkhead struct {
+	slots       chan struct{}
+	waitTimeout time.Duration
+}
+
+// NewBulkhead maxConcurrent одновременных вызовов; остальные ждут свободного места
+// не дольше waitTimeout (0 — не ждать)
+func NewBulkhead(maxConcurrent int, waitTimeout time.Duration) *Bulkhead {
+	return &Bulkhead{
+		slots:       make(chan struct{}, max(maxConcurrent, 1)),
+		waitTimeout: waitTimeout,
+	}
+}
+
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() { <-b.slots }()
+
+	return fn(ctx)
+}
+
+func (b *Bulkhead) wait(ctx context.Context) error {
+	if b.waitTimeout <= 0 {
+		return ErrBulkheadFull
+	}
+
+	timer := time.NewTimer(b.waitTimeout)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type BulkheadStatus struct {
+	InFlight      int `json:"in_flight"`
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
+func (b *Bulkhead) Status() BulkheadStatus {
+	return BulkheadStatus{
+		InFlight:      len(b.slots),
+		MaxConcurrent: cap(b.slots),
+	}
+}
@@ -0,0 +1,172 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// CircuitBreaker размыкается после FailureThreshold ошибок подряд и отклоняет вызовы
+// на OpenTimeout. Затем пропускает до HalfOpenProbes пробных вызовов: успех всех проб
+// замыкает цепь, любая ошибка снова размыкает.
+type CircuitBreaker struct {
+	name             string
+	log              *slog.Logger
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	// isFailure какие ошибки считать отказом зависимости; nil — любые
+	isFailure func(err error) bool
+	now       func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	inFlight  int
+	successes int
+}
+
+type CircuitBreakerOptions struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int
+	IsFailure        func(err error) bool
+}
+
+func NewCircuitBreaker(name string, log *slog.Logger, opts CircuitBreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:             name,
+		log:              log,
+		failureThreshold: max(opts.FailureThreshold, 1),
+		openTimeout:      opts.OpenTimeout,
+		halfOpenProbes:   max(opts.HalfOpenProbes, 1),
+		isFailure:        opts.IsFailure,
+		now:              time.Now,
+		state:            StateClosed,
+	}
+}
+
+// Execute вызывает fn, если цепь это позволяет, иначе сразу возвращает ErrCircuitOpen
+func (b *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.acquire(); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+	b.record(ctx, err)
+	return err
+}
+
+func (b *CircuitBreaker) acquire() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.transition(StateHalfOpen)
+	}
+
+	if b.state == StateHalfOpen {
+		if b.inFlight+b.successes >= b.halfOpenProbes {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+
+	return nil
+}
+
+// record учитывает результат вызова. Отмена вызывающим и переполнение ограничителя ничего
+// не говорят о зависимости: такой вызов не считается ни отказом, ни успехом.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && (ctx.Err() != nil || errors.Is(err, ErrBulkheadFull)) {
+		if b.state == StateHalfOpen {
+			// проба не состоялась — место освобождается для следующей
+			b.inFlight--
+		}
+		return
+	}
+
+	failed := err != nil && (b.isFailure == nil || b.isFailure(err))
+
+	switch b.state {
+	case StateHalfOpen:
+		b.inFlight--
+		if failed {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenProbes {
+			b.transition(StateClosed)
+		}
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.transition(StateOpen)
+		}
+	case StateOpen:
+		// вызов начался до размыкания цепи — результат уже не важен
+	}
+}
+
+// transition вызывается под b.mu
+func (b *CircuitBreaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.failures = 0
+	b.successes = 0
+	b.inFlight = 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+
+	b.log.Warn("circuit breaker state changed",
+		slog.String("breaker", b.name),
+		slog.String("from", string(from)),
+		slog.String("to", string(to)),
+	)
+}
+
+type CircuitBreakerStatus struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+func (b *CircuitBreaker) Status() CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := CircuitBreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
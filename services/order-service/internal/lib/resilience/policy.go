@@ -0,0 +1,90 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type Options struct {
+	MaxAttempts      int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int
+	MaxConcurrent    int
+	WaitTimeout      time.Duration
+}
+
+// Policy защищает вызовы одной внешней зависимости.
+// Порядок: повтор -> ограничитель параллелизма -> предохранитель, то есть каждая попытка
+// отдельно учитывается предохранителем и занимает слот только на время самого вызова,
+// а отказ ограничителя до предохранителя не доходит и не засчитывается ему как успех.
+type Policy struct {
+	name     string
+	retry    Retry
+	breaker  *CircuitBreaker
+	bulkhead *Bulkhead
+}
+
+// NewPolicy isTransient отличает временные отказы зависимости (повторяем, считаем предохранителем)
+// от ошибок бизнес-логики вроде «не найдено»
+func NewPolicy(name string, log *slog.Logger, opts Options, isTransient func(err error) bool) *Policy {
+	log = log.With(slog.String("dependency", name))
+
+	return &Policy{
+		name: name,
+		retry: Retry{
+			MaxAttempts: opts.MaxAttempts,
+			BaseDelay:   opts.BaseDelay,
+			MaxDelay:    opts.MaxDelay,
+			Retryable: func(err error) bool {
+				// при разомкнутой цепи или переполнении повтор только добавит нагрузки
+				if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+					return false
+				}
+				return isTransient(err)
+			},
+			OnRetry: func(attempt int, delay time.Duration, err error) {
+				log.Warn("retrying call",
+					slog.Int("attempt", attempt),
+					slog.Duration("delay", delay),
+					slog.String("error", err.Error()),
+				)
+			},
+		},
+		breaker: NewCircuitBreaker(name, log, CircuitBreakerOptions{
+			FailureThreshold: opts.FailureThreshold,
+			OpenTimeout:      opts.OpenTimeout,
+			HalfOpenProbes:   opts.HalfOpenProbes,
+			IsFailure:        isTransient,
+		}),
+		bulkhead: NewBulkhead(opts.MaxConcurrent, opts.WaitTimeout),
+	}
+}
+
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.retry.Do(ctx, func(ctx context.Context) error {
+		return p.bulkhead.Execute(ctx, func(ctx context.Context) error {
+			return p.breaker.Execute(ctx, fn)
+		})
+	})
+}
+
+func (p *Policy) Name() string {
+	return p.name
+}
+
+type PolicyStatus struct {
+	CircuitBreaker CircuitBreakerStatus `json:"circuit_breaker"`
+	Bulkhead       BulkheadStatus       `json:"bulkhead"`
+}
+
+func (p *Policy) Status() any {
+	return PolicyStatus{
+		CircuitBreaker: p.breaker.Status(),
+		Bulkhead:       p.bulkhead.Status(),
+	}
+}
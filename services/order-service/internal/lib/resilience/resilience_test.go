@@ -0,0 +1,293 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestRetry_Do(t *testing.T) {
+	ctx := context.Background()
+	retry := Retry{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Retryable: isTransient}
+
+	t.Run("succeeds after transient errors", func(t *testing.T) {
+		calls := 0
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			calls++
+			return errTransient
+		})
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		calls := 0
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			calls++
+			return errPermanent
+		})
+		assert.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stops waiting on cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		slow := Retry{MaxAttempts: 5, BaseDelay: time.Hour, Retryable: isTransient}
+		calls := 0
+		err := slow.Do(ctx, func(ctx context.Context) error {
+			calls++
+			return errTransient
+		})
+		assert.ErrorIs(t, err, errTransient)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	retry := Retry{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, upper := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		5:  time.Second,
+		40: time.Second,
+	} {
+		delay := retry.backoff(attempt)
+		assert.LessOrEqual(t, delay, upper, "attempt %d", attempt)
+		assert.GreaterOrEqual(t, delay, upper/2, "attempt %d", attempt)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+
+	breaker := NewCircuitBreaker("test", slogdiscard.NewDiscardLogger(), CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+		IsFailure:        isTransient,
+	})
+	breaker.now = func() time.Time { return now }
+
+	fail := func(ctx context.Context) error { return errTransient }
+	ok := func(ctx context.Context) error { return nil }
+
+	// бизнес-ошибки не размыкают цепь
+	for range 5 {
+		assert.ErrorIs(t, breaker.Execute(ctx, func(ctx context.Context) error { return errPermanent }), errPermanent)
+	}
+	assert.Equal(t, StateClosed, breaker.Status().State)
+
+	assert.ErrorIs(t, breaker.Execute(ctx, fail), errTransient)
+	assert.ErrorIs(t, breaker.Execute(ctx, fail), errTransient)
+	assert.Equal(t, StateOpen, breaker.Status().State)
+
+	called := false
+	err := breaker.Execute(ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, called)
+
+	// после OpenTimeout пробный вызов с ошибкой снова размыкает цепь
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, breaker.Execute(ctx, fail), errTransient)
+	assert.Equal(t, StateOpen, breaker.Status().State)
+	assert.ErrorIs(t, breaker.Execute(ctx, ok), ErrCircuitOpen)
+
+	// успешная проба замыкает цепь
+	now = now.Add(time.Minute)
+	require.NoError(t, breaker.Execute(ctx, ok))
+	assert.Equal(t, StateClosed, breaker.Status().State)
+}
+
+func TestCircuitBreaker_HalfOpenLimitsProbes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	breaker := NewCircuitBreaker("test", slogdiscard.NewDiscardLogger(), CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Second,
+		HalfOpenProbes:   1,
+	})
+	breaker.now = func() time.Time { return now }
+
+	_ = breaker.Execute(ctx, func(ctx context.Context) error { return errTransient })
+	now = now.Add(time.Second)
+
+	probeStarted := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- breaker.Execute(ctx, func(ctx context.Context) error {
+			close(probeStarted)
+			<-release
+			return nil
+		})
+	}()
+
+	<-probeStarted
+	assert.Equal(t, StateHalfOpen, breaker.Status().State)
+	assert.ErrorIs(t, breaker.Execute(ctx, func(ctx context.Context) error { return nil }), ErrCircuitOpen)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, StateClosed, breaker.Status().State)
+}
+
+func TestBulkhead(t *testing.T) {
+	ctx := context.Background()
+	bulkhead := NewBulkhead(2, 10*time.Millisecond)
+
+	var started sync.WaitGroup
+	started.Add(2)
+	release := make(chan struct{})
+	var finished sync.WaitGroup
+
+	for range 2 {
+		finished.Add(1)
+		go func() {
+			defer finished.Done()
+			_ = bulkhead.Execute(ctx, func(ctx context.Context) error {
+				started.Done()
+				<-release
+				return nil
+			})
+		}()
+	}
+	started.Wait()
+
+	assert.Equal(t, BulkheadStatus{InFlight: 2, MaxConcurrent: 2}, bulkhead.Status())
+	assert.ErrorIs(t, bulkhead.Execute(ctx, func(ctx context.Context) error { return nil }), ErrBulkheadFull)
+
+	close(release)
+	finished.Wait()
+
+	require.NoError(t, bulkhead.Execute(ctx, func(ctx context.Context) error { return nil }))
+	assert.Equal(t, 0, bulkhead.Status().InFlight)
+}
+
+func TestPolicy_DoesNotRetryOpenCircuit(t *testing.T) {
+	policy := NewPolicy("test", slogdiscard.NewDiscardLogger(), Options{
+		MaxAttempts:      5,
+		BaseDelay:        time.Millisecond,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Hour,
+		MaxConcurrent:    1,
+	}, isTransient)
+
+	calls := 0
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, calls, "retries must stop once the breaker opens")
+	assert.Equal(t, StateOpen, policy.Status().(PolicyStatus).CircuitBreaker.State)
+}
+
+func TestCircuitBreaker_NeutralOutcomes(t *testing.T) {
+	now := time.Now()
+
+	breaker := NewCircuitBreaker("test", slogdiscard.NewDiscardLogger(), CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Second,
+		HalfOpenProbes:   1,
+	})
+	breaker.now = func() time.Time { return now }
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// нейтральный исход не сбрасывает счётчик ошибок подряд
+	_ = breaker.Execute(context.Background(), func(ctx context.Context) error { return errTransient })
+	_ = breaker.Execute(cancelled, func(ctx context.Context) error { return ctx.Err() })
+	_ = breaker.Execute(context.Background(), func(ctx context.Context) error { return ErrBulkheadFull })
+	assert.Equal(t, 1, breaker.Status().ConsecutiveFailures)
+	_ = breaker.Execute(context.Background(), func(ctx context.Context) error { return errTransient })
+	assert.Equal(t, StateOpen, breaker.Status().State)
+
+	// проба, отклонённая ограничителем или отменённая вызывающим, не замыкает цепь
+	now = now.Add(time.Second)
+	assert.ErrorIs(t, breaker.Execute(context.Background(), func(ctx context.Context) error { return ErrBulkheadFull }), ErrBulkheadFull)
+	assert.Equal(t, StateHalfOpen, breaker.Status().State)
+	_ = breaker.Execute(cancelled, func(ctx context.Context) error { return ctx.Err() })
+	assert.Equal(t, StateHalfOpen, breaker.Status().State)
+
+	// место пробы освободилось: настоящая проба с ошибкой снова размыкает цепь
+	assert.ErrorIs(t, breaker.Execute(context.Background(), func(ctx context.Context) error { return errTransient }), errTransient)
+	assert.Equal(t, StateOpen, breaker.Status().State)
+}
+
+func TestPolicy_BulkheadRejectionIsNotAProbe(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	policy := NewPolicy("test", slogdiscard.NewDiscardLogger(), Options{
+		MaxAttempts:      1,
+		FailureThreshold: 1,
+		OpenTimeout:      time.Second,
+		HalfOpenProbes:   1,
+		MaxConcurrent:    1,
+	}, isTransient)
+	policy.breaker.now = func() time.Time { return now }
+
+	assert.ErrorIs(t, policy.Do(ctx, func(ctx context.Context) error { return errTransient }), errTransient)
+	now = now.Add(time.Second)
+
+	// слот занят, поэтому проба не доходит до предохранителя
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- policy.bulkhead.Execute(ctx, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	assert.ErrorIs(t, policy.Do(ctx, func(ctx context.Context) error { return nil }), ErrBulkheadFull)
+	assert.NotEqual(t, StateClosed, policy.Status().(PolicyStatus).CircuitBreaker.State)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	assert.ErrorIs(t, policy.Do(ctx, func(ctx context.Context) error { return errTransient }), errTransient)
+	assert.Equal(t, StateOpen, policy.Status().(PolicyStatus).CircuitBreaker.State)
+}
@@ -0,0 +1,63 @@
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Retry повторяет вызов с экспоненциальной задержкой.
+// Повторять стоит только идемпотентные операции.
+type Retry struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable решает, стоит ли повторять после ошибки; nil — повторять любую
+	Retryable func(err error) bool
+	// OnRetry вызывается перед очередной попыткой, например для логирования
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// Do выполняет fn не более MaxAttempts раз. Ожидание прерывается отменой ctx.
+func (r Retry) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := max(r.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		if attempt >= attempts || r.Retryable != nil && !r.Retryable(err) {
+			return err
+		}
+
+		delay := r.backoff(attempt)
+		if r.OnRetry != nil {
+			r.OnRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff BaseDelay * 2^(attempt-1), не больше MaxDelay, со случайным разбросом в верхней половине,
+// чтобы клиенты не повторяли запросы синхронно
+func (r Retry) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay << min(attempt-1, 30)
+	if delay <= 0 || r.MaxDelay > 0 && delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
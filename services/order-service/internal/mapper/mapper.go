@@ -39,6 +39,15 @@ func mapToOrderItemResponseFromOrderItem(orderItem *domain.OrderItem) *dto.Order
 	}
 }
 
+func mapToPriceLineResponseFromPriceLine(line *domain.OrderPriceLine) *dto.PriceLineResponse {
+	return &dto.PriceLineResponse{
+		Kind:        line.Kind,
+		Rule:        line.Rule,
+		Description: line.Description,
+		Amount:      line.Amount,
+	}
+}
+
 func MapToOrderResponseFromOrder(order *domain.Order) *dto.OrderResponse {
 	var orderItemsRes []*dto.OrderItemResponse
 
@@ -47,16 +56,121 @@ func MapToOrderResponseFromOrder(order *domain.Order) *dto.OrderResponse {
 		orderItemsRes = append(orderItemsRes, orderItemRes)
 	}
 
+	var priceLinesRes []*dto.PriceLineResponse
+
+	for _, line := range order.PriceLines {
+		priceLinesRes = append(priceLinesRes, mapToPriceLineResponseFromPriceLine(line))
+	}
+
 	return &dto.OrderResponse{
-		ID:            order.ID,
-		PaymentMethod: order.PaymentMethod,
-		TaxPrice:      order.TaxPrice,
-		ShippingPrice: order.ShippingPrice,
-		TotalPrice:    order.TotalPrice,
-		Items:         orderItemsRes,
-		UserID:        order.UserID,
-		Status:        order.Status,
-		CreatedAt:     order.CreatedAt,
-		UpdatedAt:     order.UpdatedAt,
+		ID:                 order.ID,
+		PaymentMethod:      order.PaymentMethod,
+		PromoCode:          order.PromoCode,
+		DiscountPrice:      order.DiscountPrice,
+		TaxPrice:           order.TaxPrice,
+		ShippingPrice:      order.ShippingPrice,
+		TotalPrice:         order.TotalPrice,
+		Currency:           order.TotalPrice.Currency,
+		Items:              orderItemsRes,
+		PriceLines:         priceLinesRes,
+		UserID:             order.UserID,
+		Status:             order.Status,
+		CreatedAt:          order.CreatedAt,
+		UpdatedAt:          order.UpdatedAt,
+		CancellationReason: order.CancellationReason,
+		ShippingAddress:    MapToDTOAddressFromAddress(order.ShippingAddress),
+		BillingAddress:     MapToDTOAddressFromAddress(order.BillingAddress),
+		Version:            order.Version,
+	}
+}
+
+func MapToReturnResponseFromReturn(ret *domain.Return) *dto.ReturnResponse {
+	items := make([]*dto.ReturnItemResponse, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, &dto.ReturnItemResponse{
+			ID:          item.ID,
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Amount:      item.Amount,
+		})
+	}
+
+	return &dto.ReturnResponse{
+		ID:             ret.ID,
+		OrderID:        ret.OrderID,
+		Status:         ret.Status,
+		Reason:         ret.Reason,
+		RequestedBy:    ret.RequestedBy,
+		ItemsAmount:    ret.ItemsAmount,
+		TaxAmount:      ret.TaxAmount,
+		ShippingAmount: ret.ShippingAmount,
+		TotalAmount:    ret.TotalAmount,
+		CreatedAt:      ret.CreatedAt,
+		UpdatedAt:      ret.UpdatedAt,
+		Items:          items,
+	}
+}
+
+func MapToShipmentResponseFromShipment(shipment *domain.Shipment) *dto.ShipmentResponse {
+	items := make([]*dto.ShipmentItemResponse, 0, len(shipment.Items))
+	for _, item := range shipment.Items {
+		items = append(items, &dto.ShipmentItemResponse{
+			ID:          item.ID,
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	return &dto.ShipmentResponse{
+		ID:             shipment.ID,
+		OrderID:        shipment.OrderID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		LabelURL:       shipment.LabelURL,
+		Status:         shipment.Status,
+		CreatedAt:      shipment.CreatedAt,
+		UpdatedAt:      shipment.UpdatedAt,
+		DeliveredAt:    shipment.DeliveredAt,
+		Items:          items,
+	}
+}
+
+// MapToDTOAddressFromAddress nil для заказов без адреса
+func MapToDTOAddressFromAddress(address *domain.Address) *dto.Address {
+	if address == nil {
+		return nil
+	}
+	return &dto.Address{
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		Region:     address.Region,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+	}
+}
+
+func MapToAddressFromDTOAddress(address *dto.Address) domain.Address {
+	return domain.Address{
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		Region:     address.Region,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+	}
+}
+
+func MapToAddressResponseFromSavedAddress(address *domain.SavedAddress) *dto.AddressResponse {
+	return &dto.AddressResponse{
+		ID:        address.ID,
+		Label:     address.Label,
+		Address:   *MapToDTOAddressFromAddress(&address.Address),
+		CreatedAt: address.CreatedAt,
+		UpdatedAt: address.UpdatedAt,
 	}
 }
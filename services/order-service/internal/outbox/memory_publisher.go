@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// MemoryPublisher хранит опубликованные события в памяти; для тестов и локального запуска
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Envelope
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, NewEnvelope(msg))
+	return nil
+}
+
+// Messages копия опубликованных событий в порядке публикации
+func (p *MemoryPublisher) Messages() []Envelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Envelope(nil), p.messages...)
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// Publisher доставляет событие во внешнюю систему (брокер, файл и т.п.).
+// Доставка «хотя бы один раз»: событие может прийти повторно, потребители дедуплицируют по ID.
+type Publisher interface {
+	Publish(ctx context.Context, msg *domain.OutboxMessage) error
+}
+
+// Envelope формат события для потребителей
+type Envelope struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int64           `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func NewEnvelope(msg *domain.OutboxMessage) Envelope {
+	return Envelope{
+		ID:            msg.ID,
+		AggregateType: msg.AggregateType,
+		AggregateID:   msg.AggregateID,
+		EventType:     msg.EventType,
+		Payload:       msg.Payload,
+		CreatedAt:     msg.CreatedAt,
+	}
+}
@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+type Storage interface {
+	FetchUnpublishedOutbox(ctx context.Context, limit int, now time.Time) ([]*domain.OutboxMessage, error)
+	MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error
+	MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	MarkOutboxDead(ctx context.Context, id int64, reason string, at time.Time) error
+}
+
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Relay периодически забирает неопубликованные события из outbox и отправляет их в Publisher.
+// Событие помечается опубликованным только после успешной отправки, поэтому при сбое
+// между отправкой и фиксацией транзакции оно уйдёт повторно (at-least-once).
+// Неудачная отправка повторяется с растущей задержкой, а после maxAttempts попыток событие
+// помечается мёртвым, чтобы одно неотправляемое событие не останавливало релей.
+type Relay struct {
+	log            *slog.Logger
+	storage        Storage
+	txManager      TxManager
+	publisher      Publisher
+	pollInterval   time.Duration
+	batchSize      int
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	now            func() time.Time
+}
+
+func NewRelay(
+	log *slog.Logger,
+	storage Storage,
+	txManager TxManager,
+	publisher Publisher,
+	cfg config.OutboxConfig,
+) *Relay {
+	return &Relay{
+		log:            log.With(slog.String("component", "outbox-relay")),
+		storage:        storage,
+		txManager:      txManager,
+		publisher:      publisher,
+		pollInterval:   cfg.PollInterval,
+		batchSize:      max(cfg.BatchSize, 1),
+		maxAttempts:    max(cfg.MaxAttempts, 1),
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+		now:            time.Now,
+	}
+}
+
+// Run публикует события до отмены ctx. Пока пачки что-то публикуют, следующая обрабатывается сразу:
+// в пачку попадает только одно событие агрегата, и следующие события заказа ждут следующей пачки.
+// Иначе релей ждёт pollInterval.
+func (r *Relay) Run(ctx context.Context) {
+	r.log.Info("outbox relay started")
+
+	for {
+		published, err := r.PublishBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error("failed to publish outbox batch", slog.String("error", err.Error()))
+		}
+
+		if err == nil && published > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.Info("outbox relay stopped")
+			return
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// PublishBatch отправляет одну пачку событий в порядке записи. Неудачное событие откладывается
+// до следующей попытки и до тех пор держит следующие события своего агрегата (их не выбирает
+// FetchUnpublishedOutbox); события других агрегатов отправляются дальше.
+func (r *Relay) PublishBatch(ctx context.Context) (int, error) {
+	const op = "outbox.Relay.PublishBatch"
+
+	var (
+		published  int
+		failed     int
+		publishErr error
+	)
+
+	err := r.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := r.now()
+		messages, err := r.storage.FetchUnpublishedOutbox(ctx, r.batchSize, now)
+		if err != nil {
+			return fmt.Errorf("failed to fetch outbox: %w", err)
+		}
+
+		ids := make([]int64, 0, len(messages))
+
+		for _, msg := range messages {
+			if err := r.publisher.Publish(ctx, msg); err != nil {
+				if publishErr == nil {
+					publishErr = err
+				}
+				failed++
+				if err := r.markFailed(ctx, msg, err, now); err != nil {
+					return err
+				}
+				continue
+			}
+			ids = append(ids, msg.ID)
+		}
+
+		if len(ids) > 0 {
+			if err := r.storage.MarkOutboxPublished(ctx, ids, r.now()); err != nil {
+				return fmt.Errorf("failed to mark outbox published: %w", err)
+			}
+		}
+
+		// отметки об успешных и неудачных отправках фиксируем даже при ошибке публикации
+		published = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if publishErr != nil {
+		return published, fmt.Errorf("%s: failed to publish %d events: %w", op, failed, publishErr)
+	}
+
+	return published, nil
+}
+
+// markFailed откладывает событие до следующей попытки или, если попытки кончились, помечает мёртвым
+func (r *Relay) markFailed(ctx context.Context, msg *domain.OutboxMessage, publishErr error, now time.Time) error {
+	attempts := msg.Attempts + 1
+
+	if attempts >= r.maxAttempts {
+		r.log.Error("outbox event is dead, giving up",
+			slog.Int64("outbox_id", msg.ID),
+			slog.String("event_type", msg.EventType),
+			slog.Int("attempts", attempts),
+			slog.String("error", publishErr.Error()),
+		)
+		if err := r.storage.MarkOutboxDead(ctx, msg.ID, publishErr.Error(), now); err != nil {
+			return fmt.Errorf("failed to mark outbox message dead: %w", err)
+		}
+		return nil
+	}
+
+	delay := r.retryDelay(attempts)
+	r.log.Warn("failed to publish event",
+		slog.Int64("outbox_id", msg.ID),
+		slog.String("event_type", msg.EventType),
+		slog.Int("attempts", attempts),
+		slog.Duration("retry_in", delay),
+		slog.String("error", publishErr.Error()),
+	)
+	if err := r.storage.MarkOutboxFailed(ctx, msg.ID, publishErr.Error(), now.Add(delay)); err != nil {
+		return fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+	return nil
+}
+
+// retryDelay retryBaseDelay, удваивающаяся с каждой неудачной попыткой, не больше retryMaxDelay
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	delay := r.retryBaseDelay
+	for i := 1; i < attempts && (r.retryMaxDelay <= 0 || delay < r.retryMaxDelay); i++ {
+		delay *= 2
+	}
+	if r.retryMaxDelay > 0 && delay > r.retryMaxDelay {
+		delay = r.retryMaxDelay
+	}
+	return delay
+}
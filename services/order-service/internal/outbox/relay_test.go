@@ -0,0 +1,248 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage хранит outbox в памяти; отметки применяются сразу, без транзакций
+type fakeStorage struct {
+	messages []*domain.OutboxMessage
+	failures map[int64]string
+}
+
+// FetchUnpublishedOutbox повторяет условия запроса: от агрегата выбирается только самое раннее
+// неопубликованное живое событие, и только когда наступил его next_attempt_at
+func (f *fakeStorage) FetchUnpublishedOutbox(ctx context.Context, limit int, now time.Time) ([]*domain.OutboxMessage, error) {
+	var result []*domain.OutboxMessage
+	seen := make(map[int64]bool)
+	for _, msg := range f.messages {
+		if msg.PublishedAt != nil || msg.DeadAt != nil || seen[msg.AggregateID] {
+			continue
+		}
+		seen[msg.AggregateID] = true
+		if msg.NextAttemptAt != nil && msg.NextAttemptAt.After(now) {
+			continue
+		}
+		if len(result) < limit {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStorage) MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error {
+	for _, msg := range f.messages {
+		for _, id := range ids {
+			if msg.ID == id {
+				msg.PublishedAt = &at
+				msg.Attempts++
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	msg := f.fail(id, reason)
+	msg.NextAttemptAt = &retryAt
+	return nil
+}
+
+func (f *fakeStorage) MarkOutboxDead(ctx context.Context, id int64, reason string, at time.Time) error {
+	msg := f.fail(id, reason)
+	msg.NextAttemptAt, msg.DeadAt = nil, &at
+	return nil
+}
+
+func (f *fakeStorage) fail(id int64, reason string) *domain.OutboxMessage {
+	if f.failures == nil {
+		f.failures = make(map[int64]string)
+	}
+	f.failures[id] = reason
+	for _, msg := range f.messages {
+		if msg.ID == id {
+			msg.Attempts++
+			return msg
+		}
+	}
+	panic("unknown outbox message")
+}
+
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// failingPublisher отклоняет события с указанным ID
+type failingPublisher struct {
+	*MemoryPublisher
+	failID int64
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	if msg.ID == p.failID {
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, msg)
+}
+
+func newMessages(n int) []*domain.OutboxMessage {
+	messages := make([]*domain.OutboxMessage, 0, n)
+	for i := 1; i <= n; i++ {
+		messages = append(messages, &domain.OutboxMessage{
+			ID:            int64(i),
+			AggregateType: domain.AggregateTypeOrder,
+			AggregateID:   int64(i),
+			EventType:     domain.EventOrderCreated,
+			Payload:       []byte(`{"order_id":1}`),
+		})
+	}
+	return messages
+}
+
+func relayConfig(batchSize int) config.OutboxConfig {
+	return config.OutboxConfig{
+		PollInterval:   time.Second,
+		BatchSize:      batchSize,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  time.Minute,
+	}
+}
+
+func TestRelay_PublishBatch(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeStorage{messages: newMessages(3)}
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(slogdiscard.NewDiscardLogger(), storage, fakeTxManager{}, publisher, relayConfig(2))
+
+	published, err := relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	published, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	published, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	messages := publisher.Messages()
+	require.Len(t, messages, 3)
+	for i, msg := range messages {
+		assert.Equal(t, int64(i+1), msg.ID)
+	}
+}
+
+func TestRelay_PublishErrorHoldsOnlyItsAggregate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	// события 2 и 3 относятся к одному заказу
+	messages := newMessages(4)
+	messages[2].AggregateID = messages[1].AggregateID
+	storage := &fakeStorage{messages: messages}
+	publisher := &failingPublisher{MemoryPublisher: NewMemoryPublisher(), failID: 2}
+	relay := NewRelay(slogdiscard.NewDiscardLogger(), storage, fakeTxManager{}, publisher, relayConfig(10))
+	relay.now = func() time.Time { return now }
+
+	published, err := relay.PublishBatch(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 2, published)
+
+	assert.NotNil(t, messages[0].PublishedAt)
+	assert.Nil(t, messages[1].PublishedAt)
+	assert.Nil(t, messages[2].PublishedAt, "later events of the order must wait for the failed one")
+	assert.NotNil(t, messages[3].PublishedAt, "other orders are not blocked")
+	assert.Equal(t, "broker unavailable", storage.failures[2])
+	require.NotNil(t, messages[1].NextAttemptAt)
+	assert.Equal(t, now.Add(time.Second), *messages[1].NextAttemptAt)
+
+	// до next_attempt_at событие и его заказ не отправляются, даже если брокер восстановился
+	publisher.failID = 0
+	published, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	// событие 3 выбирается только после того, как опубликовано событие 2
+	now = now.Add(time.Second)
+	published, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	published, err = relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	sent := publisher.Messages()
+	require.Len(t, sent, 4)
+	assert.Equal(t, int64(2), sent[2].ID)
+	assert.Equal(t, int64(3), sent[3].ID)
+}
+
+func TestRelay_DeadAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	messages := newMessages(2)
+	messages[1].AggregateID = messages[0].AggregateID
+	storage := &fakeStorage{messages: messages}
+	publisher := &failingPublisher{MemoryPublisher: NewMemoryPublisher(), failID: 1}
+	relay := NewRelay(slogdiscard.NewDiscardLogger(), storage, fakeTxManager{}, publisher, relayConfig(10))
+	relay.now = func() time.Time { return now }
+
+	// задержки между попытками: 1s, затем 2s
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second} {
+		_, err := relay.PublishBatch(ctx)
+		assert.Error(t, err)
+		require.NotNil(t, messages[0].NextAttemptAt)
+		assert.Equal(t, now.Add(delay), *messages[0].NextAttemptAt)
+		now = now.Add(delay)
+	}
+
+	// третья попытка последняя: событие мёртвое и больше не держит свой заказ
+	_, err := relay.PublishBatch(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 3, messages[0].Attempts)
+	assert.NotNil(t, messages[0].DeadAt)
+	assert.Nil(t, messages[0].NextAttemptAt)
+
+	published, err := relay.PublishBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.NotNil(t, messages[1].PublishedAt)
+	assert.Nil(t, messages[0].PublishedAt)
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+
+	for _, msg := range newMessages(2) {
+		require.NoError(t, publisher.Publish(context.Background(), msg))
+	}
+	require.NoError(t, publisher.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t,
+		`{"id":1,"aggregate_type":"order","aggregate_id":1,"event_type":"OrderCreated","payload":{"order_id":1},"created_at":"0001-01-01T00:00:00Z"}`,
+		lines[0],
+	)
+}
@@ -0,0 +1,159 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+const (
+	fakeAuthorized = "authorized"
+	fakeCaptured   = "captured"
+	fakeVoided     = "voided"
+	fakeRefunded   = "refunded"
+)
+
+// FakeProvider провайдер в памяти для локального запуска и тестов: одобряет любой платёж
+// не больше declineAbove (nil — без ограничения)
+type FakeProvider struct {
+	name         string
+	methods      []string
+	declineAbove *money.Money
+
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+	byIdempotency  map[string]string
+	refunds        map[string]struct{}
+}
+
+type fakeAuthorization struct {
+	amount   money.Money
+	captured money.Money
+	refunded money.Money
+	state    string
+}
+
+func NewFakeProvider(name string, methods []string, declineAbove *money.Money) *FakeProvider {
+	return &FakeProvider{
+		name:           name,
+		methods:        methods,
+		declineAbove:   declineAbove,
+		authorizations: make(map[string]*fakeAuthorization),
+		byIdempotency:  make(map[string]string),
+		refunds:        make(map[string]struct{}),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return p.name
+}
+
+func (p *FakeProvider) Methods() []string {
+	return p.methods
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+	const op = "payments.FakeProvider.Authorize"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.byIdempotency[req.IdempotencyKey]; ok {
+		return id, nil
+	}
+
+	if p.declineAbove != nil && req.Amount.Cmp(*p.declineAbove) > 0 {
+		return "", fmt.Errorf("%s: %w: amount %s exceeds limit", op, ErrPaymentDeclined, req.Amount)
+	}
+
+	id := fmt.Sprintf("%s-%d", p.name, len(p.authorizations)+1)
+	p.authorizations[id] = &fakeAuthorization{
+		amount:   req.Amount,
+		captured: money.New(0, req.Amount.Currency),
+		refunded: money.New(0, req.Amount.Currency),
+		state:    fakeAuthorized,
+	}
+	p.byIdempotency[req.IdempotencyKey] = id
+
+	return id, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, authorizationID string, amount money.Money) error {
+	const op = "payments.FakeProvider.Capture"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	auth, ok := p.authorizations[authorizationID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownAuthorization)
+	}
+
+	switch auth.state {
+	case fakeCaptured, fakeRefunded:
+		return nil
+	case fakeVoided:
+		return fmt.Errorf("%s: authorization %s is voided", op, authorizationID)
+	}
+	if amount.Cmp(auth.amount) > 0 {
+		return fmt.Errorf("%s: capture %s exceeds authorized %s", op, amount, auth.amount)
+	}
+
+	auth.captured = amount
+	auth.state = fakeCaptured
+	return nil
+}
+
+func (p *FakeProvider) Void(ctx context.Context, authorizationID string) error {
+	const op = "payments.FakeProvider.Void"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	auth, ok := p.authorizations[authorizationID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownAuthorization)
+	}
+
+	switch auth.state {
+	case fakeVoided:
+		return nil
+	case fakeCaptured, fakeRefunded:
+		return fmt.Errorf("%s: authorization %s is already captured", op, authorizationID)
+	}
+
+	auth.state = fakeVoided
+	return nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) error {
+	const op = "payments.FakeProvider.Refund"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.refunds[req.IdempotencyKey]; ok {
+		return nil
+	}
+
+	authorizationID, amount := req.AuthorizationID, req.Amount
+	auth, ok := p.authorizations[authorizationID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrUnknownAuthorization)
+	}
+	if auth.state != fakeCaptured && auth.state != fakeRefunded {
+		return fmt.Errorf("%s: authorization %s is not captured", op, authorizationID)
+	}
+
+	refunded := auth.refunded.Add(amount)
+	if refunded.Cmp(auth.captured) > 0 {
+		return fmt.Errorf("%s: refund %s exceeds captured %s", op, refunded, auth.captured)
+	}
+
+	auth.refunded = refunded
+	auth.state = fakeRefunded
+	p.refunds[req.IdempotencyKey] = struct{}{}
+	return nil
+}
@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProvider_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	limit := money.MustParse("100.00", money.DefaultCurrency)
+	p := NewFakeProvider("fake", []string{"card"}, &limit)
+
+	_, err := p.Authorize(ctx, AuthorizeRequest{OrderID: 1, Amount: money.MustParse("100.01", money.DefaultCurrency), IdempotencyKey: "1"})
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+
+	amount := money.MustParse("80.00", money.DefaultCurrency)
+	id, err := p.Authorize(ctx, AuthorizeRequest{OrderID: 2, Amount: amount, IdempotencyKey: "2"})
+	require.NoError(t, err)
+
+	// повтор с тем же ключом возвращает ту же авторизацию
+	again, err := p.Authorize(ctx, AuthorizeRequest{OrderID: 2, Amount: amount, IdempotencyKey: "2"})
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	require.NoError(t, p.Capture(ctx, id, amount))
+	require.NoError(t, p.Capture(ctx, id, amount))
+	assert.Error(t, p.Void(ctx, id))
+
+	refund := func(amount, key string) error {
+		return p.Refund(ctx, RefundRequest{AuthorizationID: id, Amount: money.MustParse(amount, money.DefaultCurrency), IdempotencyKey: key})
+	}
+	require.NoError(t, refund("30.00", "return-1"))
+	// повтор того же возврата не возвращает деньги второй раз
+	require.NoError(t, refund("30.00", "return-1"))
+	require.NoError(t, refund("50.00", "return-2"))
+	assert.Error(t, refund("0.01", "return-3"))
+
+	assert.ErrorIs(t, p.Void(ctx, "unknown"), ErrUnknownAuthorization)
+}
+
+func TestRegistry(t *testing.T) {
+	cards := NewFakeProvider("cards", []string{"card"}, nil)
+	sbp := NewFakeProvider("sbp", []string{"sbp"}, nil)
+
+	r, err := NewRegistry(cards, sbp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"card", "sbp"}, r.Methods())
+
+	provider, err := r.Provider("sbp")
+	require.NoError(t, err)
+	assert.Equal(t, "sbp", provider.Name())
+
+	_, err = r.Provider("cash")
+	assert.ErrorIs(t, err, ErrUnsupportedPaymentMethod)
+
+	_, err = NewRegistry(cards, NewFakeProvider("other", []string{"card"}, nil))
+	assert.Error(t, err)
+}
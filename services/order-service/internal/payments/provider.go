@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+var (
+	ErrPaymentDeclined          = apperror.New(apperror.CodeUnprocessable, "payment declined")
+	ErrUnsupportedPaymentMethod = apperror.New(apperror.CodeInvalidArgument, "unsupported payment method")
+	ErrUnknownAuthorization     = apperror.New(apperror.CodeNotFound, "payment authorization not found")
+)
+
+// PaymentProvider платёжный провайдер. Деньги блокируются Authorize и списываются Capture;
+// Void снимает блокировку до списания, Refund возвращает списанное (можно частями).
+// Повторный Capture или Void той же авторизации ничего не меняет, как и повторный Refund
+// с тем же ключом идемпотентности.
+type PaymentProvider interface {
+	Name() string
+	// Methods способы оплаты, которые принимает провайдер
+	Methods() []string
+	// Authorize возвращает ID авторизации у провайдера; ErrPaymentDeclined, если платёж отклонён
+	Authorize(ctx context.Context, req AuthorizeRequest) (string, error)
+	Capture(ctx context.Context, authorizationID string, amount money.Money) error
+	Void(ctx context.Context, authorizationID string) error
+	Refund(ctx context.Context, req RefundRequest) error
+}
+
+// AuthorizeRequest IdempotencyKey одинаков для повторов одной попытки оплаты
+type AuthorizeRequest struct {
+	OrderID        int64
+	UserID         int64
+	Method         string
+	Amount         money.Money
+	IdempotencyKey string
+}
+
+// RefundRequest IdempotencyKey одинаков для повторов одного возврата
+type RefundRequest struct {
+	AuthorizationID string
+	Amount          money.Money
+	IdempotencyKey  string
+}
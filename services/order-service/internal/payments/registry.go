@@ -0,0 +1,58 @@
+package payments
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+// Registry выбирает провайдера по способу оплаты
+type Registry struct {
+	byMethod map[string]PaymentProvider
+}
+
+// NewRegistry один способ оплаты может обслуживать только один провайдер
+func NewRegistry(providers ...PaymentProvider) (*Registry, error) {
+	r := &Registry{byMethod: make(map[string]PaymentProvider)}
+
+	for _, provider := range providers {
+		for _, method := range provider.Methods() {
+			if existing, ok := r.byMethod[method]; ok {
+				return nil, fmt.Errorf("payment method %q is served by both %s and %s", method, existing.Name(), provider.Name())
+			}
+			r.byMethod[method] = provider
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Provider(method string) (PaymentProvider, error) {
+	provider, ok := r.byMethod[method]
+	if !ok {
+		return nil, ErrUnsupportedPaymentMethod.WithFields(apperror.Field(
+			"payment_method", fmt.Sprintf("supported methods: %v", r.Methods()),
+		))
+	}
+	return provider, nil
+}
+
+// ProviderByName провайдер, через которого прошла оплата; нужен для списания и возврата
+func (r *Registry) ProviderByName(name string) (PaymentProvider, error) {
+	for _, provider := range r.byMethod {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown payment provider %q", name)
+}
+
+func (r *Registry) Methods() []string {
+	methods := make([]string, 0, len(r.byMethod))
+	for method := range r.byMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
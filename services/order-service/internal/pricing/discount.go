@@ -0,0 +1,104 @@
+package pricing
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// ApplyPromo распределяет скидку промокода по позициям, на которые он действует, проставляет
+// Line.Discount и возвращает общую скидку. Процент округляется в каждой позиции; фиксированная
+// сумма не больше стоимости подходящих позиций и делится между ними пропорционально стоимости
+// методом наибольших остатков (splitFixed). free_shipping позиции не меняет.
+func ApplyPromo(lines []Line, promo *domain.PromoCode) money.Money {
+	var eligible []int
+	eligibleTotal := money.Zero()
+	for i, line := range lines {
+		if promo.AppliesTo(line.ProductID) {
+			eligible = append(eligible, i)
+			eligibleTotal = eligibleTotal.Add(line.Total())
+		}
+	}
+
+	discount := money.Zero()
+	if len(eligible) == 0 || eligibleTotal.IsZero() {
+		return discount
+	}
+
+	switch promo.Kind {
+	case domain.PromoKindPercent:
+		for _, i := range eligible {
+			lines[i].Discount = lines[i].Total().MulRate(promo.Rate, money.RoundHalfUp)
+			discount = discount.Add(lines[i].Discount)
+		}
+	case domain.PromoKindFixed:
+		amount := promo.Amount
+		if amount.Cmp(eligibleTotal) > 0 {
+			amount = eligibleTotal
+		}
+
+		totals := make([]money.Money, 0, len(eligible))
+		for _, i := range eligible {
+			totals = append(totals, lines[i].Total())
+		}
+		for n, share := range splitFixed(amount, totals, eligibleTotal) {
+			lines[eligible[n]].Discount = share
+		}
+		discount = amount
+	}
+
+	return discount
+}
+
+// splitFixed делит amount (не больше total — суммы totals) пропорционально totals: доли округляются
+// вниз, а оставшиеся копейки по одной получают позиции с наибольшим остатком от деления.
+// Доля позиции не превышает её стоимость.
+func splitFixed(amount money.Money, totals []money.Money, total money.Money) []money.Money {
+	shares := make([]money.Money, len(totals))
+	remainders := make([]int64, len(totals))
+	left := amount
+	for i, lineTotal := range totals {
+		shares[i] = amount.Share(lineTotal.Amount, total.Amount, money.RoundDown)
+		remainders[i] = amount.Amount * lineTotal.Amount % total.Amount
+		left = left.Sub(shares[i])
+	}
+
+	order := make([]int, len(totals))
+	for i := range order {
+		order[i] = i
+	}
+	// при равных остатках копейка достаётся позиции, стоящей раньше
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(remainders[b], remainders[a])
+	})
+
+	cent := money.New(1, amount.Currency)
+	for _, i := range order {
+		if left.IsZero() {
+			break
+		}
+		if shares[i].Cmp(totals[i]) < 0 {
+			shares[i] = shares[i].Add(cent)
+			left = left.Sub(cent)
+		}
+	}
+	return shares
+}
+
+// discountLine строка расчёта со скидкой промокода
+func discountLine(promo *domain.PromoCode, discount money.Money) *domain.OrderPriceLine {
+	description := fmt.Sprintf("%s off", promo.Amount)
+	if promo.Kind == domain.PromoKindPercent {
+		description = fmt.Sprintf("%s off", promo.Rate)
+	}
+
+	return &domain.OrderPriceLine{
+		Kind:        domain.PriceLineKindDiscount,
+		Rule:        promo.Code,
+		Description: description,
+		Amount:      discount,
+	}
+}
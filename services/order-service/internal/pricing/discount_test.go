@@ -0,0 +1,104 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discountQuote(promo *domain.PromoCode) Quote {
+	return Quote{
+		Promo: promo,
+		Lines: []Line{
+			{ProductID: 1, Category: "electronics", Quantity: 2, UnitPrice: rub("100.00"), WeightGrams: 500},
+			{ProductID: 2, Category: "food", Quantity: 1, UnitPrice: rub("55.55"), WeightGrams: 1000},
+		},
+	}
+}
+
+func TestRuleEngine_Calculate_DiscountBeforeTax(t *testing.T) {
+	engine, err := NewRuleEngine(testConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		promo    *domain.PromoCode
+		discount string
+		tax      string
+		shipping string
+		total    string
+	}{
+		{
+			// 10% от 200.00 и от 55.55 (5.555 -> 5.56); налог 20% от 180.00 и 10% от 49.99 (4.999 -> 5.00)
+			name:     "percent",
+			promo:    &domain.PromoCode{Code: "SALE10", Kind: domain.PromoKindPercent, Rate: money.Percent(10)},
+			discount: "25.56", tax: "41.00", shipping: "150.00", total: "420.99",
+		},
+		{
+			// скидка только на продукт 2: налог 10% от 5.55 = 0.555 -> 0.56
+			name:     "fixed for product",
+			promo:    &domain.PromoCode{Code: "FOOD50", Kind: domain.PromoKindFixed, Amount: rub("50.00"), ProductIDs: []int64{2}},
+			discount: "50.00", tax: "40.56", shipping: "150.00", total: "396.11",
+		},
+		{
+			name:     "free shipping",
+			promo:    &domain.PromoCode{Code: "SHIPFREE", Kind: domain.PromoKindFreeShipping},
+			discount: "0.00", tax: "45.56", shipping: "0.00", total: "301.11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := discountQuote(tt.promo)
+			breakdown, err := engine.Calculate(context.Background(), quote)
+			require.NoError(t, err)
+
+			assert.Equal(t, rub("255.55"), breakdown.ItemsPrice)
+			assert.Equal(t, rub(tt.discount), breakdown.DiscountPrice)
+			assert.Equal(t, rub(tt.tax), breakdown.TaxPrice)
+			assert.Equal(t, rub(tt.shipping), breakdown.ShippingPrice)
+			assert.Equal(t, rub(tt.total), breakdown.TotalPrice)
+			// позиции запроса не меняются
+			assert.True(t, quote.Lines[0].Discount.IsZero())
+		})
+	}
+}
+
+func TestApplyPromo_FixedAmountSplitsAcrossLines(t *testing.T) {
+	lines := discountQuote(nil).Lines
+
+	discount := ApplyPromo(lines, &domain.PromoCode{Kind: domain.PromoKindFixed, Amount: rub("100.00")})
+
+	assert.Equal(t, rub("100.00"), discount)
+	// 100.00 × 200.00 / 255.55 = 78.26…, 100.00 × 55.55 / 255.55 = 21.73…; копейка — большему остатку
+	assert.Equal(t, rub("78.26"), lines[0].Discount)
+	assert.Equal(t, rub("21.74"), lines[1].Discount)
+
+	lines = discountQuote(nil).Lines
+	discount = ApplyPromo(lines, &domain.PromoCode{Kind: domain.PromoKindFixed, Amount: rub("1000.00")})
+	assert.Equal(t, rub("255.55"), discount)
+	assert.True(t, lines[1].Net().IsZero())
+}
+
+func TestApplyPromo_FixedAmountShareNeverExceedsLine(t *testing.T) {
+	lines := []Line{
+		{ProductID: 1, Quantity: 1, UnitPrice: rub("10.00")},
+		{ProductID: 2, Quantity: 1, UnitPrice: rub("10.00")},
+		{ProductID: 3, Quantity: 1, UnitPrice: rub("0.01")},
+	}
+
+	discount := ApplyPromo(lines, &domain.PromoCode{Kind: domain.PromoKindFixed, Amount: rub("20.00")})
+
+	assert.Equal(t, rub("20.00"), discount)
+	// доли 9.995…, 9.995… и 0.0099…: копейки достаются третьей и первой позициям
+	assert.Equal(t, rub("10.00"), lines[0].Discount)
+	assert.Equal(t, rub("9.99"), lines[1].Discount)
+	assert.Equal(t, rub("0.01"), lines[2].Discount)
+	for _, line := range lines {
+		assert.False(t, line.Net().IsNegative(), "product %d", line.ProductID)
+	}
+}
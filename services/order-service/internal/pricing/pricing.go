@@ -0,0 +1,59 @@
+package pricing
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+var ErrNoShippingRule = apperror.New(apperror.CodeUnprocessable, "no shipping rule matches order")
+
+// Line позиция заказа в том виде, в котором она нужна для расчёта цены.
+// Discount — доля скидки промокода, приходящаяся на позицию (проставляет ApplyPromo).
+type Line struct {
+	ProductID   int64
+	Category    string
+	Quantity    int64
+	UnitPrice   money.Money
+	WeightGrams int64
+	Discount    money.Money
+}
+
+func (l Line) Total() money.Money {
+	return l.UnitPrice.Mul(l.Quantity)
+}
+
+// Net стоимость позиции после скидки: от неё считается налог
+func (l Line) Net() money.Money {
+	if l.Discount.IsZero() {
+		return l.Total()
+	}
+	return l.Total().Sub(l.Discount)
+}
+
+// Quote входные данные для расчёта цены заказа; Promo — проверенный промокод или nil
+type Quote struct {
+	Region string
+	Lines  []Line
+	Promo  *domain.PromoCode
+}
+
+// Breakdown итог расчёта. Lines — по строке на каждое применённое правило.
+// LineTaxRules — налоговое правило каждой строки Quote.Lines в том же порядке; пусто — без налога.
+// TotalPrice = ItemsPrice - DiscountPrice + TaxPrice + ShippingPrice.
+type Breakdown struct {
+	ItemsPrice    money.Money
+	DiscountPrice money.Money
+	TaxPrice      money.Money
+	ShippingPrice money.Money
+	TotalPrice    money.Money
+	Lines         []*domain.OrderPriceLine
+	LineTaxRules  []string
+}
+
+// Engine рассчитывает налог и доставку заказа
+type Engine interface {
+	Calculate(ctx context.Context, quote Quote) (*Breakdown, error)
+}
@@ -0,0 +1,108 @@
+package pricing
+
+import (
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// RefundBreakdown сумма возврата по составляющим
+type RefundBreakdown struct {
+	ItemsPrice    money.Money
+	TaxPrice      money.Money
+	ShippingPrice money.Money
+	TotalPrice    money.Money
+}
+
+// Refund рассчитывает возврат позиций items заказа order (с загруженными Items и PriceLines)
+// и проставляет стоимость каждой позиции возврата по цене заказа. Скидка, налог и доставка
+// не пересчитываются по текущим правилам, а делятся из сохранённого расчёта заказа: скидка —
+// пропорционально стоимости возвращённых позиций, налог — по строке налога правила каждой позиции
+// пропорционально стоимости возвращённого среди позиций этого правила, доставка — только когда
+// возвращён весь заказ. Доли считаются нарастающим итогом вместе с прошлыми возвратами previous,
+// поэтому возвраты всего заказа в сумме дают ровно его итог, без потерь на округлении.
+func Refund(order *domain.Order, items []*domain.ReturnItem, previous []*domain.Return) *RefundBreakdown {
+	zero := money.New(0, order.TotalPrice.Currency)
+
+	prices := make(map[int64]money.Money, len(order.Items))
+	taxRules := make(map[int64]string, len(order.Items))
+	ordered, orderedQty := zero, int64(0)
+	for _, item := range order.Items {
+		prices[item.ID] = item.Price
+		taxRules[item.ID] = item.TaxRule
+		ordered = ordered.Add(item.Price.Mul(item.Quantity))
+		orderedQty += item.Quantity
+	}
+
+	itemsPrice, returnedQty := zero, int64(0)
+	for _, item := range items {
+		item.Amount = prices[item.OrderItemID].Mul(item.Quantity)
+		itemsPrice = itemsPrice.Add(item.Amount)
+		returnedQty += item.Quantity
+	}
+
+	// стоимость возвращённого нарастающим итогом по налоговым правилам позиций
+	returnedByRule := make(map[string]int64)
+	for _, item := range items {
+		returnedByRule[taxRules[item.OrderItemID]] += item.Amount.Amount
+	}
+
+	// ItemsAmount прошлых возвратов — уже за вычетом скидки, стоимость позиций — без неё
+	returnedGross, returnedNet, refundedTax, refundedShipping := zero, zero, zero, zero
+	for _, ret := range previous {
+		returnedNet = returnedNet.Add(ret.ItemsAmount)
+		refundedTax = refundedTax.Add(ret.TaxAmount)
+		refundedShipping = refundedShipping.Add(ret.ShippingAmount)
+		for _, item := range ret.Items {
+			returnedGross = returnedGross.Add(item.Amount)
+			returnedByRule[taxRules[item.OrderItemID]] += item.Amount.Amount
+			returnedQty += item.Quantity
+		}
+	}
+
+	refund := &RefundBreakdown{ItemsPrice: itemsPrice, TaxPrice: zero, ShippingPrice: zero}
+
+	if ordered.Amount > 0 {
+		returned := returnedGross.Add(itemsPrice).Amount
+
+		if !order.DiscountPrice.IsZero() {
+			discount := order.DiscountPrice.Share(returned, ordered.Amount, money.RoundHalfUp)
+			refund.ItemsPrice = itemsPrice.Sub(discount.Sub(returnedGross.Sub(returnedNet)))
+		}
+
+		tax := refundTax(order, returned, ordered.Amount, returnedByRule)
+		refund.TaxPrice = tax.Sub(refundedTax)
+	}
+	if returnedQty == orderedQty {
+		refund.ShippingPrice = order.ShippingPrice.Sub(refundedShipping)
+	}
+
+	refund.TotalPrice = money.Sum(refund.ItemsPrice, refund.TaxPrice, refund.ShippingPrice)
+	return refund
+}
+
+// refundTax налог, приходящийся на всё возвращённое. Строка налога правила делится между позициями
+// этого правила, поэтому возврат позиции с высокой ставкой возвращает её налог, а не среднюю долю.
+// Налог, не привязанный к позициям (заказы, оформленные до сохранения правила позиции), делится
+// пропорционально стоимости всего возвращённого.
+func refundTax(order *domain.Order, returned, ordered int64, returnedByRule map[string]int64) money.Money {
+	orderedByRule := make(map[string]int64)
+	for _, item := range order.Items {
+		if item.TaxRule != "" {
+			orderedByRule[item.TaxRule] += item.Price.Mul(item.Quantity).Amount
+		}
+	}
+
+	tax, unlinked := money.New(0, order.TaxPrice.Currency), order.TaxPrice
+	for _, line := range order.PriceLines {
+		if line.Kind != domain.PriceLineKindTax || orderedByRule[line.Rule] == 0 {
+			continue
+		}
+		tax = tax.Add(line.Amount.Share(returnedByRule[line.Rule], orderedByRule[line.Rule], money.RoundHalfUp))
+		unlinked = unlinked.Sub(line.Amount)
+	}
+
+	if !unlinked.IsZero() {
+		tax = tax.Add(unlinked.Share(returned, ordered, money.RoundHalfUp))
+	}
+	return tax
+}
@@ -0,0 +1,116 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefund_PartialReturnsAddUpToOrderTotal(t *testing.T) {
+	order := &domain.Order{
+		TaxPrice:      rub("45.56"),
+		ShippingPrice: rub("150.00"),
+		TotalPrice:    rub("451.11"),
+		Items: []*domain.OrderItem{
+			{ID: 1, Quantity: 2, Price: rub("100.00")},
+			{ID: 2, Quantity: 1, Price: rub("55.55")},
+		},
+	}
+
+	first := []*domain.ReturnItem{{OrderItemID: 1, Quantity: 1}}
+	refund := Refund(order, first, nil)
+
+	assert.Equal(t, rub("100.00"), first[0].Amount)
+	assert.Equal(t, rub("100.00"), refund.ItemsPrice)
+	// 45.56 × 100.00 / 255.55 = 17.828…
+	assert.Equal(t, rub("17.83"), refund.TaxPrice)
+	assert.Equal(t, rub("0.00"), refund.ShippingPrice)
+	assert.Equal(t, rub("117.83"), refund.TotalPrice)
+
+	previous := []*domain.Return{{
+		Items:          first,
+		ItemsAmount:    refund.ItemsPrice,
+		TaxAmount:      refund.TaxPrice,
+		ShippingAmount: refund.ShippingPrice,
+	}}
+	rest := []*domain.ReturnItem{{OrderItemID: 1, Quantity: 1}, {OrderItemID: 2, Quantity: 1}}
+	last := Refund(order, rest, previous)
+
+	assert.Equal(t, rub("155.55"), last.ItemsPrice)
+	// налог считается нарастающим итогом: 45.56 − 17.83
+	assert.Equal(t, rub("27.73"), last.TaxPrice)
+	// возвращён весь заказ — возвращается и доставка
+	assert.Equal(t, rub("150.00"), last.ShippingPrice)
+	require.Equal(t, order.TotalPrice, refund.TotalPrice.Add(last.TotalPrice))
+}
+
+func TestRefund_SharesOrderDiscount(t *testing.T) {
+	// заказ со скидкой 25.56 (SALE10 из TestRuleEngine_Calculate_DiscountBeforeTax)
+	order := &domain.Order{
+		DiscountPrice: rub("25.56"),
+		TaxPrice:      rub("41.00"),
+		ShippingPrice: rub("150.00"),
+		TotalPrice:    rub("420.99"),
+		Items: []*domain.OrderItem{
+			{ID: 1, Quantity: 2, Price: rub("100.00")},
+			{ID: 2, Quantity: 1, Price: rub("55.55")},
+		},
+	}
+
+	first := []*domain.ReturnItem{{OrderItemID: 2, Quantity: 1}}
+	refund := Refund(order, first, nil)
+
+	assert.Equal(t, rub("55.55"), first[0].Amount)
+	// 25.56 × 55.55 / 255.55 = 5.556… -> 5.56
+	assert.Equal(t, rub("49.99"), refund.ItemsPrice)
+
+	previous := []*domain.Return{{
+		Items:          first,
+		ItemsAmount:    refund.ItemsPrice,
+		TaxAmount:      refund.TaxPrice,
+		ShippingAmount: refund.ShippingPrice,
+	}}
+	last := Refund(order, []*domain.ReturnItem{{OrderItemID: 1, Quantity: 2}}, previous)
+
+	assert.Equal(t, rub("180.00"), last.ItemsPrice)
+	require.Equal(t, order.TotalPrice, refund.TotalPrice.Add(last.TotalPrice))
+}
+
+func TestRefund_TaxFollowsItemRate(t *testing.T) {
+	// позиции из TestRuleEngine_Calculate: 20% на 200.00 и 10% на 55.55
+	order := &domain.Order{
+		TaxPrice:      rub("45.56"),
+		ShippingPrice: rub("150.00"),
+		TotalPrice:    rub("451.11"),
+		Items: []*domain.OrderItem{
+			{ID: 1, Quantity: 2, Price: rub("100.00"), TaxRule: "vat"},
+			{ID: 2, Quantity: 1, Price: rub("55.55"), TaxRule: "vat-food"},
+		},
+		PriceLines: []*domain.OrderPriceLine{
+			{Kind: domain.PriceLineKindTax, Rule: "vat", Amount: rub("40.00")},
+			{Kind: domain.PriceLineKindTax, Rule: "vat-food", Amount: rub("5.56")},
+			{Kind: domain.PriceLineKindShipping, Rule: "standard", Amount: rub("150.00")},
+		},
+	}
+
+	first := []*domain.ReturnItem{{OrderItemID: 1, Quantity: 1}}
+	refund := Refund(order, first, nil)
+
+	// половина налога по 20%, а не 45.56 × 100.00 / 255.55 = 17.83
+	assert.Equal(t, rub("20.00"), refund.TaxPrice)
+	assert.Equal(t, rub("120.00"), refund.TotalPrice)
+
+	previous := []*domain.Return{{
+		Items:          first,
+		ItemsAmount:    refund.ItemsPrice,
+		TaxAmount:      refund.TaxPrice,
+		ShippingAmount: refund.ShippingPrice,
+	}}
+	rest := []*domain.ReturnItem{{OrderItemID: 1, Quantity: 1}, {OrderItemID: 2, Quantity: 1}}
+	last := Refund(order, rest, previous)
+
+	assert.Equal(t, rub("25.56"), last.TaxPrice)
+	require.Equal(t, order.TotalPrice, refund.TotalPrice.Add(last.TotalPrice))
+}
@@ -0,0 +1,228 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// TaxRounding определяет, где округляется налог
+type TaxRounding int
+
+const (
+	// TaxRoundingPerOrder налог считается от суммы строк правила и округляется один раз
+	TaxRoundingPerOrder TaxRounding = iota
+	// TaxRoundingPerLine налог округляется в каждой строке, затем строки суммируются
+	TaxRoundingPerLine
+)
+
+// RuleEngine применяет налоговые правила и правила доставки из конфига
+type RuleEngine struct {
+	defaultRegion string
+	taxRounding   TaxRounding
+	taxRules      []config.TaxRuleConfig
+	shippingRules []config.ShippingRuleConfig
+}
+
+func NewRuleEngine(cfg config.PricingConfig) (*RuleEngine, error) {
+	const op = "pricing.NewRuleEngine"
+
+	engine := &RuleEngine{
+		defaultRegion: cfg.DefaultRegion,
+		taxRules:      cfg.TaxRules,
+		shippingRules: cfg.ShippingRules,
+	}
+
+	switch cfg.TaxRounding {
+	case "", "per_order":
+		engine.taxRounding = TaxRoundingPerOrder
+	case "per_line":
+		engine.taxRounding = TaxRoundingPerLine
+	default:
+		return nil, fmt.Errorf("%s: unknown tax rounding %q", op, cfg.TaxRounding)
+	}
+
+	for _, rule := range cfg.TaxRules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%s: tax rule without name", op)
+		}
+	}
+
+	if len(cfg.ShippingRules) == 0 {
+		return nil, fmt.Errorf("%s: at least one shipping rule is required", op)
+	}
+	for _, rule := range cfg.ShippingRules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%s: shipping rule without name", op)
+		}
+		if rule.Price.Currency == "" {
+			return nil, fmt.Errorf("%s: shipping rule %q has no price", op, rule.Name)
+		}
+	}
+
+	return engine, nil
+}
+
+func (e *RuleEngine) Calculate(ctx context.Context, quote Quote) (*Breakdown, error) {
+	const op = "pricing.Calculate"
+
+	region := quote.Region
+	if region == "" {
+		region = e.defaultRegion
+	}
+
+	// скидка применяется до налога: налог считается от стоимости позиций после скидки
+	lines := slices.Clone(quote.Lines)
+	discount := money.Zero()
+	if quote.Promo != nil {
+		discount = ApplyPromo(lines, quote.Promo)
+	}
+
+	lineTotals := make([]money.Money, 0, len(lines))
+	var weight int64
+	for _, line := range lines {
+		lineTotals = append(lineTotals, line.Total())
+		weight += line.WeightGrams * line.Quantity
+	}
+	itemsPrice := money.Sum(lineTotals...)
+
+	breakdown := &Breakdown{
+		ItemsPrice:    itemsPrice,
+		DiscountPrice: discount,
+		TaxPrice:      money.Zero(),
+	}
+
+	var priceLines []*domain.OrderPriceLine
+	if !discount.IsZero() {
+		priceLines = append(priceLines, discountLine(quote.Promo, discount))
+	}
+
+	taxLines, lineTaxRules := e.calculateTax(region, lines)
+	breakdown.LineTaxRules = lineTaxRules
+	for _, line := range taxLines {
+		breakdown.TaxPrice = breakdown.TaxPrice.Add(line.Amount)
+	}
+
+	shippingLine, err := e.calculateShipping(region, weight, itemsPrice.Sub(discount))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if quote.Promo != nil && quote.Promo.Kind == domain.PromoKindFreeShipping {
+		shippingLine.Description = fmt.Sprintf("free shipping with promo code %s", quote.Promo.Code)
+		shippingLine.Amount = money.Zero()
+	}
+	breakdown.ShippingPrice = shippingLine.Amount
+
+	breakdown.Lines = append(append(priceLines, taxLines...), shippingLine)
+	breakdown.TotalPrice = money.Sum(itemsPrice, breakdown.TaxPrice, breakdown.ShippingPrice).Sub(discount)
+
+	return breakdown, nil
+}
+
+// calculateTax группирует строки по применённому правилу; по строке расчёта на правило.
+// Второй результат — имя правила каждой строки lines.
+func (e *RuleEngine) calculateTax(region string, lines []Line) ([]*domain.OrderPriceLine, []string) {
+	var (
+		order  []int
+		groups = make(map[int][]money.Money)
+		rules  = make([]string, len(lines))
+	)
+
+	for i, line := range lines {
+		idx := e.matchTaxRule(region, line.Category)
+		if idx < 0 {
+			continue
+		}
+		rules[i] = e.taxRules[idx].Name
+		if _, seen := groups[idx]; !seen {
+			order = append(order, idx)
+		}
+		groups[idx] = append(groups[idx], line.Net())
+	}
+
+	result := make([]*domain.OrderPriceLine, 0, len(order))
+	for _, idx := range order {
+		rule := e.taxRules[idx]
+		base := money.Sum(groups[idx]...)
+		result = append(result, &domain.OrderPriceLine{
+			Kind:        domain.PriceLineKindTax,
+			Rule:        rule.Name,
+			Description: fmt.Sprintf("%s of %s", rule.Rate, base),
+			Amount:      CalculateTax(groups[idx], rule.Rate, e.taxRounding),
+		})
+	}
+
+	return result, rules
+}
+
+// matchTaxRule выбирает самое точное правило: регион и категория важнее только региона,
+// регион важнее только категории. При равенстве побеждает объявленное раньше.
+func (e *RuleEngine) matchTaxRule(region, category string) int {
+	best, bestScore := -1, -1
+	for i, rule := range e.taxRules {
+		if rule.Region != "" && rule.Region != region {
+			continue
+		}
+		if rule.Category != "" && rule.Category != category {
+			continue
+		}
+
+		score := 0
+		if rule.Region != "" {
+			score += 2
+		}
+		if rule.Category != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func (e *RuleEngine) calculateShipping(region string, weight int64, itemsPrice money.Money) (*domain.OrderPriceLine, error) {
+	for _, rule := range e.shippingRules {
+		if rule.Region != "" && rule.Region != region {
+			continue
+		}
+		if rule.MaxWeightGrams > 0 && weight > rule.MaxWeightGrams {
+			continue
+		}
+		if rule.MinItemsPrice != nil && itemsPrice.Cmp(*rule.MinItemsPrice) < 0 {
+			continue
+		}
+
+		line := &domain.OrderPriceLine{
+			Kind:        domain.PriceLineKindShipping,
+			Rule:        rule.Name,
+			Description: fmt.Sprintf("%d g to %s", weight, region),
+			Amount:      rule.Price,
+		}
+		if rule.FreeFrom != nil && itemsPrice.Cmp(*rule.FreeFrom) >= 0 {
+			line.Description = fmt.Sprintf("free shipping from %s", rule.FreeFrom)
+			line.Amount = money.Zero()
+		}
+		return line, nil
+	}
+
+	return nil, fmt.Errorf("%w: region %s, weight %d g", ErrNoShippingRule, region, weight)
+}
+
+// CalculateTax считает налог по стоимостям строк. Половина копейки округляется от нуля.
+// При TaxRoundingPerLine сумма может отличаться от TaxRoundingPerOrder на копейку на строку.
+func CalculateTax(lines []money.Money, rate money.Rate, rounding TaxRounding) money.Money {
+	if rounding == TaxRoundingPerLine {
+		tax := money.Zero()
+		for _, line := range lines {
+			tax = tax.Add(line.MulRate(rate, money.RoundHalfUp))
+		}
+		return tax
+	}
+
+	return money.Sum(lines...).MulRate(rate, money.RoundHalfUp)
+}
@@ -0,0 +1,155 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rub(s string) money.Money {
+	return money.MustParse(s, money.DefaultCurrency)
+}
+
+func rubPtr(s string) *money.Money {
+	m := rub(s)
+	return &m
+}
+
+func testConfig() config.PricingConfig {
+	return config.PricingConfig{
+		DefaultRegion: "RU",
+		TaxRules: []config.TaxRuleConfig{
+			{Name: "vat", Rate: money.Percent(20)},
+			{Name: "vat-food", Category: "food", Rate: money.Percent(10)},
+			{Name: "vat-kz", Region: "KZ", Rate: money.Percent(12)},
+			{Name: "vat-kz-food", Region: "KZ", Category: "food", Rate: money.Percent(0)},
+		},
+		ShippingRules: []config.ShippingRuleConfig{
+			{Name: "standard", MaxWeightGrams: 10000, Price: rub("150.00"), FreeFrom: rubPtr("5000.00")},
+			{Name: "oversize", Price: rub("600.00")},
+		},
+	}
+}
+
+func TestRuleEngine_Calculate(t *testing.T) {
+	engine, err := NewRuleEngine(testConfig())
+	require.NoError(t, err)
+
+	breakdown, err := engine.Calculate(context.Background(), Quote{
+		Lines: []Line{
+			{ProductID: 1, Category: "electronics", Quantity: 2, UnitPrice: rub("100.00"), WeightGrams: 500},
+			{ProductID: 2, Category: "food", Quantity: 1, UnitPrice: rub("55.55"), WeightGrams: 1000},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, rub("255.55"), breakdown.ItemsPrice)
+	// 20% от 200.00 = 40.00; 10% от 55.55 = 5.555 -> 5.56
+	assert.Equal(t, rub("45.56"), breakdown.TaxPrice)
+	assert.Equal(t, rub("150.00"), breakdown.ShippingPrice)
+	assert.Equal(t, rub("451.11"), breakdown.TotalPrice)
+
+	require.Len(t, breakdown.Lines, 3)
+	assert.Equal(t, "vat", breakdown.Lines[0].Rule)
+	assert.Equal(t, rub("40.00"), breakdown.Lines[0].Amount)
+	assert.Equal(t, "vat-food", breakdown.Lines[1].Rule)
+	assert.Equal(t, rub("5.56"), breakdown.Lines[1].Amount)
+	assert.Equal(t, domain.PriceLineKindShipping, breakdown.Lines[2].Kind)
+	assert.Equal(t, "standard", breakdown.Lines[2].Rule)
+	assert.Equal(t, []string{"vat", "vat-food"}, breakdown.LineTaxRules)
+}
+
+func TestRuleEngine_TaxRuleSpecificity(t *testing.T) {
+	engine, err := NewRuleEngine(testConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		region   string
+		category string
+		want     string
+	}{
+		{region: "RU", category: "toys", want: "vat"},
+		{region: "RU", category: "food", want: "vat-food"},
+		{region: "KZ", category: "toys", want: "vat-kz"},
+		{region: "KZ", category: "food", want: "vat-kz-food"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region+"/"+tt.category, func(t *testing.T) {
+			idx := engine.matchTaxRule(tt.region, tt.category)
+			require.GreaterOrEqual(t, idx, 0)
+			assert.Equal(t, tt.want, engine.taxRules[idx].Name)
+		})
+	}
+}
+
+func TestRuleEngine_Shipping(t *testing.T) {
+	engine, err := NewRuleEngine(testConfig())
+	require.NoError(t, err)
+
+	t.Run("free from threshold", func(t *testing.T) {
+		breakdown, err := engine.Calculate(context.Background(), Quote{
+			Lines: []Line{{Quantity: 1, UnitPrice: rub("5000.00"), WeightGrams: 100}},
+		})
+		require.NoError(t, err)
+		assert.True(t, breakdown.ShippingPrice.IsZero())
+	})
+
+	t.Run("heavy order falls through to oversize", func(t *testing.T) {
+		breakdown, err := engine.Calculate(context.Background(), Quote{
+			Lines: []Line{{Quantity: 3, UnitPrice: rub("10.00"), WeightGrams: 4000}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, rub("600.00"), breakdown.ShippingPrice)
+	})
+
+	t.Run("no matching rule", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.ShippingRules = cfg.ShippingRules[:1]
+		engine, err := NewRuleEngine(cfg)
+		require.NoError(t, err)
+
+		_, err = engine.Calculate(context.Background(), Quote{
+			Lines: []Line{{Quantity: 1, UnitPrice: rub("10.00"), WeightGrams: 20000}},
+		})
+		assert.ErrorIs(t, err, ErrNoShippingRule)
+	})
+}
+
+func TestNewRuleEngine_InvalidConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.TaxRounding = "sometimes"
+	_, err := NewRuleEngine(cfg)
+	assert.Error(t, err)
+
+	cfg = testConfig()
+	cfg.ShippingRules = nil
+	_, err = NewRuleEngine(cfg)
+	assert.Error(t, err)
+
+	cfg = testConfig()
+	cfg.ShippingRules = []config.ShippingRuleConfig{{Name: "no-price"}}
+	_, err = NewRuleEngine(cfg)
+	assert.Error(t, err)
+}
+
+func TestCalculateTax_Rounding(t *testing.T) {
+	// три строки по 0.05 при 10%: по строкам 0.005 -> 0.01 каждая, по заказу 0.015 -> 0.02
+	lines := []money.Money{rub("0.05"), rub("0.05"), rub("0.05")}
+
+	assert.Equal(t, rub("0.03"), CalculateTax(lines, money.Percent(10), TaxRoundingPerLine))
+	assert.Equal(t, rub("0.02"), CalculateTax(lines, money.Percent(10), TaxRoundingPerOrder))
+
+	// 19.99 * 3 при 7.5%: 4.49775 -> 4.50 по заказу; 1.49925 -> 1.50 * 3 = 4.50 по строкам
+	rate, err := money.ParseRate("7.5%")
+	require.NoError(t, err)
+	lines = []money.Money{rub("19.99"), rub("19.99"), rub("19.99")}
+
+	assert.Equal(t, rub("4.50"), CalculateTax(lines, rate, TaxRoundingPerOrder))
+	assert.Equal(t, rub("4.50"), CalculateTax(lines, rate, TaxRoundingPerLine))
+}
@@ -0,0 +1,217 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// Store хранилище состояния саг; состояние сохраняется после каждого шага
+type Store interface {
+	CreateSaga(ctx context.Context, saga *domain.Saga) error
+	UpdateSaga(ctx context.Context, saga *domain.Saga) error
+	ListStaleSagas(ctx context.Context, name string, before time.Time, limit int) ([]*domain.Saga, error)
+	ClaimSaga(ctx context.Context, saga *domain.Saga, at time.Time) (bool, error)
+}
+
+// Step шаг саги. После падения процесса шаг может выполниться повторно, а компенсация —
+// вызваться для шага, действие которого не успело выполниться, поэтому обе функции
+// должны быть идемпотентны. Compensate nil — шагу нечего откатывать.
+type Step[T any] struct {
+	Name       string
+	Action     func(ctx context.Context, data *T) error
+	Compensate func(ctx context.Context, data *T) error
+}
+
+// Definition упорядоченные шаги саги. CompensateOnRecover: прерванная сага при восстановлении
+// откатывается, а не доводится до конца (например, если клиент уже не получит ответ).
+type Definition[T any] struct {
+	Name                string
+	Steps               []Step[T]
+	CompensateOnRecover bool
+}
+
+// Orchestrator выполняет шаги по порядку, а при ошибке шага вызывает компенсации
+// выполненных шагов в обратном порядке. Данные саги T сохраняются в JSON.
+type Orchestrator[T any] struct {
+	log   *slog.Logger
+	store Store
+	def   Definition[T]
+	now   func() time.Time
+}
+
+func NewOrchestrator[T any](log *slog.Logger, store Store, def Definition[T]) *Orchestrator[T] {
+	return &Orchestrator[T]{
+		log:   log.With(slog.String("component", "saga"), slog.String("saga", def.Name)),
+		store: store,
+		def:   def,
+		now:   time.Now,
+	}
+}
+
+// Execute выполняет сагу. Если шаг упал, возвращается его ошибка (уже после компенсации);
+// если не удалась и компенсация, сага остаётся в compensating и будет доведена Recover.
+func (o *Orchestrator[T]) Execute(ctx context.Context, data *T) error {
+	const op = "saga.Orchestrator.Execute"
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%s: failed to marshal saga data: %w", op, err)
+	}
+
+	now := o.now()
+	saga := &domain.Saga{
+		Name:      o.def.Name,
+		Status:    domain.SagaStatusRunning,
+		Data:      payload,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := o.store.CreateSaga(ctx, saga); err != nil {
+		return fmt.Errorf("%s: failed to create saga: %w", op, err)
+	}
+
+	return o.run(ctx, saga, data)
+}
+
+// Recover доводит саги, зависшие дольше staleAfter: продолжает их с прерванного шага
+// или, при CompensateOnRecover, откатывает. Возвращает число обработанных саг.
+func (o *Orchestrator[T]) Recover(ctx context.Context, staleAfter time.Duration, limit int) (int, error) {
+	const op = "saga.Orchestrator.Recover"
+
+	sagas, err := o.store.ListStaleSagas(ctx, o.def.Name, o.now().Add(-staleAfter), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to list stale sagas: %w", op, err)
+	}
+
+	recovered := 0
+	for _, saga := range sagas {
+		claimed, err := o.store.ClaimSaga(ctx, saga, o.now())
+		if err != nil {
+			return recovered, fmt.Errorf("%s: failed to claim saga %d: %w", op, saga.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		var data T
+		if err := json.Unmarshal(saga.Data, &data); err != nil {
+			return recovered, fmt.Errorf("%s: failed to unmarshal saga %d: %w", op, saga.ID, err)
+		}
+
+		o.log.Info("recovering saga",
+			slog.Int64("saga_id", saga.ID),
+			slog.String("status", saga.Status),
+			slog.Int("step", saga.Step),
+		)
+
+		if saga.Status == domain.SagaStatusRunning && !o.def.CompensateOnRecover {
+			err = o.run(ctx, saga, &data)
+		} else {
+			err = o.compensate(ctx, saga, &data, nil)
+		}
+		if err != nil {
+			o.log.Error("failed to recover saga", slog.Int64("saga_id", saga.ID), slog.String("error", err.Error()))
+			continue
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+// RecoverLoop вызывает Recover каждые interval до отмены ctx
+func (o *Orchestrator[T]) RecoverLoop(ctx context.Context, interval, staleAfter time.Duration, limit int) {
+	for {
+		if _, err := o.Recover(ctx, staleAfter, limit); err != nil && ctx.Err() == nil {
+			o.log.Error("saga recovery failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// run выполняет шаги начиная с saga.Step
+func (o *Orchestrator[T]) run(ctx context.Context, saga *domain.Saga, data *T) error {
+	for saga.Step < len(o.def.Steps) {
+		step := o.def.Steps[saga.Step]
+
+		if err := step.Action(ctx, data); err != nil {
+			stepErr := fmt.Errorf("saga %s: step %s: %w", o.def.Name, step.Name, err)
+			if compErr := o.compensate(ctx, saga, data, stepErr); compErr != nil {
+				o.log.Error("saga compensation failed",
+					slog.Int64("saga_id", saga.ID),
+					slog.String("error", compErr.Error()),
+				)
+			}
+			return stepErr
+		}
+
+		saga.Step++
+		if err := o.save(ctx, saga, data); err != nil {
+			return err
+		}
+	}
+
+	saga.Status = domain.SagaStatusCompleted
+	return o.save(ctx, saga, data)
+}
+
+// compensate откатывает шаги с saga.Step до первого. Текущий шаг тоже компенсируется:
+// его действие могло выполниться до сбоя, а сохранить это сага не успела.
+func (o *Orchestrator[T]) compensate(ctx context.Context, saga *domain.Saga, data *T, cause error) error {
+	// компенсации должны дойти до конца, даже если запрос клиента уже отменён
+	ctx = context.WithoutCancel(ctx)
+
+	saga.Status = domain.SagaStatusCompensating
+	if cause != nil {
+		reason := cause.Error()
+		saga.LastError = &reason
+	}
+	if err := o.save(ctx, saga, data); err != nil {
+		return err
+	}
+
+	for saga.Step >= 0 {
+		current := saga.Step
+		saga.Step--
+		if current >= len(o.def.Steps) || o.def.Steps[current].Compensate == nil {
+			continue
+		}
+
+		step := o.def.Steps[current]
+		if err := step.Compensate(ctx, data); err != nil {
+			saga.Step = current
+			return fmt.Errorf("saga %s: compensate %s: %w", o.def.Name, step.Name, err)
+		}
+		if err := o.save(ctx, saga, data); err != nil {
+			return err
+		}
+	}
+
+	saga.Step = 0
+	saga.Status = domain.SagaStatusCompensated
+	return o.save(ctx, saga, data)
+}
+
+func (o *Orchestrator[T]) save(ctx context.Context, saga *domain.Saga, data *T) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("saga %s: failed to marshal data: %w", o.def.Name, err)
+	}
+
+	saga.Data = payload
+	saga.UpdatedAt = o.now()
+	if err := o.store.UpdateSaga(ctx, saga); err != nil {
+		return fmt.Errorf("saga %s: failed to save state: %w", o.def.Name, err)
+	}
+	return nil
+}
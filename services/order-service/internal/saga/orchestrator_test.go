@@ -0,0 +1,193 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore хранит копии саг, как их увидел бы перезапущенный процесс
+type memoryStore struct {
+	sagas map[int64]domain.Saga
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sagas: make(map[int64]domain.Saga)}
+}
+
+func (s *memoryStore) CreateSaga(ctx context.Context, saga *domain.Saga) error {
+	saga.ID = int64(len(s.sagas) + 1)
+	s.sagas[saga.ID] = *saga
+	return nil
+}
+
+func (s *memoryStore) UpdateSaga(ctx context.Context, saga *domain.Saga) error {
+	s.sagas[saga.ID] = *saga
+	return nil
+}
+
+func (s *memoryStore) ListStaleSagas(ctx context.Context, name string, before time.Time, limit int) ([]*domain.Saga, error) {
+	var stale []*domain.Saga
+	for _, saga := range s.sagas {
+		if saga.Name == name && !saga.Finished() && saga.UpdatedAt.Before(before) {
+			stale = append(stale, &saga)
+		}
+	}
+	return stale, nil
+}
+
+func (s *memoryStore) ClaimSaga(ctx context.Context, saga *domain.Saga, at time.Time) (bool, error) {
+	stored := s.sagas[saga.ID]
+	if !stored.UpdatedAt.Equal(saga.UpdatedAt) {
+		return false, nil
+	}
+	stored.UpdatedAt, saga.UpdatedAt = at, at
+	s.sagas[saga.ID] = stored
+	return true, nil
+}
+
+type testData struct {
+	Counter int `json:"counter"`
+}
+
+// recorder журнал вызовов шагов; fail — имя шага, действие которого упадёт
+type recorder struct {
+	calls []string
+	fail  string
+}
+
+func (r *recorder) step(name string) Step[testData] {
+	return Step[testData]{
+		Name: name,
+		Action: func(ctx context.Context, data *testData) error {
+			r.calls = append(r.calls, name)
+			if name == r.fail {
+				return errors.New("boom")
+			}
+			data.Counter++
+			return nil
+		},
+		Compensate: func(ctx context.Context, data *testData) error {
+			r.calls = append(r.calls, "undo "+name)
+			return nil
+		},
+	}
+}
+
+func newTestOrchestrator(store Store, rec *recorder, compensateOnRecover bool) *Orchestrator[testData] {
+	return NewOrchestrator(slogdiscard.NewDiscardLogger(), store, Definition[testData]{
+		Name:                "test",
+		Steps:               []Step[testData]{rec.step("a"), rec.step("b"), rec.step("c")},
+		CompensateOnRecover: compensateOnRecover,
+	})
+}
+
+func TestExecute_RunsStepsInOrder(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{}
+	o := newTestOrchestrator(store, rec, false)
+
+	data := &testData{}
+	require.NoError(t, o.Execute(context.Background(), data))
+
+	assert.Equal(t, []string{"a", "b", "c"}, rec.calls)
+	assert.Equal(t, 3, data.Counter)
+
+	saga := store.sagas[1]
+	assert.Equal(t, domain.SagaStatusCompleted, saga.Status)
+	assert.JSONEq(t, `{"counter":3}`, string(saga.Data))
+}
+
+func TestExecute_CompensatesInReverseOrder(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{fail: "c"}
+	o := newTestOrchestrator(store, rec, false)
+
+	err := o.Execute(context.Background(), &testData{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step c: boom")
+
+	// упавший шаг тоже компенсируется: компенсации идемпотентны
+	assert.Equal(t, []string{"a", "b", "c", "undo c", "undo b", "undo a"}, rec.calls)
+
+	saga := store.sagas[1]
+	assert.Equal(t, domain.SagaStatusCompensated, saga.Status)
+	require.NotNil(t, saga.LastError)
+	assert.Contains(t, *saga.LastError, "boom")
+}
+
+func TestRecover_ResumesInterruptedSaga(t *testing.T) {
+	store := newMemoryStore()
+	store.sagas[1] = domain.Saga{
+		ID: 1, Name: "test", Status: domain.SagaStatusRunning, Step: 1,
+		Data: []byte(`{"counter":1}`), UpdatedAt: time.Now().Add(-time.Hour),
+	}
+	rec := &recorder{}
+	o := newTestOrchestrator(store, rec, false)
+
+	recovered, err := o.Recover(context.Background(), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	assert.Equal(t, []string{"b", "c"}, rec.calls)
+	assert.Equal(t, domain.SagaStatusCompleted, store.sagas[1].Status)
+	assert.JSONEq(t, `{"counter":3}`, string(store.sagas[1].Data))
+}
+
+func TestRecover_CompensatesWhenConfigured(t *testing.T) {
+	store := newMemoryStore()
+	store.sagas[1] = domain.Saga{
+		ID: 1, Name: "test", Status: domain.SagaStatusRunning, Step: 1,
+		Data: []byte(`{"counter":1}`), UpdatedAt: time.Now().Add(-time.Hour),
+	}
+	// свежая сага ещё выполняется другим процессом, её не трогаем
+	store.sagas[2] = domain.Saga{
+		ID: 2, Name: "test", Status: domain.SagaStatusRunning, Step: 1,
+		Data: []byte(`{"counter":1}`), UpdatedAt: time.Now(),
+	}
+	rec := &recorder{}
+	o := newTestOrchestrator(store, rec, true)
+
+	recovered, err := o.Recover(context.Background(), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	assert.Equal(t, []string{"undo b", "undo a"}, rec.calls)
+	assert.Equal(t, domain.SagaStatusCompensated, store.sagas[1].Status)
+	assert.Equal(t, domain.SagaStatusRunning, store.sagas[2].Status)
+}
+
+func TestRecover_RetriesFailedCompensation(t *testing.T) {
+	store := newMemoryStore()
+	rec := &recorder{fail: "b"}
+	o := newTestOrchestrator(store, rec, false)
+
+	failUndo := true
+	o.def.Steps[0].Compensate = func(ctx context.Context, data *testData) error {
+		if failUndo {
+			return errors.New("inventory unavailable")
+		}
+		rec.calls = append(rec.calls, "undo a")
+		return nil
+	}
+
+	require.Error(t, o.Execute(context.Background(), &testData{}))
+	assert.Equal(t, domain.SagaStatusCompensating, store.sagas[1].Status)
+	assert.Equal(t, 0, store.sagas[1].Step)
+
+	failUndo = false
+	rec.calls = nil
+	o.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	recovered, err := o.Recover(context.Background(), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	assert.Equal(t, []string{"undo a"}, rec.calls)
+	assert.Equal(t, domain.SagaStatusCompensated, store.sagas[1].Status)
+}
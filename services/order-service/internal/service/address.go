@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/mapper"
+)
+
+var ErrAddressNotFound = apperror.New(apperror.CodeNotFound, "address not found")
+
+// maxAddressLabelLength соответствует VARCHAR(100) колонки addresses.label
+const maxAddressLabelLength = 100
+
+// ListAddresses адресная книга пользователя
+func (s *defaultOrderService) ListAddresses(ctx context.Context, userID int64) ([]*dto.AddressResponse, error) {
+	addresses, err := s.storage.GetUserAddresses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service.ListAddresses: failed to get addresses: %w", err)
+	}
+
+	response := make([]*dto.AddressResponse, 0, len(addresses))
+	for _, address := range addresses {
+		response = append(response, mapper.MapToAddressResponseFromSavedAddress(address))
+	}
+	return response, nil
+}
+
+// GetAddress чужие адреса не видны: для них, как и для несуществующих, ErrAddressNotFound
+func (s *defaultOrderService) GetAddress(ctx context.Context, userID, id int64) (*dto.AddressResponse, error) {
+	address, err := s.storage.GetAddress(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("service.GetAddress: %w", ErrAddressNotFound)
+		}
+		return nil, fmt.Errorf("service.GetAddress: failed to get address: %w", err)
+	}
+	return mapper.MapToAddressResponseFromSavedAddress(address), nil
+}
+
+func (s *defaultOrderService) CreateAddress(ctx context.Context, userID int64, request *dto.SaveAddressRequest) (*dto.AddressResponse, error) {
+	const op = "service.CreateAddress"
+
+	label, address, err := parseSaveAddressRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := s.dbNow()
+	saved := &domain.SavedAddress{
+		UserID:    userID,
+		Label:     label,
+		Address:   address,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.storage.CreateAddress(ctx, saved); err != nil {
+		return nil, fmt.Errorf("%s: failed to create address: %w", op, err)
+	}
+
+	return mapper.MapToAddressResponseFromSavedAddress(saved), nil
+}
+
+// UpdateAddress заменяет адрес целиком; уже оформленные заказы хранят копию и не меняются
+func (s *defaultOrderService) UpdateAddress(ctx context.Context, userID, id int64, request *dto.SaveAddressRequest) (*dto.AddressResponse, error) {
+	const op = "service.UpdateAddress"
+
+	label, address, err := parseSaveAddressRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := s.storage.GetAddress(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrAddressNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to get address: %w", op, err)
+	}
+
+	saved.Label = label
+	saved.Address = address
+	saved.UpdatedAt = s.dbNow()
+	if err := s.storage.UpdateAddress(ctx, saved); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrAddressNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to update address: %w", op, err)
+	}
+
+	return mapper.MapToAddressResponseFromSavedAddress(saved), nil
+}
+
+func (s *defaultOrderService) DeleteAddress(ctx context.Context, userID, id int64) error {
+	if err := s.storage.DeleteAddress(ctx, userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("service.DeleteAddress: %w", ErrAddressNotFound)
+		}
+		return fmt.Errorf("service.DeleteAddress: failed to delete address: %w", err)
+	}
+	return nil
+}
+
+func parseSaveAddressRequest(request *dto.SaveAddressRequest) (string, domain.Address, error) {
+	label := strings.TrimSpace(request.Label)
+	if utf8.RuneCountInString(label) > maxAddressLabelLength {
+		return "", domain.Address{}, domain.ErrInvalidAddress.WithFields(
+			apperror.Field("label", fmt.Sprintf("must be at most %d characters", maxAddressLabelLength)),
+		)
+	}
+
+	address := mapper.MapToAddressFromDTOAddress(&request.Address)
+	address.Normalize()
+	if err := address.Validate("address"); err != nil {
+		return "", domain.Address{}, err
+	}
+	return label, address, nil
+}
+
+// resolveOrderAddresses адреса заказа из запроса или адресной книги пользователя.
+// Адрес оплаты nil, если он не задан: оплата идёт по адресу доставки.
+func (s *defaultOrderService) resolveOrderAddresses(ctx context.Context, request *dto.CreateOrderRequest) (*domain.Address, *domain.Address, error) {
+	shipping, err := s.resolveAddress(ctx, request.UserID, request.ShippingAddress, request.ShippingAddressID, "shipping_address")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	billing, err := s.resolveAddress(ctx, request.UserID, request.BillingAddress, request.BillingAddressID, "billing_address")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return shipping, billing, nil
+}
+
+// resolveAddress адрес, заданный целиком, проверяется; адрес из книги берётся как есть. nil — адрес не задан.
+func (s *defaultOrderService) resolveAddress(ctx context.Context, userID int64, address *dto.Address, id *int64, field string) (*domain.Address, error) {
+	if id != nil {
+		saved, err := s.storage.GetAddress(ctx, userID, *id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, domain.ErrInvalidAddress.WithFields(apperror.Field(field+"_id", fmt.Sprintf("address %d not found", *id)))
+			}
+			return nil, fmt.Errorf("failed to get address: %w", err)
+		}
+		return &saved.Address, nil
+	}
+
+	if address == nil {
+		return nil, nil
+	}
+
+	resolved := mapper.MapToAddressFromDTOAddress(address)
+	resolved.Normalize()
+	if err := resolved.Validate(field); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+// pricingRegion налоги и доставка считаются по стране доставки. Поле region запроса
+// осталось для совместимости и должно с ней совпадать.
+func pricingRegion(request *dto.CreateOrderRequest, shipping *domain.Address) (string, error) {
+	if request.Region != "" && !strings.EqualFold(request.Region, shipping.Country) {
+		return "", domain.ErrInvalidAddress.WithFields(
+			apperror.Field("region", fmt.Sprintf("must match shipping_address country %s", shipping.Country)),
+		)
+	}
+	return shipping.Country, nil
+}
+
+// validateOrderAddresses адрес доставки обязателен; каждый адрес задаётся либо целиком, либо ID
+func validateOrderAddresses(req *dto.OrderAddressesRequest) error {
+	if req.ShippingAddress == nil && req.ShippingAddressID == nil {
+		return domain.ErrInvalidAddress.WithFields(apperror.Field("shipping_address", "is required"))
+	}
+	if req.ShippingAddress != nil && req.ShippingAddressID != nil {
+		return domain.ErrInvalidAddress.WithFields(apperror.Field("shipping_address_id", "must not be set together with shipping_address"))
+	}
+	if req.BillingAddress != nil && req.BillingAddressID != nil {
+		return domain.ErrInvalidAddress.WithFields(apperror.Field("billing_address_id", "must not be set together with billing_address"))
+	}
+	return nil
+}
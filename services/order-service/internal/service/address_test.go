@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAddress() *dto.Address {
+	return &dto.Address{
+		Recipient:  "Иван Петров",
+		Country:    "ru",
+		City:       "Москва",
+		PostalCode: "101000",
+		Line1:      " ул. Тверская, 1 ",
+	}
+}
+
+func TestCreateOrder_StoresAddressSnapshot(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	saved, err := svc.CreateAddress(ctx, 42, &dto.SaveAddressRequest{Label: "Дом", Address: *newTestAddress()})
+	require.NoError(t, err)
+	assert.Equal(t, "RU", saved.Address.Country)
+	assert.Equal(t, "ул. Тверская, 1", saved.Address.Line1)
+
+	billing := newTestAddress()
+	billing.City = "Казань"
+
+	req := newCreateOrderRequest(1)
+	req.ShippingAddress = nil
+	req.ShippingAddressID = &saved.ID
+	req.BillingAddress = billing
+	order, err := svc.CreateOrder(ctx, req)
+	require.NoError(t, err)
+
+	require.NotNil(t, order.ShippingAddress)
+	assert.Equal(t, saved.Address, *order.ShippingAddress)
+	require.NotNil(t, order.BillingAddress)
+	assert.Equal(t, "Казань", order.BillingAddress.City)
+
+	// правка адресной книги не меняет оформленный заказ
+	moved := newTestAddress()
+	moved.City = "Тверь"
+	_, err = svc.UpdateAddress(ctx, 42, saved.ID, &dto.SaveAddressRequest{Address: *moved})
+	require.NoError(t, err)
+
+	got, err := svc.GetOrder(ctx, order.ID, domain.Actor{UserID: 42, Role: domain.RoleUser})
+	require.NoError(t, err)
+	assert.Equal(t, "Москва", got.ShippingAddress.City)
+}
+
+func TestCreateOrder_InvalidAddress(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	foreign, err := svc.CreateAddress(ctx, 43, &dto.SaveAddressRequest{Address: *newTestAddress()})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		modify    func(req *dto.CreateOrderRequest)
+		wantField string
+	}{
+		{
+			name:      "missing",
+			modify:    func(req *dto.CreateOrderRequest) { req.ShippingAddress = nil },
+			wantField: "shipping_address",
+		},
+		{
+			name:      "address and id",
+			modify:    func(req *dto.CreateOrderRequest) { req.ShippingAddressID = &foreign.ID },
+			wantField: "shipping_address_id",
+		},
+		{
+			name:      "empty city",
+			modify:    func(req *dto.CreateOrderRequest) { req.ShippingAddress.City = " " },
+			wantField: "shipping_address.city",
+		},
+		{
+			name:      "bad country",
+			modify:    func(req *dto.CreateOrderRequest) { req.ShippingAddress.Country = "Russia" },
+			wantField: "shipping_address.country",
+		},
+		{
+			name: "another user's address",
+			modify: func(req *dto.CreateOrderRequest) {
+				req.ShippingAddress = nil
+				req.ShippingAddressID = &foreign.ID
+			},
+			wantField: "shipping_address_id",
+		},
+		{
+			name:      "region differs from country",
+			modify:    func(req *dto.CreateOrderRequest) { req.Region = "KZ" },
+			wantField: "region",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newCreateOrderRequest(1)
+			tt.modify(req)
+
+			_, err := svc.CreateOrder(ctx, req)
+			require.ErrorIs(t, err, domain.ErrInvalidAddress)
+			appErr, ok := apperror.As(err)
+			require.True(t, ok)
+			require.NotEmpty(t, appErr.Fields)
+			assert.Equal(t, tt.wantField, appErr.Fields[0].Field)
+		})
+	}
+	assert.Empty(t, storage.orders)
+}
+
+func TestAddressBook_OwnAddressesOnly(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(&fakeOrderStorage{})
+
+	own, err := svc.CreateAddress(ctx, 42, &dto.SaveAddressRequest{Label: "Работа", Address: *newTestAddress()})
+	require.NoError(t, err)
+	_, err = svc.CreateAddress(ctx, 43, &dto.SaveAddressRequest{Address: *newTestAddress()})
+	require.NoError(t, err)
+
+	list, err := svc.ListAddresses(ctx, 42)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "Работа", list[0].Label)
+
+	_, err = svc.GetAddress(ctx, 43, own.ID)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	_, err = svc.UpdateAddress(ctx, 43, own.ID, &dto.SaveAddressRequest{Address: *newTestAddress()})
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	assert.ErrorIs(t, svc.DeleteAddress(ctx, 43, own.ID), ErrAddressNotFound)
+
+	require.NoError(t, svc.DeleteAddress(ctx, 42, own.ID))
+	_, err = svc.GetAddress(ctx, 42, own.ID)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+
+	_, err = svc.CreateAddress(ctx, 42, &dto.SaveAddressRequest{Address: dto.Address{Recipient: "X"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidAddress)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/cache"
+)
+
+// CachedProductClient декоратор ProductClient с кэшем снимков товаров.
+// Одновременные запросы одного и того же ID объединяются в один вызов product-service;
+// общий вызов не зависит от отмены запроса, который его начал, и ограничен timeout.
+// Если cacheStock выключен, из кэша отдаются только название, картинка и цена,
+// а CountInStock равен dto.StockUnknown.
+type CachedProductClient struct {
+	next       ProductClient
+	cache      *cache.LRU[int64, dto.ExternalProduct]
+	cacheStock bool
+	timeout    time.Duration
+
+	mu       sync.Mutex
+	inflight map[int64]*productCall
+
+	deduplicated atomic.Int64
+}
+
+// productCall один запрос к product-service, которого могут ждать несколько вызывающих
+type productCall struct {
+	done     chan struct{}
+	products map[int64]*dto.ExternalProduct
+	err      error
+}
+
+func NewCachedProductClient(next ProductClient, cfg config.ProductCacheConfig, timeout time.Duration) *CachedProductClient {
+	return &CachedProductClient{
+		next:       next,
+		cache:      cache.NewLRU[int64, dto.ExternalProduct](cfg.MaxSize, cfg.TTL),
+		cacheStock: cfg.CacheStock,
+		timeout:    timeout,
+		inflight:   make(map[int64]*productCall),
+	}
+}
+
+func (c *CachedProductClient) GetProductsByIDs(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	const op = "service.CachedProductClient.GetProductsByIDs"
+
+	found := make(map[int64]*dto.ExternalProduct, len(ids))
+	var missing []int64
+
+	for _, id := range ids {
+		if product, ok := c.cache.Get(id); ok {
+			if !c.cacheStock {
+				product.CountInStock = dto.StockUnknown
+			}
+			found[id] = &product
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		if err := c.load(ctx, missing, found); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	products := make([]*dto.ExternalProduct, 0, len(ids))
+	for _, id := range ids {
+		product, ok := found[id]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w: %d", op, ErrProductNotFound, id)
+		}
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// load догружает отсутствующие в кэше товары. ID, которые уже запрашивает другая горутина,
+// не запрашиваются повторно: ждём её результата. Каждый вызывающий ждёт не дольше своего ctx.
+func (c *CachedProductClient) load(ctx context.Context, ids []int64, found map[int64]*dto.ExternalProduct) error {
+	waits := make(map[*productCall][]int64)
+	own := &productCall{done: make(chan struct{})}
+	var ownIDs []int64
+
+	c.mu.Lock()
+	for _, id := range ids {
+		if call, ok := c.inflight[id]; ok {
+			waits[call] = append(waits[call], id)
+			continue
+		}
+		c.inflight[id] = own
+		ownIDs = append(ownIDs, id)
+	}
+	c.mu.Unlock()
+
+	if len(ownIDs) > 0 {
+		// результат нужен и тем, кто встал в очередь за нами, поэтому запрос не отменяется вместе с ctx
+		fetchCtx := context.WithoutCancel(ctx)
+		cancel := func() {}
+		if c.timeout > 0 {
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, c.timeout)
+		}
+		go func() {
+			defer cancel()
+			c.fetch(fetchCtx, own, ownIDs)
+		}()
+		waits[own] = ownIDs
+	}
+
+	for call, callIDs := range waits {
+		if call != own {
+			c.deduplicated.Add(int64(len(callIDs)))
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if call.err != nil {
+			return call.err
+		}
+		for _, id := range callIDs {
+			if product, ok := call.products[id]; ok {
+				found[id] = product
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CachedProductClient) fetch(ctx context.Context, call *productCall, ids []int64) {
+	defer func() {
+		c.mu.Lock()
+		for _, id := range ids {
+			delete(c.inflight, id)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	products, err := c.next.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	call.products = make(map[int64]*dto.ExternalProduct, len(products))
+	for _, product := range products {
+		call.products[product.ID] = product
+		c.cache.Set(product.ID, *product)
+	}
+}
+
+func (c *CachedProductClient) Name() string {
+	return "product-cache"
+}
+
+type ProductCacheStatus struct {
+	cache.Stats
+	Deduplicated int64 `json:"deduplicated"`
+}
+
+func (c *CachedProductClient) Status() any {
+	return ProductCacheStatus{
+		Stats:        c.cache.Stats(),
+		Deduplicated: c.deduplicated.Load(),
+	}
+}
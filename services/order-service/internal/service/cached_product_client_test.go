@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProductClient считает запрошенные ID; если задан release, ждёт его перед ответом
+type countingProductClient struct {
+	requested atomic.Int32
+	started   chan struct{}
+	release   chan struct{}
+	err       error
+}
+
+func (c *countingProductClient) GetProductsByIDs(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	c.requested.Add(int32(len(ids)))
+	if c.started != nil {
+		c.started <- struct{}{}
+	}
+	if c.release != nil {
+		<-c.release
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return NewStubProductClient().GetProductsByIDs(ctx, ids)
+}
+
+func cacheConfig(cacheStock bool) config.ProductCacheConfig {
+	return config.ProductCacheConfig{Enabled: true, TTL: time.Minute, MaxSize: 100, CacheStock: cacheStock}
+}
+
+func TestCachedProductClient_ServesFromCache(t *testing.T) {
+	ctx := context.Background()
+	next := &countingProductClient{}
+	client := NewCachedProductClient(next, cacheConfig(false), time.Second)
+
+	products, err := client.GetProductsByIDs(ctx, []int64{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), products[0].CountInStock, "fresh lookup returns real stock")
+
+	products, err = client.GetProductsByIDs(ctx, []int64{2, 1, 3})
+	require.NoError(t, err)
+	require.Len(t, products, 3)
+	assert.Equal(t, []int64{2, 1, 3}, []int64{products[0].ID, products[1].ID, products[2].ID})
+	assert.Equal(t, dto.StockUnknown, products[0].CountInStock, "stock must not be served from cache")
+	assert.Equal(t, int64(100), products[2].CountInStock)
+
+	assert.Equal(t, int32(3), next.requested.Load())
+
+	status := client.Status().(ProductCacheStatus)
+	assert.Equal(t, int64(2), status.Hits)
+	assert.Equal(t, int64(3), status.Misses)
+}
+
+func TestCachedProductClient_CachesStockWhenEnabled(t *testing.T) {
+	ctx := context.Background()
+	client := NewCachedProductClient(&countingProductClient{}, cacheConfig(true), time.Second)
+
+	_, err := client.GetProductsByIDs(ctx, []int64{1})
+	require.NoError(t, err)
+
+	products, err := client.GetProductsByIDs(ctx, []int64{1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), products[0].CountInStock)
+}
+
+func TestCachedProductClient_DeduplicatesConcurrentLookups(t *testing.T) {
+	ctx := context.Background()
+	next := &countingProductClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	client := NewCachedProductClient(next, cacheConfig(false), time.Second)
+
+	var wg sync.WaitGroup
+	results := make([][]*dto.ExternalProduct, 5)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = client.GetProductsByIDs(ctx, []int64{7})
+	}()
+	<-next.started
+
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = client.GetProductsByIDs(ctx, []int64{7})
+		}()
+	}
+
+	// ждём, пока все вызывающие встанут в очередь за первым запросом
+	require.Eventually(t, func() bool {
+		return client.Status().(ProductCacheStatus).Deduplicated == int64(len(results)-1)
+	}, time.Second, time.Millisecond)
+
+	close(next.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), next.requested.Load())
+	for _, products := range results {
+		require.Len(t, products, 1)
+		assert.Equal(t, int64(7), products[0].ID)
+	}
+}
+
+func TestCachedProductClient_DoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	next := &countingProductClient{err: ErrProductServiceUnavailable}
+	client := NewCachedProductClient(next, cacheConfig(false), time.Second)
+
+	_, err := client.GetProductsByIDs(ctx, []int64{1})
+	assert.True(t, errors.Is(err, ErrProductServiceUnavailable))
+
+	next.err = nil
+	products, err := client.GetProductsByIDs(ctx, []int64{1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), products[0].ID)
+	assert.Equal(t, int32(2), next.requested.Load())
+}
+
+func TestCachedProductClient_SharedLookupOutlivesFirstCaller(t *testing.T) {
+	next := &countingProductClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	client := NewCachedProductClient(next, cacheConfig(false), time.Second)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := client.GetProductsByIDs(firstCtx, []int64{7})
+		firstDone <- err
+	}()
+	<-next.started
+
+	var products []*dto.ExternalProduct
+	secondDone := make(chan error)
+	go func() {
+		var err error
+		products, err = client.GetProductsByIDs(context.Background(), []int64{7})
+		secondDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		return client.Status().(ProductCacheStatus).Deduplicated == 1
+	}, time.Second, time.Millisecond)
+
+	// первый вызывающий уходит, не дожидаясь ответа product-service
+	cancelFirst()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+
+	close(next.release)
+	require.NoError(t, <-secondDone)
+	require.Len(t, products, 1)
+	assert.Equal(t, int64(7), products[0].ID)
+	assert.Equal(t, int32(1), next.requested.Load())
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+var (
+	ErrCartEmpty         = apperror.New(apperror.CodeInvalidArgument, "cart is empty")
+	ErrCartItemNotFound  = apperror.New(apperror.CodeNotFound, "cart item not found")
+	ErrCartPricesChanged = apperror.New(apperror.CodeConflict, "cart prices changed")
+)
+
+// GetCart корзина пользователя по текущим ценам и остаткам product-service
+func (s *defaultOrderService) GetCart(ctx context.Context, userID int64) (*dto.CartResponse, error) {
+	const op = "service.GetCart"
+
+	items, products, err := s.loadCart(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return cartResponse(items, products), nil
+}
+
+// AddCartItem добавляет товар в корзину; если он уже там, количество складывается
+func (s *defaultOrderService) AddCartItem(ctx context.Context, userID int64, request *dto.AddCartItemRequest) (*dto.CartResponse, error) {
+	const op = "service.AddCartItem"
+
+	if request.ProductID <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidProductID.WithFields(apperror.Field("product_id", "must be positive")))
+	}
+	if request.Quantity <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidQuantity.WithFields(apperror.Field("quantity", "must be positive")))
+	}
+
+	items, err := s.storage.GetCartItems(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get cart: %w", op, err)
+	}
+
+	item := findCartItem(items, request.ProductID)
+	if item == nil {
+		item = &domain.CartItem{UserID: userID, ProductID: request.ProductID, CreatedAt: s.dbNow()}
+	}
+
+	if err := s.saveCartItem(ctx, item, item.Quantity+request.Quantity); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.GetCart(ctx, userID)
+}
+
+// UpdateCartItem заменяет количество товара в корзине
+func (s *defaultOrderService) UpdateCartItem(ctx context.Context, userID, productID int64, request *dto.UpdateCartItemRequest) (*dto.CartResponse, error) {
+	const op = "service.UpdateCartItem"
+
+	if request.Quantity <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidQuantity.WithFields(apperror.Field("quantity", "must be positive")))
+	}
+
+	items, err := s.storage.GetCartItems(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get cart: %w", op, err)
+	}
+
+	item := findCartItem(items, productID)
+	if item == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrCartItemNotFound)
+	}
+
+	if err := s.saveCartItem(ctx, item, request.Quantity); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.GetCart(ctx, userID)
+}
+
+func (s *defaultOrderService) RemoveCartItem(ctx context.Context, userID, productID int64) (*dto.CartResponse, error) {
+	const op = "service.RemoveCartItem"
+
+	if err := s.storage.DeleteCartItem(ctx, userID, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrCartItemNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to delete cart item: %w", op, err)
+	}
+
+	return s.GetCart(ctx, userID)
+}
+
+// Checkout оформляет заказ из корзины обычным созданием заказа. Если цены изменились после
+// добавления товаров, заказ не создаётся: корзина запоминает новые цены, а клиент получает 409
+// и должен подтвердить их повторным запросом. Корзина очищается в транзакции записи заказа.
+func (s *defaultOrderService) Checkout(ctx context.Context, userID int64, request *dto.CheckoutRequest) (*dto.OrderResponse, error) {
+	const op = "service.Checkout"
+
+	items, products, err := s.loadCart(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrCartEmpty)
+	}
+
+	var changed []apperror.FieldError
+	orderReq := &dto.CreateOrderRequest{
+		PaymentMethod:         request.PaymentMethod,
+		UserID:                userID,
+		Region:                request.Region,
+		PromoCode:             request.PromoCode,
+		OrderAddressesRequest: request.OrderAddressesRequest,
+		Items:                 make([]*dto.CreateOrderItemRequest, 0, len(items)),
+	}
+	// upTo последнее изменение корзины, которое видит заказ
+	var upTo time.Time
+
+	for i, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", op, ErrProductNotFound.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].product_id", i), fmt.Sprintf("product %d not found", item.ProductID),
+			)))
+		}
+
+		if product.Price.Cmp(item.Price) != 0 {
+			changed = append(changed, apperror.Field(
+				fmt.Sprintf("items[%d].price", i),
+				fmt.Sprintf("product %d: %s -> %s", item.ProductID, item.Price, product.Price),
+			))
+			item.Price = product.Price
+			item.UpdatedAt = s.dbNow()
+			if err := s.storage.SaveCartItem(ctx, item); err != nil {
+				return nil, fmt.Errorf("%s: failed to save cart item: %w", op, err)
+			}
+		}
+
+		if item.UpdatedAt.After(upTo) {
+			upTo = item.UpdatedAt
+		}
+		orderReq.Items = append(orderReq.Items, &dto.CreateOrderItemRequest{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	if len(changed) > 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrCartPricesChanged.WithFields(changed...))
+	}
+
+	response, err := s.createOrder(ctx, orderReq, op, func(ctx context.Context, _ *dto.OrderResponse) error {
+		if err := s.storage.ClearCart(ctx, userID, upTo); err != nil {
+			return fmt.Errorf("failed to clear cart: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("cart checked out",
+		slog.Int64("order_id", response.ID),
+		slog.Int64("user_id", userID),
+	)
+
+	return response, nil
+}
+
+// saveCartItem проверяет товар в product-service и сохраняет позицию корзины с количеством
+// quantity по текущей цене
+func (s *defaultOrderService) saveCartItem(ctx context.Context, item *domain.CartItem, quantity int64) error {
+	products, err := s.productClient.GetProductsByIDs(ctx, []int64{item.ProductID})
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return ErrProductNotFound.WithFields(apperror.Field("product_id", fmt.Sprintf("product %d not found", item.ProductID)))
+		}
+		return fmt.Errorf("failed to get product from product-service: %w", err)
+	}
+	if len(products) == 0 {
+		return ErrProductNotFound.WithFields(apperror.Field("product_id", fmt.Sprintf("product %d not found", item.ProductID)))
+	}
+
+	product := products[0]
+	if product.CountInStock != dto.StockUnknown && product.CountInStock < quantity {
+		return ErrInsufficientStock.WithFields(apperror.Field(
+			"quantity", fmt.Sprintf("product %d: requested %d, available %d", item.ProductID, quantity, product.CountInStock),
+		))
+	}
+
+	item.Quantity = quantity
+	item.Price = product.Price
+	item.UpdatedAt = s.dbNow()
+	if err := s.storage.SaveCartItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to save cart item: %w", err)
+	}
+	return nil
+}
+
+// loadCart позиции корзины и их продукты из product-service
+func (s *defaultOrderService) loadCart(ctx context.Context, userID int64) ([]*domain.CartItem, map[int64]*dto.ExternalProduct, error) {
+	items, err := s.storage.GetCartItems(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+	if len(items) == 0 {
+		return items, nil, nil
+	}
+
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := s.productClient.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get products from product-service: %w", err)
+	}
+
+	productMap := make(map[int64]*dto.ExternalProduct, len(products))
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+	return items, productMap, nil
+}
+
+func findCartItem(items []*domain.CartItem, productID int64) *domain.CartItem {
+	for _, item := range items {
+		if item.ProductID == productID {
+			return item
+		}
+	}
+	return nil
+}
+
+// cartResponse товары, пропавшие из product-service, показываются по сохранённой цене как недоступные
+func cartResponse(items []*domain.CartItem, products map[int64]*dto.ExternalProduct) *dto.CartResponse {
+	response := &dto.CartResponse{
+		Items:      make([]*dto.CartItemResponse, 0, len(items)),
+		ItemsPrice: money.Zero(),
+		Currency:   money.DefaultCurrency,
+	}
+
+	for _, item := range items {
+		resp := &dto.CartItemResponse{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}
+
+		if product, ok := products[item.ProductID]; ok {
+			resp.Name = product.Name
+			resp.Image = product.Image
+			resp.Price = product.Price
+			resp.Available = product.CountInStock == dto.StockUnknown || product.CountInStock >= item.Quantity
+			if product.Price.Cmp(item.Price) != 0 {
+				previous := item.Price
+				resp.PreviousPrice = &previous
+			}
+		}
+
+		resp.Total = resp.Price.Mul(item.Quantity)
+		response.ItemsPrice = response.ItemsPrice.Add(resp.Total)
+		response.Items = append(response.Items, resp)
+	}
+
+	return response
+}
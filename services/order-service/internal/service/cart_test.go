@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repricedProductClient заглушка product-service, цены которой тест может поменять
+type repricedProductClient struct {
+	prices map[int64]money.Money
+}
+
+func (c *repricedProductClient) GetProductsByIDs(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	products, err := NewStubProductClient().GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		if price, ok := c.prices[product.ID]; ok {
+			product.Price = price
+		}
+	}
+	return products, nil
+}
+
+func newCartTestService(storage OrderStorage, products ProductClient) *defaultOrderService {
+	return NewDefaultOrderService(slogdiscard.NewDiscardLogger(), storage, fakeTxManager{}, products, newTestInventory(), newTestPayments(nil), newTestCarriers(newFakeCarrier()), fakePricingEngine{}, config.IdempotencyConfig{TTL: time.Hour})
+}
+
+func newCheckoutRequest() *dto.CheckoutRequest {
+	return &dto.CheckoutRequest{
+		PaymentMethod:         "card",
+		OrderAddressesRequest: dto.OrderAddressesRequest{ShippingAddress: newTestAddress()},
+	}
+}
+
+func TestAddCartItem_MergesQuantity(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	_, err := svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+	cart, err := svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 1, Quantity: 3})
+	require.NoError(t, err)
+
+	require.Len(t, cart.Items, 1)
+	assert.Equal(t, int64(5), cart.Items[0].Quantity)
+	assert.True(t, cart.Items[0].Available)
+	assert.Equal(t, rub("500.00"), cart.ItemsPrice)
+
+	// на складе заглушки 100 штук
+	_, err = svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 1, Quantity: 96})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	_, err = svc.UpdateCartItem(ctx, 42, 2, &dto.UpdateCartItemRequest{Quantity: 1})
+	assert.ErrorIs(t, err, ErrCartItemNotFound)
+	_, err = svc.RemoveCartItem(ctx, 43, 1)
+	assert.ErrorIs(t, err, ErrCartItemNotFound)
+}
+
+func TestCheckout_CreatesOrderAndClearsCart(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	_, err := svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+	_, err = svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 2, Quantity: 1})
+	require.NoError(t, err)
+	_, err = svc.AddCartItem(ctx, 43, &dto.AddCartItemRequest{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+
+	order, err := svc.Checkout(ctx, 42, newCheckoutRequest())
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(42), order.UserID)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, rub("400.00"), order.TotalPrice)
+
+	cart, err := svc.GetCart(ctx, 42)
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items)
+	// корзины других пользователей не затрагиваются
+	assert.Len(t, storage.cart, 1)
+
+	_, err = svc.Checkout(ctx, 42, newCheckoutRequest())
+	assert.ErrorIs(t, err, ErrCartEmpty)
+}
+
+func TestCheckout_PriceChangedRequiresConfirmation(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	products := &repricedProductClient{}
+	svc := newCartTestService(storage, products)
+
+	_, err := svc.AddCartItem(ctx, 42, &dto.AddCartItemRequest{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+
+	products.prices = map[int64]money.Money{1: rub("120.00")}
+
+	cart, err := svc.GetCart(ctx, 42)
+	require.NoError(t, err)
+	require.NotNil(t, cart.Items[0].PreviousPrice)
+	assert.Equal(t, rub("100.00"), *cart.Items[0].PreviousPrice)
+	assert.Equal(t, rub("240.00"), cart.ItemsPrice)
+
+	_, err = svc.Checkout(ctx, 42, newCheckoutRequest())
+	require.ErrorIs(t, err, ErrCartPricesChanged)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	require.Len(t, appErr.Fields, 1)
+	assert.Equal(t, "items[0].price", appErr.Fields[0].Field)
+	assert.Empty(t, storage.orders)
+
+	// корзина запомнила новую цену: повторное оформление её подтверждает
+	order, err := svc.Checkout(ctx, 42, newCheckoutRequest())
+	require.NoError(t, err)
+	assert.Equal(t, rub("240.00"), order.TotalPrice)
+	assert.Empty(t, storage.cart)
+}
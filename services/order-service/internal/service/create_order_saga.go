@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/mapper"
+	"github.com/defan6/market/services/order-service/internal/pricing"
+	"github.com/defan6/market/services/order-service/internal/saga"
+)
+
+const createOrderSagaName = "create_order"
+
+// createOrderSagaData состояние саги создания заказа. В БД сохраняется только Order:
+// запрос, колбэк и ответ нужны лишь процессу, который обслуживает запрос клиента.
+type createOrderSagaData struct {
+	Order *domain.Order `json:"order,omitempty"`
+
+	request  *dto.CreateOrderRequest
+	inTx     func(ctx context.Context, response *dto.OrderResponse) error
+	response *dto.OrderResponse
+}
+
+// newCreateOrderSaga шаги создания заказа: подготовка (товары, промокод, цены, ID заказа),
+// резерв остатков, авторизация оплаты и запись заказа одной транзакцией. Запись — последний шаг:
+// после неё откатывать нечего, дальше заказ живёт своим жизненным циклом.
+func (s *defaultOrderService) newCreateOrderSaga(store saga.Store) *saga.Orchestrator[createOrderSagaData] {
+	return saga.NewOrchestrator(s.log, store, saga.Definition[createOrderSagaData]{
+		Name: createOrderSagaName,
+		Steps: []saga.Step[createOrderSagaData]{
+			{Name: "prepare_order", Action: s.prepareOrder},
+			{Name: "reserve_stock", Action: s.reserveStock, Compensate: s.releaseStock},
+			{Name: "authorize_payment", Action: s.authorizePayment, Compensate: s.voidPaymentIfNotSaved},
+			{Name: "save_order", Action: s.saveOrder},
+		},
+		// клиент прерванного запроса ответа уже не получит и, скорее всего, повторит его:
+		// такую сагу откатываем, а не создаём заказ за него
+		CompensateOnRecover: true,
+	})
+}
+
+func (s *defaultOrderService) prepareOrder(ctx context.Context, data *createOrderSagaData) error {
+	const op = "service.prepareOrder"
+
+	request := data.request
+
+	// Получаем продукты из product-service (цены не доверяем клиенту!)
+	domainItems, priceLines, err := s.processOrderRequest(ctx, request, op)
+	if err != nil {
+		return fmt.Errorf("failed to process order request: %w", err)
+	}
+
+	// Промокод проверяется до расчёта цены: скидка применяется до налога
+	var promo *domain.PromoCode
+	if request.PromoCode != "" {
+		promo, err = s.checkPromoCode(ctx, request.PromoCode, request.UserID, domainItems, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	shipping, billing, err := s.resolveOrderAddresses(ctx, request)
+	if err != nil {
+		return err
+	}
+	region, err := pricingRegion(request, shipping)
+	if err != nil {
+		return err
+	}
+
+	// Рассчитываем цены
+	breakdown, err := s.pricing.Calculate(ctx, pricing.Quote{Region: region, Lines: priceLines, Promo: promo})
+	if err != nil {
+		return fmt.Errorf("failed to calculate prices: %w", err)
+	}
+
+	for i, item := range domainItems {
+		if i < len(breakdown.LineTaxRules) {
+			item.TaxRule = breakdown.LineTaxRules[i]
+		}
+	}
+
+	id, err := s.storage.NextOrderID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to allocate order id: %w", err)
+	}
+
+	data.Order = &domain.Order{
+		ID:              id,
+		PaymentMethod:   request.PaymentMethod,
+		DiscountPrice:   breakdown.DiscountPrice,
+		TaxPrice:        breakdown.TaxPrice,
+		ShippingPrice:   breakdown.ShippingPrice,
+		TotalPrice:      breakdown.TotalPrice,
+		UserID:          request.UserID,
+		Status:          domain.OrderStatusPending,
+		CreatedAt:       s.dbNow(),
+		ShippingAddress: shipping,
+		BillingAddress:  billing,
+		Items:           domainItems,
+		PriceLines:      breakdown.Lines,
+		Version:         1,
+	}
+	if promo != nil {
+		data.Order.PromoCode = &promo.Code
+	}
+
+	return nil
+}
+
+// dbNow текущее время с точностью timestamptz (микросекунды), чтобы ответ на создание
+// совпадал с заказом, прочитанным из БД
+func (s *defaultOrderService) dbNow() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
+
+func (s *defaultOrderService) reserveStock(ctx context.Context, data *createOrderSagaData) error {
+	if err := s.inventory.Reserve(ctx, data.Order.ID, reservationItems(data.Order.Items)); err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	return nil
+}
+
+// releaseStock снимает резерв, если заказ так и не был записан. Записанный заказ мог не успеть
+// подтвердить резерв до падения процесса: тогда резерв подтверждается, чтобы не истечь.
+func (s *defaultOrderService) releaseStock(ctx context.Context, data *createOrderSagaData) error {
+	if data.Order == nil {
+		return nil
+	}
+
+	saved, err := s.orderSaved(ctx, data.Order.ID)
+	if err != nil {
+		return err
+	}
+	if saved {
+		s.confirmReservation(ctx, data.Order.ID)
+		return nil
+	}
+
+	if err := s.inventory.Release(ctx, data.Order.ID); err != nil {
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+	return nil
+}
+
+// orderSaved записан ли заказ. Записанный заказ мог не успеть отметить сагу завершённой:
+// его резерв и оплата снимаются уже только отменой заказа.
+func (s *defaultOrderService) orderSaved(ctx context.Context, orderID int64) (bool, error) {
+	_, err := s.storage.GetOrder(ctx, orderID)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check order: %w", err)
+}
+
+// saveOrder записывает заказ и переводит его в обработку. Каждая попытка транзакции работает
+// со своей копией заказа: после конфликта повтор начинает с заказа в pending, а не с изменённого
+// прошлой попыткой. Резерв подтверждается только после фиксации: до неё он должен истекать сам,
+// если заказ так и не запишется.
+func (s *defaultOrderService) saveOrder(ctx context.Context, data *createOrderSagaData) error {
+	var saved *domain.Order
+
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		attempt := *data.Order
+		order := &attempt
+
+		// до записи заказа: если лимит кода исчерпан, сага откатит резерв и оплату
+		promo, err := s.lockPromoCode(ctx, order)
+		if err != nil {
+			return err
+		}
+
+		if err := s.storage.CreateOrder(ctx, order); err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+
+		// Проставляем OrderID для элементов
+		for _, item := range order.Items {
+			item.OrderID = order.ID
+		}
+
+		if err := s.storage.CreateOrderItems(ctx, order.Items); err != nil {
+			return fmt.Errorf("failed to create order items: %w", err)
+		}
+
+		for _, line := range order.PriceLines {
+			line.OrderID = order.ID
+		}
+
+		if err := s.storage.CreateOrderPriceLines(ctx, order.PriceLines); err != nil {
+			return fmt.Errorf("failed to create order price lines: %w", err)
+		}
+
+		if err := s.redeemPromoCode(ctx, promo, order); err != nil {
+			return err
+		}
+
+		msg, err := newOrderCreatedMessage(order)
+		if err != nil {
+			return err
+		}
+		if err := s.writeOutbox(ctx, msg); err != nil {
+			return err
+		}
+
+		// оплата уже авторизована предыдущим шагом: заказ сразу уходит в обработку
+		history, err := order.TransitionTo(domain.OrderStatusProcessing, nil, "payment authorized", s.dbNow())
+		if err != nil {
+			return err
+		}
+		if err := s.applyStatusChange(ctx, order, history); err != nil {
+			return err
+		}
+
+		saved = order
+		data.response = mapper.MapToOrderResponseFromOrder(order)
+		if data.inTx != nil {
+			return data.inTx(ctx, data.response)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data.Order = saved
+	s.confirmReservation(ctx, saved.ID)
+	return nil
+}
+
+// confirmReservation делает резерв записанного заказа бессрочным. Ошибка только логируется:
+// заказ уже в обработке, и откатывать его из-за неё нельзя.
+func (s *defaultOrderService) confirmReservation(ctx context.Context, orderID int64) {
+	if err := s.inventory.Confirm(context.WithoutCancel(ctx), orderID); err != nil {
+		s.log.Error("failed to confirm stock reservation",
+			slog.Int64("order_id", orderID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateOrder_CompletesSaga(t *testing.T) {
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	resp, err := svc.CreateOrder(context.Background(), newCreateOrderRequest(2))
+	require.NoError(t, err)
+
+	require.Len(t, storage.sagas, 1)
+	saga := storage.sagas[0]
+	assert.Equal(t, createOrderSagaName, saga.Name)
+	assert.Equal(t, domain.SagaStatusCompleted, saga.Status)
+
+	var data createOrderSagaData
+	require.NoError(t, json.Unmarshal(saga.Data, &data))
+	assert.Equal(t, resp.ID, data.Order.ID)
+}
+
+// abandonCreateOrderSaga имитирует процесс, упавший на записи заказа: остаток
+// зарезервирован, оплата авторизована, а сага осталась в running на шаге save_order
+func abandonCreateOrderSaga(t *testing.T, svc *defaultOrderService, storage *fakeOrderStorage) *createOrderSagaData {
+	t.Helper()
+	ctx := context.Background()
+
+	data := &createOrderSagaData{request: newCreateOrderRequest(2)}
+	require.NoError(t, svc.prepareOrder(ctx, data))
+	require.NoError(t, svc.reserveStock(ctx, data))
+	require.NoError(t, svc.authorizePayment(ctx, data))
+
+	payload, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.NoError(t, storage.CreateSaga(ctx, &domain.Saga{
+		Name:      createOrderSagaName,
+		Status:    domain.SagaStatusRunning,
+		Step:      3,
+		Data:      payload,
+		UpdatedAt: time.Now().Add(-time.Hour),
+	}))
+
+	return data
+}
+
+func TestCreateOrderSaga_RecoverReleasesAbandonedReservation(t *testing.T) {
+	storage := &fakeOrderStorage{}
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(storage, inv)
+
+	abandonCreateOrderSaga(t, svc, storage)
+	assert.Equal(t, int64(98), inv.available(1))
+
+	recovered, err := svc.createOrderSaga.Recover(context.Background(), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	assert.Equal(t, int64(100), inv.available(1))
+	assert.Equal(t, domain.PaymentStatusVoided, storage.payments[0].Status)
+	assert.Equal(t, domain.SagaStatusCompensated, storage.sagas[0].Status)
+}
+
+func TestCreateOrderSaga_RecoverKeepsReservationOfSavedOrder(t *testing.T) {
+	storage := &fakeOrderStorage{}
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(storage, inv)
+
+	// заказ записан, но процесс упал до того, как отметил сагу завершённой
+	data := abandonCreateOrderSaga(t, svc, storage)
+	require.NoError(t, svc.saveOrder(context.Background(), data))
+
+	_, err := svc.createOrderSaga.Recover(context.Background(), time.Minute, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(98), inv.available(1))
+	assert.Equal(t, domain.PaymentStatusAuthorized, storage.payments[0].Status)
+	assert.Equal(t, domain.SagaStatusCompensated, storage.sagas[0].Status)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+// ErrProductServiceUnavailable временная ошибка product-service (5xx, 429, таймаут, сеть);
+// запрос можно повторить
+var ErrProductServiceUnavailable = apperror.New(apperror.CodeUnavailable, "product service unavailable")
+
+const defaultProductBatchSize = 50
+
+// HTTPProductClient клиент product-service: GET /api/v1/products?ids=1,2,3
+type HTTPProductClient struct {
+	baseURL   string
+	timeout   time.Duration
+	batchSize int
+	client    *http.Client
+}
+
+func NewHTTPProductClient(cfg config.ProductServiceConfig, client *http.Client) *HTTPProductClient {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultProductBatchSize
+	}
+
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &HTTPProductClient{
+		baseURL:   strings.TrimRight(cfg.BaseURL, "/"),
+		timeout:   cfg.Timeout,
+		batchSize: batchSize,
+		client:    client,
+	}
+}
+
+// GetProductsByIDs запрашивает товары пачками по batchSize ID.
+// Если какой-то товар не найден, возвращает ErrProductNotFound.
+func (c *HTTPProductClient) GetProductsByIDs(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	const op = "service.HTTPProductClient.GetProductsByIDs"
+
+	products := make([]*dto.ExternalProduct, 0, len(ids))
+
+	for start := 0; start < len(ids); start += c.batchSize {
+		end := min(start+c.batchSize, len(ids))
+
+		batch, err := c.fetchBatch(ctx, ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		products = append(products, batch...)
+	}
+
+	return products, nil
+}
+
+func (c *HTTPProductClient) fetchBatch(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	// таймаут из конфига действует и под дедлайном запроса: у HTTP-запроса он всегда есть и намного длиннее
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.productsURL(ids), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProductServiceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: ids %v", ErrProductNotFound, ids)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("%w: status %d", ErrProductServiceUnavailable, resp.StatusCode)
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var products []*dto.ExternalProduct
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		return nil, fmt.Errorf("failed to decode products: %w", err)
+	}
+
+	if missing := missingProductIDs(ids, products); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: ids %v", ErrProductNotFound, missing)
+	}
+
+	return products, nil
+}
+
+func (c *HTTPProductClient) productsURL(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+
+	query := url.Values{}
+	query.Set("ids", strings.Join(parts, ","))
+
+	return c.baseURL + "/api/v1/products?" + query.Encode()
+}
+
+func missingProductIDs(ids []int64, products []*dto.ExternalProduct) []int64 {
+	found := make(map[int64]struct{}, len(products))
+	for _, product := range products {
+		found[product.ID] = struct{}{}
+	}
+
+	var missing []int64
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
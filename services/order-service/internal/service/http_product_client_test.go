@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// productServer отдаёт товары с ценой 10.00 * id; ID из notFound не возвращаются
+func productServer(t *testing.T, calls *atomic.Int32, notFound ...int64) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		assert.Equal(t, "/api/v1/products", r.URL.Path)
+
+		var products []*dto.ExternalProduct
+		for _, raw := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			skip := false
+			for _, nf := range notFound {
+				skip = skip || nf == id
+			}
+			if skip {
+				continue
+			}
+
+			products = append(products, &dto.ExternalProduct{
+				ID:           id,
+				Name:         "Product-" + raw,
+				Price:        money.New(1000*id, money.DefaultCurrency),
+				CountInStock: 10,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(products))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func statusServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestHTTPProductClient_GetProductsByIDs(t *testing.T) {
+	var calls atomic.Int32
+	server := productServer(t, &calls)
+
+	client := NewHTTPProductClient(config.ProductServiceConfig{
+		BaseURL:   server.URL + "/",
+		Timeout:   time.Second,
+		BatchSize: 2,
+	}, server.Client())
+
+	products, err := client.GetProductsByIDs(context.Background(), []int64{1, 2, 3, 4, 5})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(3), calls.Load(), "5 ids with batch size 2 must take 3 requests")
+	require.Len(t, products, 5)
+	for i, product := range products {
+		assert.Equal(t, int64(i+1), product.ID)
+	}
+	assert.Equal(t, "30.00", products[2].Price.String())
+}
+
+func TestHTTPProductClient_Errors(t *testing.T) {
+	var calls atomic.Int32
+
+	tests := []struct {
+		name    string
+		server  *httptest.Server
+		wantErr error
+	}{
+		{name: "404", server: statusServer(t, http.StatusNotFound), wantErr: ErrProductNotFound},
+		{name: "missing in response", server: productServer(t, &calls, 2), wantErr: ErrProductNotFound},
+		{name: "500", server: statusServer(t, http.StatusInternalServerError), wantErr: ErrProductServiceUnavailable},
+		{name: "503", server: statusServer(t, http.StatusServiceUnavailable), wantErr: ErrProductServiceUnavailable},
+		{name: "429", server: statusServer(t, http.StatusTooManyRequests), wantErr: ErrProductServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewHTTPProductClient(config.ProductServiceConfig{BaseURL: tt.server.URL}, tt.server.Client())
+
+			_, err := client.GetProductsByIDs(context.Background(), []int64{1, 2})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("400 is not transient", func(t *testing.T) {
+		server := statusServer(t, http.StatusBadRequest)
+		client := NewHTTPProductClient(config.ProductServiceConfig{BaseURL: server.URL}, server.Client())
+
+		_, err := client.GetProductsByIDs(context.Background(), []int64{1})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrProductServiceUnavailable)
+		assert.NotErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		server := statusServer(t, http.StatusOK)
+		server.Close()
+		client := NewHTTPProductClient(config.ProductServiceConfig{BaseURL: server.URL}, nil)
+
+		_, err := client.GetProductsByIDs(context.Background(), []int64{1})
+		assert.ErrorIs(t, err, ErrProductServiceUnavailable)
+	})
+}
+
+func TestHTTPProductClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+
+	t.Run("config timeout", func(t *testing.T) {
+		client := NewHTTPProductClient(config.ProductServiceConfig{
+			BaseURL: server.URL,
+			Timeout: 20 * time.Millisecond,
+		}, server.Client())
+
+		_, err := client.GetProductsByIDs(context.Background(), []int64{1})
+		assert.ErrorIs(t, err, ErrProductServiceUnavailable)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("context deadline wins", func(t *testing.T) {
+		client := NewHTTPProductClient(config.ProductServiceConfig{
+			BaseURL: server.URL,
+			Timeout: time.Minute,
+		}, server.Client())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.GetProductsByIDs(ctx, []int64{1})
+		assert.ErrorIs(t, err, ErrProductServiceUnavailable)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("config timeout under longer context deadline", func(t *testing.T) {
+		client := NewHTTPProductClient(config.ProductServiceConfig{
+			BaseURL: server.URL,
+			Timeout: 20 * time.Millisecond,
+		}, server.Client())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.GetProductsByIDs(ctx, []int64{1})
+		assert.ErrorIs(t, err, ErrProductServiceUnavailable)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
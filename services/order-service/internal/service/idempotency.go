@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var (
+	ErrIdempotencyKeyReused  = apperror.New(apperror.CodeUnprocessable, "idempotency key reused with different request body")
+	ErrInvalidIdempotencyKey = apperror.New(apperror.CodeInvalidArgument, "invalid idempotency key")
+)
+
+const maxIdempotencyKeyLength = 255
+
+// CreateOrderIdempotent создаёт заказ не более одного раза на пару (пользователь, ключ).
+// Ответ сохраняется в той же транзакции, что и заказ, и возвращается при повторах без создания нового заказа.
+func (s *defaultOrderService) CreateOrderIdempotent(ctx context.Context, request *dto.CreateOrderRequest, key string) (*dto.IdempotentResponse, error) {
+	const op = "service.CreateOrderIdempotent"
+
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidIdempotencyKey)
+	}
+
+	hash, err := requestHash(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resp, err := s.replayIdempotent(ctx, request.UserID, key, hash); !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return resp, nil
+	}
+
+	var body []byte
+	_, err = s.createOrder(ctx, request, op, func(ctx context.Context, response *dto.OrderResponse) error {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return fmt.Errorf("failed to marshal response: %w", err)
+		}
+		body = data
+
+		now := s.now()
+		return s.storage.SaveIdempotencyKey(ctx, &domain.IdempotencyKey{
+			UserID:       request.UserID,
+			Key:          key,
+			RequestHash:  hash,
+			OrderID:      response.ID,
+			StatusCode:   http.StatusCreated,
+			ResponseBody: body,
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(s.idempotency.TTL),
+		})
+	})
+	if err != nil {
+		// ключ успела сохранить параллельная транзакция, наш заказ откатился
+		if errors.Is(err, sql.ErrNoRows) {
+			resp, err := s.replayIdempotent(ctx, request.UserID, key, hash)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			return resp, nil
+		}
+		return nil, err
+	}
+
+	return &dto.IdempotentResponse{StatusCode: http.StatusCreated, Body: body}, nil
+}
+
+// replayIdempotent возвращает сохранённый ответ или sql.ErrNoRows, если действующего ключа нет
+func (s *defaultOrderService) replayIdempotent(ctx context.Context, userID int64, key, hash string) (*dto.IdempotentResponse, error) {
+	record, err := s.storage.GetIdempotencyKey(ctx, userID, key, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if record.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	return &dto.IdempotentResponse{
+		StatusCode: record.StatusCode,
+		Body:       record.ResponseBody,
+		Replayed:   true,
+	}, nil
+}
+
+// requestHash отпечаток запроса; считается по разобранному телу, поэтому не зависит от пробелов и порядка полей
+func requestHash(request *dto.CreateOrderRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCreateOrderRequest(quantity int64) *dto.CreateOrderRequest {
+	return &dto.CreateOrderRequest{
+		PaymentMethod:         "card",
+		UserID:                42,
+		OrderAddressesRequest: dto.OrderAddressesRequest{ShippingAddress: newTestAddress()},
+		Items:                 []*dto.CreateOrderItemRequest{{ProductID: 1, Quantity: quantity}},
+	}
+}
+
+func TestCreateOrderIdempotent_ReplaysStoredResponse(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	first, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, first.StatusCode)
+	assert.False(t, first.Replayed)
+
+	second, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+	assert.True(t, second.Replayed)
+	assert.Equal(t, first.StatusCode, second.StatusCode)
+	assert.Equal(t, first.Body, second.Body)
+	assert.Len(t, storage.orders, 1)
+
+	var resp dto.OrderResponse
+	require.NoError(t, json.Unmarshal(second.Body, &resp))
+	assert.Equal(t, storage.orders[0].ID, resp.ID)
+}
+
+func TestCreateOrderIdempotent_KeyReusedWithDifferentBody(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	_, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+
+	_, err = svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(3), "key-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	assert.Len(t, storage.orders, 1)
+}
+
+func TestCreateOrderIdempotent_KeysAreScopedPerUser(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	_, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+
+	other := newCreateOrderRequest(3)
+	other.UserID = 7
+	resp, err := svc.CreateOrderIdempotent(ctx, other, "key-1")
+	require.NoError(t, err)
+	assert.False(t, resp.Replayed)
+	assert.Len(t, storage.orders, 2)
+}
+
+func TestCreateOrderIdempotent_ExpiredKeyCreatesNewOrder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	resp, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(3), "key-1")
+	require.NoError(t, err)
+	assert.False(t, resp.Replayed)
+	assert.Len(t, storage.orders, 2)
+}
+
+func TestCreateOrderIdempotent_ConcurrentSaveReplaysWinner(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	// ключ сохранила параллельная транзакция уже после нашей проверки
+	lookup := &racingStorage{fakeOrderStorage: storage}
+	svc.storage = lookup
+
+	winner := &domain.IdempotencyKey{
+		UserID:       42,
+		Key:          "key-1",
+		StatusCode:   http.StatusCreated,
+		ResponseBody: []byte(`{"id":100}`),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	winner.RequestHash, _ = requestHash(newCreateOrderRequest(2))
+	lookup.winner = winner
+
+	resp, err := svc.CreateOrderIdempotent(ctx, newCreateOrderRequest(2), "key-1")
+	require.NoError(t, err)
+	assert.True(t, resp.Replayed)
+	assert.JSONEq(t, `{"id":100}`, string(resp.Body))
+}
+
+// racingStorage отдаёт ключ winner только после первой проверки
+type racingStorage struct {
+	*fakeOrderStorage
+	winner  *domain.IdempotencyKey
+	lookups int
+}
+
+func (r *racingStorage) GetIdempotencyKey(ctx context.Context, userID int64, key string, now time.Time) (*domain.IdempotencyKey, error) {
+	r.lookups++
+	if r.lookups == 1 {
+		return r.fakeOrderStorage.GetIdempotencyKey(ctx, userID, key, now)
+	}
+	return r.winner, nil
+}
+
+func (r *racingStorage) SaveIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error {
+	r.fakeOrderStorage.idempotencyKeys = map[string]*domain.IdempotencyKey{"42/key-1": r.winner}
+	return r.fakeOrderStorage.SaveIdempotencyKey(ctx, record)
+}
+
+func TestCreateOrderIdempotent_InvalidKey(t *testing.T) {
+	svc := newTestService(&fakeOrderStorage{})
+
+	_, err := svc.CreateOrderIdempotent(context.Background(), newCreateOrderRequest(1), string(make([]byte, 256)))
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+var ErrReservationNotFound = apperror.New(apperror.CodeConflict, "stock reservation not found or expired")
+
+// InventoryClient резервирование остатков под заказ. Резерв, который не подтверждён
+// за время жизни резерва, снимается и товар снова становится доступен.
+type InventoryClient interface {
+	// Reserve резервирует все позиции целиком или ни одной (ErrInsufficientStock).
+	// Повторный вызов для того же заказа ничего не меняет.
+	Reserve(ctx context.Context, orderID int64, items []dto.ReservationItem) error
+	// Confirm делает резерв бессрочным; ErrReservationNotFound, если он уже истёк
+	Confirm(ctx context.Context, orderID int64) error
+	// Release снимает резерв заказа; отсутствие резерва ошибкой не считается
+	Release(ctx context.Context, orderID int64) error
+}
+
+func reservationItems(items []*domain.OrderItem) []dto.ReservationItem {
+	reservation := make([]dto.ReservationItem, 0, len(items))
+	for _, item := range items {
+		reservation = append(reservation, dto.ReservationItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return reservation
+}
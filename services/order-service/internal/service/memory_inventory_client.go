@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+)
+
+// MemoryInventoryClient склад в памяти процесса для локального запуска и тестов.
+// Истёкшие резервы снимаются при следующем обращении к складу.
+type MemoryInventoryClient struct {
+	mu           sync.Mutex
+	stock        map[int64]int64 // остаток без учёта резервов
+	defaultStock int64
+	reserved     map[int64]int64 // зарезервировано по товарам
+	reservations map[int64]*reservation
+	ttl          time.Duration
+	now          func() time.Time
+
+	expired int64
+}
+
+// reservation резерв одного заказа; у подтверждённого резерва нет срока
+type reservation struct {
+	items     map[int64]int64
+	expiresAt time.Time
+	confirmed bool
+}
+
+func NewMemoryInventoryClient(cfg config.InventoryConfig) *MemoryInventoryClient {
+	stock := make(map[int64]int64, len(cfg.Stock))
+	for productID, count := range cfg.Stock {
+		stock[productID] = count
+	}
+
+	return &MemoryInventoryClient{
+		stock:        stock,
+		defaultStock: cfg.DefaultStock,
+		reserved:     make(map[int64]int64),
+		reservations: make(map[int64]*reservation),
+		ttl:          cfg.ReservationTTL,
+		now:          time.Now,
+	}
+}
+
+func (c *MemoryInventoryClient) Reserve(ctx context.Context, orderID int64, items []dto.ReservationItem) error {
+	const op = "service.MemoryInventoryClient.Reserve"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expire(now)
+
+	if _, ok := c.reservations[orderID]; ok {
+		return nil
+	}
+
+	requested := make(map[int64]int64, len(items))
+	for _, item := range items {
+		requested[item.ProductID] += item.Quantity
+	}
+
+	for productID, quantity := range requested {
+		if available := c.available(productID); available < quantity {
+			return fmt.Errorf("%s: %w", op, ErrInsufficientStock.WithFields(apperror.Field(
+				"items", fmt.Sprintf("product %d: requested %d, available %d", productID, quantity, available),
+			)))
+		}
+	}
+
+	for productID, quantity := range requested {
+		c.reserved[productID] += quantity
+	}
+	c.reservations[orderID] = &reservation{items: requested, expiresAt: now.Add(c.ttl)}
+
+	return nil
+}
+
+func (c *MemoryInventoryClient) Confirm(ctx context.Context, orderID int64) error {
+	const op = "service.MemoryInventoryClient.Confirm"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(c.now())
+
+	r, ok := c.reservations[orderID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, ErrReservationNotFound)
+	}
+	r.confirmed = true
+
+	return nil
+}
+
+func (c *MemoryInventoryClient) Release(ctx context.Context, orderID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(c.now())
+
+	if r, ok := c.reservations[orderID]; ok {
+		c.release(orderID, r)
+	}
+
+	return nil
+}
+
+// available остаток товара за вычетом резервов
+func (c *MemoryInventoryClient) available(productID int64) int64 {
+	stock, ok := c.stock[productID]
+	if !ok {
+		stock = c.defaultStock
+	}
+	return stock - c.reserved[productID]
+}
+
+func (c *MemoryInventoryClient) expire(now time.Time) {
+	for orderID, r := range c.reservations {
+		if !r.confirmed && !now.Before(r.expiresAt) {
+			c.release(orderID, r)
+			c.expired++
+		}
+	}
+}
+
+func (c *MemoryInventoryClient) release(orderID int64, r *reservation) {
+	for productID, quantity := range r.items {
+		c.reserved[productID] -= quantity
+	}
+	delete(c.reservations, orderID)
+}
+
+func (c *MemoryInventoryClient) Name() string {
+	return "inventory"
+}
+
+type InventoryStatus struct {
+	Reservations int   `json:"reservations"`
+	Expired      int64 `json:"expired"`
+}
+
+func (c *MemoryInventoryClient) Status() any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(c.now())
+
+	return InventoryStatus{
+		Reservations: len(c.reservations),
+		Expired:      c.expired,
+	}
+}
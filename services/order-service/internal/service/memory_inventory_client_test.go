@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInventory() *MemoryInventoryClient {
+	return NewMemoryInventoryClient(config.InventoryConfig{ReservationTTL: 15 * time.Minute, DefaultStock: 100})
+}
+
+func TestMemoryInventory_ReserveIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	inv := NewMemoryInventoryClient(config.InventoryConfig{
+		ReservationTTL: time.Minute,
+		Stock:          map[int64]int64{1: 5, 2: 1},
+	})
+
+	require.NoError(t, inv.Reserve(ctx, 10, []dto.ReservationItem{{ProductID: 1, Quantity: 3}}))
+
+	err := inv.Reserve(ctx, 11, []dto.ReservationItem{{ProductID: 2, Quantity: 1}, {ProductID: 1, Quantity: 3}})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Equal(t, int64(1), inv.available(2))
+
+	// позиции одного товара суммируются
+	err = inv.Reserve(ctx, 12, []dto.ReservationItem{{ProductID: 1, Quantity: 1}, {ProductID: 1, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	// повторный резерв того же заказа не списывает остаток второй раз
+	require.NoError(t, inv.Reserve(ctx, 10, []dto.ReservationItem{{ProductID: 1, Quantity: 3}}))
+	assert.Equal(t, int64(2), inv.available(1))
+}
+
+func TestMemoryInventory_UnconfirmedReservationExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	inv := NewMemoryInventoryClient(config.InventoryConfig{ReservationTTL: time.Minute, Stock: map[int64]int64{1: 2}})
+	inv.now = func() time.Time { return now }
+
+	require.NoError(t, inv.Reserve(ctx, 1, []dto.ReservationItem{{ProductID: 1, Quantity: 1}}))
+	require.NoError(t, inv.Reserve(ctx, 2, []dto.ReservationItem{{ProductID: 1, Quantity: 1}}))
+	require.NoError(t, inv.Confirm(ctx, 2))
+
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, inv.Confirm(ctx, 1), ErrReservationNotFound)
+	assert.Equal(t, int64(1), inv.available(1))
+	assert.Equal(t, InventoryStatus{Reservations: 1, Expired: 1}, inv.Status())
+
+	require.NoError(t, inv.Release(ctx, 2))
+	assert.Equal(t, int64(2), inv.available(1))
+}
+
+func TestCreateOrder_DoesNotOversell(t *testing.T) {
+	ctx := context.Background()
+	inv := NewMemoryInventoryClient(config.InventoryConfig{ReservationTTL: time.Minute, Stock: map[int64]int64{1: 3}})
+	svc := newTestServiceWithInventory(&fakeOrderStorage{}, inv)
+
+	_, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+
+	// снимок товара показывает 100 на складе, но 2 из 3 уже зарезервированы
+	_, err = svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+}
+
+func TestCreateOrder_ReleasesReservationWhenTransactionFails(t *testing.T) {
+	ctx := context.Background()
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(&failingCreateStorage{fakeOrderStorage: &fakeOrderStorage{}}, inv)
+
+	_, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.Error(t, err)
+
+	assert.Equal(t, int64(100), inv.available(1))
+	assert.Empty(t, inv.reservations)
+}
+
+func TestCancelOrder_ReleasesReservation(t *testing.T) {
+	ctx := context.Background()
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(&fakeOrderStorage{}, inv)
+
+	created, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(98), inv.available(1))
+	assert.True(t, inv.reservations[created.ID].confirmed)
+
+	_, err = svc.CancelOrder(ctx, created.ID, staff, &dto.CancelOrderRequest{Reason: "out of stock", Version: created.Version})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), inv.available(1))
+}
+
+func TestUpdateOrderStatus_ExpiredReservationBlocksProcessing(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	inv := newTestInventory()
+	inv.now = func() time.Time { return now }
+	storage := &fakeOrderStorage{
+		orders:   []*domain.Order{{ID: 1, UserID: 42, Status: domain.OrderStatusPending, Version: 1}},
+		payments: []*domain.Payment{{ID: 1, OrderID: 1, Status: domain.PaymentStatusAuthorized}},
+	}
+	svc := newTestServiceWithInventory(storage, inv)
+	require.NoError(t, inv.Reserve(ctx, 1, []dto.ReservationItem{{ProductID: 1, Quantity: 2}}))
+
+	now = now.Add(16 * time.Minute)
+	_, err := svc.UpdateOrderStatus(ctx, 1, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusProcessing, Version: 1})
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+}
+
+// failingCreateStorage не может записать заказ: транзакция записи откатывается
+type failingCreateStorage struct {
+	*fakeOrderStorage
+}
+
+func (f *failingCreateStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
+	return errors.New("connection reset")
+}
+
+// failingReleaseInventory не может снять резерв
+type failingReleaseInventory struct {
+	*MemoryInventoryClient
+}
+
+func (f failingReleaseInventory) Release(ctx context.Context, orderID int64) error {
+	return errors.New("inventory unavailable")
+}
+
+func TestCreateOrder_UnsavedOrderReservationExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	inv := newTestInventory()
+	inv.now = func() time.Time { return now }
+	svc := newTestServiceWithInventory(&failingCreateStorage{fakeOrderStorage: &fakeOrderStorage{}}, failingReleaseInventory{inv})
+
+	// заказ не записан, и снять резерв компенсацией не удалось
+	_, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.Error(t, err)
+	require.Len(t, inv.reservations, 1)
+	for _, r := range inv.reservations {
+		assert.False(t, r.confirmed, "reservation of an unsaved order must not be confirmed")
+	}
+
+	now = now.Add(16 * time.Minute)
+	assert.Equal(t, InventoryStatus{Expired: 1}, inv.Status())
+	assert.Equal(t, int64(100), inv.available(1))
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// orderCursor непрозрачный для клиента курсор пагинации.
+// Сортировка зашита в курсор, чтобы его нельзя было применить к другой выборке.
+type orderCursor struct {
+	SortBy     string    `json:"s"`
+	Desc       bool      `json:"d"`
+	CreatedAt  time.Time `json:"c,omitempty"`
+	TotalPrice int64     `json:"t,omitempty"`
+	ID         int64     `json:"id"`
+}
+
+func encodeOrderCursor(filter domain.OrderFilter, last *domain.Order) string {
+	cursor := orderCursor{
+		SortBy: filter.SortBy,
+		Desc:   filter.Desc,
+		ID:     last.ID,
+	}
+	switch filter.SortBy {
+	case domain.OrderSortByTotalPrice:
+		cursor.TotalPrice = last.TotalPrice.Amount
+	default:
+		cursor.CreatedAt = last.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOrderCursor(raw string, filter domain.OrderFilter) (*domain.OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidListRequest.WithFields(apperror.Field("cursor", "malformed cursor"))
+	}
+
+	var cursor orderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidListRequest.WithFields(apperror.Field("cursor", "malformed cursor"))
+	}
+
+	if cursor.SortBy != filter.SortBy || cursor.Desc != filter.Desc {
+		return nil, ErrInvalidListRequest.WithFields(apperror.Field("cursor", "cursor does not match sort order"))
+	}
+
+	return &domain.OrderCursor{
+		CreatedAt:  cursor.CreatedAt,
+		TotalPrice: money.New(cursor.TotalPrice, money.DefaultCurrency),
+		ID:         cursor.ID,
+	}, nil
+}
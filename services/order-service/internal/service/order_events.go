@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/mapper"
+)
+
+func newOrderCreatedMessage(order *domain.Order) (*domain.OutboxMessage, error) {
+	event := dto.OrderCreatedEvent{
+		OrderID:         order.ID,
+		UserID:          order.UserID,
+		Status:          order.Status,
+		PaymentMethod:   order.PaymentMethod,
+		TotalPrice:      order.TotalPrice,
+		Currency:        order.TotalPrice.Currency,
+		Items:           make([]dto.OrderEventItem, 0, len(order.Items)),
+		CreatedAt:       order.CreatedAt,
+		ShippingAddress: mapper.MapToDTOAddressFromAddress(order.ShippingAddress),
+	}
+	for _, item := range order.Items {
+		event.Items = append(event.Items, dto.OrderEventItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+
+	return newOrderOutboxMessage(order.ID, domain.EventOrderCreated, event, order.CreatedAt)
+}
+
+// newOrderStatusMessage отмена публикуется как OrderCancelled, остальные переходы — как OrderStatusChanged
+func newOrderStatusMessage(order *domain.Order, history *domain.OrderStatusHistory) (*domain.OutboxMessage, error) {
+	if history.ToStatus == domain.OrderStatusCancelled {
+		return newOrderOutboxMessage(order.ID, domain.EventOrderCancelled, dto.OrderCancelledEvent{
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			FromStatus:  history.FromStatus,
+			Reason:      history.Comment,
+			CancelledBy: history.ChangedBy,
+			CancelledAt: history.ChangedAt,
+		}, history.ChangedAt)
+	}
+
+	return newOrderOutboxMessage(order.ID, domain.EventOrderStatusChanged, dto.OrderStatusChangedEvent{
+		OrderID:    order.ID,
+		UserID:     order.UserID,
+		FromStatus: history.FromStatus,
+		ToStatus:   history.ToStatus,
+		ChangedBy:  history.ChangedBy,
+		Comment:    history.Comment,
+		ChangedAt:  history.ChangedAt,
+	}, history.ChangedAt)
+}
+
+func newOrderOutboxMessage(orderID int64, eventType string, event any, at time.Time) (*domain.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	return &domain.OutboxMessage{
+		AggregateType: domain.AggregateTypeOrder,
+		AggregateID:   orderID,
+		EventType:     eventType,
+		Payload:       payload,
+		CreatedAt:     at,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateOrder_WritesOrderCreatedEvent(t *testing.T) {
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	resp, err := svc.CreateOrder(context.Background(), newCreateOrderRequest(2))
+	require.NoError(t, err)
+
+	// вслед за созданием заказ с авторизованной оплатой переходит в processing
+	require.Len(t, storage.outbox, 2)
+	msg := storage.outbox[0]
+	assert.Equal(t, domain.EventOrderCreated, msg.EventType)
+	assert.Equal(t, resp.ID, msg.AggregateID)
+
+	var event dto.OrderCreatedEvent
+	require.NoError(t, json.Unmarshal(msg.Payload, &event))
+	assert.Equal(t, resp.ID, event.OrderID)
+	assert.Equal(t, int64(42), event.UserID)
+	require.Len(t, event.Items, 1)
+	assert.Equal(t, int64(2), event.Items[0].Quantity)
+}
+
+func TestChangeOrderStatus_WritesStatusEvents(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{
+		orders:   []*domain.Order{{ID: 1, UserID: 42, Status: domain.OrderStatusPending, Version: 1}},
+		payments: []*domain.Payment{{ID: 1, OrderID: 1, Status: domain.PaymentStatusAuthorized}},
+	}
+	svc := newTestService(storage)
+	require.NoError(t, svc.inventory.Reserve(ctx, 1, []dto.ReservationItem{{ProductID: 1, Quantity: 1}}))
+
+	_, err := svc.UpdateOrderStatus(ctx, 1, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusProcessing, Version: 1})
+	require.NoError(t, err)
+
+	_, err = svc.CancelOrder(ctx, 1, domain.Actor{UserID: 42, Role: domain.RoleUser}, &dto.CancelOrderRequest{Reason: "changed my mind", Version: 2})
+	require.NoError(t, err)
+
+	require.Len(t, storage.outbox, 2)
+	assert.Equal(t, domain.EventOrderStatusChanged, storage.outbox[0].EventType)
+	assert.Equal(t, domain.EventOrderCancelled, storage.outbox[1].EventType)
+
+	var cancelled dto.OrderCancelledEvent
+	require.NoError(t, json.Unmarshal(storage.outbox[1].Payload, &cancelled))
+	assert.Equal(t, domain.OrderStatusProcessing, cancelled.FromStatus)
+	assert.Equal(t, "changed my mind", cancelled.Reason)
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/defan6/market/services/order-service/internal/payments"
+)
+
+// PaymentRegistry провайдеры оплаты: по способу оплаты для новой оплаты
+// и по имени для операций с уже авторизованной
+type PaymentRegistry interface {
+	Provider(method string) (payments.PaymentProvider, error)
+	ProviderByName(name string) (payments.PaymentProvider, error)
+}
+
+// authorizePayment записывает попытку оплаты и блокирует сумму заказа у провайдера.
+// Отклонённая попытка остаётся в payments со статусом failed.
+func (s *defaultOrderService) authorizePayment(ctx context.Context, data *createOrderSagaData) error {
+	order := data.Order
+
+	provider, err := s.payments.Provider(order.PaymentMethod)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	payment := &domain.Payment{
+		OrderID:   order.ID,
+		Provider:  provider.Name(),
+		Method:    order.PaymentMethod,
+		Amount:    order.TotalPrice,
+		Refunded:  money.New(0, order.TotalPrice.Currency),
+		Status:    domain.PaymentStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.storage.CreatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	authorizationID, authErr := provider.Authorize(ctx, payments.AuthorizeRequest{
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Method:         order.PaymentMethod,
+		Amount:         order.TotalPrice,
+		IdempotencyKey: fmt.Sprintf("order-%d-attempt-%d", order.ID, payment.Attempt),
+	})
+
+	payment.UpdatedAt = s.now()
+	if authErr != nil {
+		reason := authErr.Error()
+		payment.Status = domain.PaymentStatusFailed
+		payment.LastError = &reason
+	} else {
+		payment.Status = domain.PaymentStatusAuthorized
+		payment.AuthorizationID = &authorizationID
+	}
+
+	if err := s.storage.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	if authErr != nil {
+		return fmt.Errorf("failed to authorize payment: %w", authErr)
+	}
+	return nil
+}
+
+// voidPaymentIfNotSaved компенсация авторизации: снимает блокировку, если заказ так и не был записан
+func (s *defaultOrderService) voidPaymentIfNotSaved(ctx context.Context, data *createOrderSagaData) error {
+	if data.Order == nil {
+		return nil
+	}
+
+	saved, err := s.orderSaved(ctx, data.Order.ID)
+	if err != nil || saved {
+		return err
+	}
+
+	return s.voidPayment(ctx, data.Order.ID)
+}
+
+// voidPayment снимает блокировку денег по последней попытке оплаты, если она авторизована
+func (s *defaultOrderService) voidPayment(ctx context.Context, orderID int64) error {
+	payment, err := s.storage.GetLatestPayment(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment.Status != domain.PaymentStatusAuthorized {
+		return nil
+	}
+
+	provider, err := s.payments.ProviderByName(payment.Provider)
+	if err != nil {
+		return err
+	}
+	if err := provider.Void(ctx, *payment.AuthorizationID); err != nil {
+		return fmt.Errorf("failed to void payment: %w", err)
+	}
+
+	payment.Status = domain.PaymentStatusVoided
+	payment.UpdatedAt = s.now()
+	if err := s.storage.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// requireAuthorizedPayment ErrOrderNotPaid, если у заказа нет одобренной оплаты
+func (s *defaultOrderService) requireAuthorizedPayment(ctx context.Context, orderID int64) (*domain.Payment, error) {
+	payment, err := s.storage.GetLatestPayment(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOrderNotPaid
+		}
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	if !payment.IsAuthorized() {
+		return nil, domain.ErrOrderNotPaid
+	}
+	return payment, nil
+}
+
+// capturePayment списывает заблокированную сумму; уже списанная оплата не трогается
+func (s *defaultOrderService) capturePayment(ctx context.Context, orderID int64) error {
+	payment, err := s.requireAuthorizedPayment(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if payment.Status == domain.PaymentStatusCaptured {
+		return nil
+	}
+
+	provider, err := s.payments.ProviderByName(payment.Provider)
+	if err != nil {
+		return err
+	}
+	if err := provider.Capture(ctx, *payment.AuthorizationID, payment.Amount); err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+
+	payment.Status = domain.PaymentStatusCaptured
+	payment.UpdatedAt = s.now()
+	if err := s.storage.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// recordRefund записывает в payments возврат amount по списанной оплате заказа; полностью
+// возвращённая оплата переходит в refunded. Сам возврат у провайдера делает refundAtProvider.
+func (s *defaultOrderService) recordRefund(ctx context.Context, orderID int64, amount money.Money) (*domain.Payment, error) {
+	payment, err := s.storage.GetLatestPayment(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrPaymentNotCaptured
+		}
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment.Status != domain.PaymentStatusCaptured {
+		return nil, domain.ErrPaymentNotCaptured
+	}
+
+	payment.Refunded = payment.Refunded.Add(amount)
+	if payment.Refunded.Cmp(payment.Amount) >= 0 {
+		payment.Status = domain.PaymentStatusRefunded
+	}
+	payment.UpdatedAt = s.now()
+	if err := s.storage.UpdatePayment(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+	return payment, nil
+}
+
+// refundAtProvider возвращает amount по оплате у провайдера. key одинаков для повторов одного
+// возврата: провайдер не вернёт деньги второй раз, даже если прошлая попытка не записалась в БД.
+func (s *defaultOrderService) refundAtProvider(ctx context.Context, payment *domain.Payment, amount money.Money, key string) error {
+	provider, err := s.payments.ProviderByName(payment.Provider)
+	if err != nil {
+		return err
+	}
+	err = provider.Refund(ctx, payments.RefundRequest{
+		AuthorizationID: *payment.AuthorizationID,
+		Amount:          amount,
+		IdempotencyKey:  key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund payment: %w", err)
+	}
+	return nil
+}
+
+// releasePayment освобождает деньги отменённого заказа: авторизованную оплату отменяет, а списанную
+// (отмена пришлась на отгрузку) возвращает целиком. Ключ возврата у отмены один, поэтому повторный
+// вызов деньги второй раз не вернёт.
+func (s *defaultOrderService) releasePayment(ctx context.Context, orderID int64) error {
+	payment, err := s.storage.GetLatestPayment(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment.Status != domain.PaymentStatusCaptured {
+		return s.voidPayment(ctx, orderID)
+	}
+
+	amount := payment.Amount.Sub(payment.Refunded)
+	if err := s.refundAtProvider(ctx, payment, amount, fmt.Sprintf("order-%d-cancel", orderID)); err != nil {
+		return err
+	}
+
+	payment.Refunded = payment.Amount
+	payment.Status = domain.PaymentStatusRefunded
+	payment.UpdatedAt = s.now()
+	if err := s.storage.UpdatePayment(ctx, payment); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// releaseOrder после отмены заказа снимает резерв и освобождает деньги. Ошибки только логируются:
+// неподтверждённый резерв истечёт сам, а отмену из-за них откатывать нельзя.
+func (s *defaultOrderService) releaseOrder(ctx context.Context, orderID int64) {
+	ctx = context.WithoutCancel(ctx)
+
+	s.releaseReservation(ctx, orderID)
+
+	if err := s.releasePayment(ctx, orderID); err != nil {
+		s.log.Error("failed to release payment",
+			slog.Int64("order_id", orderID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
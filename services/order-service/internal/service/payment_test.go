@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/defan6/market/services/order-service/internal/payments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPayments(declineAbove *money.Money) *payments.Registry {
+	registry, err := payments.NewRegistry(payments.NewFakeProvider("fake", []string{"card", "sbp"}, declineAbove))
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func TestCreateOrder_AuthorizesPaymentBeforeProcessing(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(storage, inv)
+
+	resp, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusProcessing, resp.Status)
+
+	require.Len(t, storage.payments, 1)
+	payment := storage.payments[0]
+	assert.Equal(t, domain.PaymentStatusAuthorized, payment.Status)
+	assert.Equal(t, resp.ID, payment.OrderID)
+	assert.Equal(t, resp.TotalPrice, payment.Amount)
+	assert.True(t, inv.reservations[resp.ID].confirmed)
+
+	require.Len(t, storage.outbox, 2)
+	assert.Equal(t, domain.EventOrderCreated, storage.outbox[0].EventType)
+	assert.Equal(t, domain.EventOrderStatusChanged, storage.outbox[1].EventType)
+}
+
+func TestCreateOrder_DeclinedPaymentReleasesStock(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	inv := newTestInventory()
+	limit := money.MustParse("1.00", money.DefaultCurrency)
+	svc := newTestServiceWith(storage, inv, newTestPayments(&limit))
+
+	_, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	assert.ErrorIs(t, err, payments.ErrPaymentDeclined)
+
+	assert.Empty(t, storage.orders)
+	assert.Equal(t, int64(100), inv.available(1))
+	require.Len(t, storage.payments, 1)
+	assert.Equal(t, domain.PaymentStatusFailed, storage.payments[0].Status)
+	assert.NotNil(t, storage.payments[0].LastError)
+}
+
+func TestCreateOrder_RejectsUnsupportedPaymentMethod(t *testing.T) {
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	req := newCreateOrderRequest(1)
+	req.PaymentMethod = "cash"
+	_, err := svc.CreateOrder(context.Background(), req)
+	assert.ErrorIs(t, err, payments.ErrUnsupportedPaymentMethod)
+	assert.Empty(t, storage.sagas)
+}
+
+func TestUpdateOrderStatus_ProcessingRequiresAuthorizedPayment(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{orders: []*domain.Order{{ID: 1, UserID: 42, Status: domain.OrderStatusPending, Version: 1}}}
+	svc := newTestService(storage)
+	require.NoError(t, svc.inventory.Reserve(ctx, 1, []dto.ReservationItem{{ProductID: 1, Quantity: 1}}))
+
+	_, err := svc.UpdateOrderStatus(ctx, 1, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusProcessing, Version: 1})
+	assert.ErrorIs(t, err, domain.ErrOrderNotPaid)
+}
+
+func TestOrderLifecycle_CapturesOnShipmentAndVoidsOnCancel(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	shipped, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	_, err = svc.UpdateOrderStatus(ctx, shipped.ID, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusShipped, Version: shipped.Version})
+	require.NoError(t, err)
+
+	cancelled, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	_, err = svc.CancelOrder(ctx, cancelled.ID, staff, &dto.CancelOrderRequest{Reason: "changed my mind", Version: cancelled.Version})
+	require.NoError(t, err)
+
+	payment, err := storage.GetLatestPayment(ctx, shipped.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+
+	payment, err = storage.GetLatestPayment(ctx, cancelled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusVoided, payment.Status)
+}
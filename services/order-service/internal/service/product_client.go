@@ -2,13 +2,14 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
 )
 
-var ErrProductNotFound = errors.New("product not found")
+var ErrProductNotFound = apperror.New(apperror.CodeUnprocessable, "product not found")
 
 // ProductClient интерфейс для взаимодействия с product-service
 type ProductClient interface {
@@ -33,10 +34,12 @@ func (c *StubProductClient) GetProductsByIDs(ctx context.Context, ids []int64) (
 		product := &dto.ExternalProduct{
 			ID:           id,
 			Name:         fmt.Sprintf("Product-%d", id),
-			Price:        100.0 * float64(id), // Фейковая цена для тестирования
-			CountInStock: 100,                 // Фейковое наличие
+			Price:        money.New(10000*id, money.DefaultCurrency), // Фейковая цена для тестирования
+			CountInStock: 100,                                        // Фейковое наличие
 			Image:        "/images/product.png",
 			Description:  "Stub product description",
+			Category:     "general",
+			WeightGrams:  500,
 		}
 		products = append(products, product)
 	}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+)
+
+// checkPromoCode находит код и проверяет, что он применим к заказу пользователя userID из позиций items.
+// forUpdate — в транзакции записи заказа: строка кода блокируется, поэтому лимиты погашений
+// соблюдаются и при параллельных заказах.
+func (s *defaultOrderService) checkPromoCode(
+	ctx context.Context,
+	code string,
+	userID int64,
+	items []*domain.OrderItem,
+	forUpdate bool,
+) (*domain.PromoCode, error) {
+	get := s.storage.GetPromoCode
+	if forUpdate {
+		get = s.storage.GetPromoCodeForUpdate
+	}
+
+	promo, err := get(ctx, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrPromoCodeNotFound.WithFields(apperror.Field("promo_code", fmt.Sprintf("unknown code %q", code)))
+		}
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	itemsPrice := money.Zero()
+	productIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		itemsPrice = itemsPrice.Add(item.Price.Mul(item.Quantity))
+		productIDs = append(productIDs, item.ProductID)
+	}
+	if err := promo.CheckOrder(s.now(), itemsPrice, productIDs); err != nil {
+		return nil, err
+	}
+
+	uses, err := s.storage.CountPromoRedemptions(ctx, promo.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count promo code redemptions: %w", err)
+	}
+	if err := promo.CheckUsage(uses); err != nil {
+		return nil, err
+	}
+
+	return promo, nil
+}
+
+// lockPromoCode в транзакции записи заказа блокирует код заказа и повторно проверяет его лимиты:
+// они могли исчерпаться, пока шли резерв и оплата. nil — заказ без промокода.
+func (s *defaultOrderService) lockPromoCode(ctx context.Context, order *domain.Order) (*domain.PromoCode, error) {
+	if order.PromoCode == nil {
+		return nil, nil
+	}
+	return s.checkPromoCode(ctx, *order.PromoCode, order.UserID, order.Items, true)
+}
+
+// redeemPromoCode записывает погашение кода, заблокированного lockPromoCode, уже записанным заказом
+func (s *defaultOrderService) redeemPromoCode(ctx context.Context, promo *domain.PromoCode, order *domain.Order) error {
+	if promo == nil {
+		return nil
+	}
+
+	err := s.storage.RedeemPromoCode(ctx, &domain.PromoRedemption{
+		PromoCodeID: promo.ID,
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		Discount:    order.DiscountPrice,
+		CreatedAt:   order.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to redeem promo code: %w", err)
+	}
+	return nil
+}
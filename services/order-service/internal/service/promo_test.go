@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rub(s string) money.Money {
+	return money.MustParse(s, money.DefaultCurrency)
+}
+
+func limit(n int64) *int64 {
+	return &n
+}
+
+func TestCreateOrder_AppliesPromoCode(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{promoCodes: []*domain.PromoCode{
+		{ID: 1, Code: "SALE10", Kind: domain.PromoKindPercent, Rate: money.Percent(10)},
+	}}
+	svc := newTestService(storage)
+
+	req := newCreateOrderRequest(2)
+	req.PromoCode = "SALE10"
+	resp, err := svc.CreateOrder(ctx, req)
+	require.NoError(t, err)
+
+	require.NotNil(t, resp.PromoCode)
+	assert.Equal(t, "SALE10", *resp.PromoCode)
+	assert.Equal(t, rub("20.00"), resp.DiscountPrice)
+	assert.Equal(t, rub("180.00"), resp.TotalPrice)
+	assert.Equal(t, rub("180.00"), storage.payments[0].Amount)
+
+	require.Len(t, storage.redemptions, 1)
+	assert.Equal(t, resp.ID, storage.redemptions[0].OrderID)
+	assert.Equal(t, rub("20.00"), storage.redemptions[0].Discount)
+	assert.Equal(t, int64(1), storage.promoCodes[0].Uses)
+}
+
+func TestCreateOrder_PromoCodeUsageLimits(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{promoCodes: []*domain.PromoCode{
+		{ID: 1, Code: "ONCE", Kind: domain.PromoKindFixed, Amount: rub("50.00"), MaxUses: limit(2), MaxUsesPerUser: limit(1)},
+	}}
+	svc := newTestService(storage)
+
+	order := func(userID int64) error {
+		req := newCreateOrderRequest(1)
+		req.UserID = userID
+		req.PromoCode = "ONCE"
+		_, err := svc.CreateOrder(ctx, req)
+		return err
+	}
+
+	require.NoError(t, order(42))
+	assert.ErrorIs(t, order(42), domain.ErrPromoCodeExhausted)
+	require.NoError(t, order(43))
+	assert.ErrorIs(t, order(44), domain.ErrPromoCodeExhausted)
+	assert.Len(t, storage.redemptions, 2)
+}
+
+// staleReadStorage читает код без блокировки так, будто параллельный заказ ещё не погасил его
+type staleReadStorage struct {
+	*fakeOrderStorage
+}
+
+func (s *staleReadStorage) GetPromoCode(ctx context.Context, code string) (*domain.PromoCode, error) {
+	promo, err := s.fakeOrderStorage.GetPromoCode(ctx, code)
+	if err == nil {
+		promo.Uses = 0
+	}
+	return promo, err
+}
+
+func TestCreateOrder_PromoCodeExhaustedAtSaveRollsBack(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{promoCodes: []*domain.PromoCode{
+		{ID: 1, Code: "LAST", Kind: domain.PromoKindPercent, Rate: money.Percent(5), MaxUses: limit(1), Uses: 1},
+	}}
+	inv := newTestInventory()
+	svc := newTestServiceWithInventory(&staleReadStorage{fakeOrderStorage: storage}, inv)
+
+	req := newCreateOrderRequest(2)
+	req.PromoCode = "LAST"
+	_, err := svc.CreateOrder(ctx, req)
+	assert.ErrorIs(t, err, domain.ErrPromoCodeExhausted)
+
+	// проверка под блокировкой сработала после авторизации оплаты: сага её сняла
+	require.Len(t, storage.payments, 1)
+	assert.Equal(t, domain.PaymentStatusVoided, storage.payments[0].Status)
+	assert.Equal(t, int64(100), inv.available(1))
+	assert.Empty(t, storage.redemptions)
+}
+
+func TestCreateOrder_PromoCodeRejected(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	minTotal := rub("1000.00")
+
+	storage := &fakeOrderStorage{promoCodes: []*domain.PromoCode{
+		{ID: 1, Code: "EXPIRED", Kind: domain.PromoKindPercent, Rate: money.Percent(10), EndsAt: &past},
+		{ID: 2, Code: "BIG", Kind: domain.PromoKindPercent, Rate: money.Percent(10), MinOrderTotal: &minTotal},
+		{ID: 3, Code: "OTHER", Kind: domain.PromoKindPercent, Rate: money.Percent(10), ProductIDs: []int64{5}},
+	}}
+	svc := newTestService(storage)
+
+	tests := []struct {
+		code    string
+		wantErr error
+	}{
+		{code: "NOPE", wantErr: domain.ErrPromoCodeNotFound},
+		{code: "EXPIRED", wantErr: domain.ErrPromoCodeInactive},
+		{code: "BIG", wantErr: domain.ErrPromoCodeNotApplicable},
+		{code: "OTHER", wantErr: domain.ErrPromoCodeNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			req := newCreateOrderRequest(1)
+			req.PromoCode = tt.code
+			_, err := svc.CreateOrder(context.Background(), req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	assert.Empty(t, storage.orders)
+}
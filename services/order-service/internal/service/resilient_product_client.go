@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/resilience"
+)
+
+// ResilientProductClient декоратор ProductClient: повторы временных ошибок,
+// предохранитель и ограничение числа одновременных запросов к product-service
+type ResilientProductClient struct {
+	next   ProductClient
+	policy *resilience.Policy
+}
+
+func NewResilientProductClient(log *slog.Logger, next ProductClient, cfg config.ResilienceConfig) *ResilientProductClient {
+	policy := resilience.NewPolicy("product-service", log, resilience.Options{
+		MaxAttempts:      cfg.MaxAttempts,
+		BaseDelay:        cfg.BaseDelay,
+		MaxDelay:         cfg.MaxDelay,
+		FailureThreshold: cfg.FailureThreshold,
+		OpenTimeout:      cfg.OpenTimeout,
+		HalfOpenProbes:   cfg.HalfOpenProbes,
+		MaxConcurrent:    cfg.MaxConcurrent,
+		WaitTimeout:      cfg.WaitTimeout,
+	}, func(err error) bool {
+		return errors.Is(err, ErrProductServiceUnavailable)
+	})
+
+	return &ResilientProductClient{
+		next:   next,
+		policy: policy,
+	}
+}
+
+// GetProductsByIDs чтение идемпотентно, поэтому его можно безопасно повторять
+func (c *ResilientProductClient) GetProductsByIDs(ctx context.Context, ids []int64) ([]*dto.ExternalProduct, error) {
+	var products []*dto.ExternalProduct
+
+	err := c.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		products, err = c.next.GetProductsByIDs(ctx, ids)
+		return err
+	})
+	if errors.Is(err, resilience.ErrCircuitOpen) || errors.Is(err, resilience.ErrBulkheadFull) {
+		return nil, errors.Join(ErrProductServiceUnavailable, err)
+	}
+
+	return products, err
+}
+
+// Policy для регистрации в эндпоинте статуса
+func (c *ResilientProductClient) Policy() *resilience.Policy {
+	return c.policy
+}
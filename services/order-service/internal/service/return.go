@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/mapper"
+	"github.com/defan6/market/services/order-service/internal/pricing"
+)
+
+var ErrReturnNotFound = apperror.New(apperror.CodeNotFound, "return not found")
+
+// CreateReturn оформляет возврат позиций доставленного заказа. Вернуть можно не больше, чем заказано,
+// за вычетом уже оформленных возвратов; суммы к возврату считает pricing.Refund.
+func (s *defaultOrderService) CreateReturn(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateReturnRequest) (*dto.ReturnResponse, error) {
+	const op = "service.CreateReturn"
+
+	var created *domain.Return
+
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// блокировка заказа выстраивает в очередь параллельные возвраты одного заказа
+		order, err := s.storage.GetOrderForUpdate(ctx, orderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		if !actor.CanAccessOrder(order) {
+			return domain.ErrOrderAccessDenied
+		}
+		if order.Status != domain.OrderStatusDelivered {
+			return domain.ErrOrderNotReturnable.WithFields(
+				apperror.Field("status", fmt.Sprintf("order is %s", order.Status)),
+			)
+		}
+
+		previous, err := s.storage.GetOrderReturns(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order returns: %w", err)
+		}
+
+		items, err := buildReturnItems(order, request, domain.ReturnedQuantities(previous))
+		if err != nil {
+			return err
+		}
+
+		// налог возврата делится по строкам налога правил позиций
+		order.PriceLines, err = s.storage.GetOrderPriceLines(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order price lines: %w", err)
+		}
+
+		refund := pricing.Refund(order, items, previous)
+		now := s.dbNow()
+		created = &domain.Return{
+			OrderID:        orderID,
+			Status:         domain.ReturnStatusRequested,
+			Reason:         request.Reason,
+			RequestedBy:    actor.UserID,
+			ItemsAmount:    refund.ItemsPrice,
+			TaxAmount:      refund.TaxPrice,
+			ShippingAmount: refund.ShippingPrice,
+			TotalAmount:    refund.TotalPrice,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Items:          items,
+		}
+
+		if err := s.storage.CreateReturn(ctx, created); err != nil {
+			return fmt.Errorf("failed to create return: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("return requested",
+		slog.Int64("order_id", orderID),
+		slog.Int64("return_id", created.ID),
+		slog.String("total", created.TotalAmount.String()),
+	)
+
+	return mapper.MapToReturnResponseFromReturn(created), nil
+}
+
+// ListReturns возвраты заказа; доступ как к самому заказу
+func (s *defaultOrderService) ListReturns(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ReturnResponse, error) {
+	const op = "service.ListReturns"
+
+	order, err := s.storage.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get order: %w", op, err)
+	}
+
+	if !actor.CanAccessOrder(order) {
+		return nil, fmt.Errorf("%s: %w", op, domain.ErrOrderAccessDenied)
+	}
+
+	returns, err := s.storage.GetOrderReturns(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get order returns: %w", op, err)
+	}
+
+	response := make([]*dto.ReturnResponse, 0, len(returns))
+	for _, ret := range returns {
+		response = append(response, mapper.MapToReturnResponseFromReturn(ret))
+	}
+	return response, nil
+}
+
+// UpdateReturnStatus переводит возврат в следующий статус. При переходе в refunded
+// сумма возврата возвращается по списанной оплате заказа.
+func (s *defaultOrderService) UpdateReturnStatus(ctx context.Context, orderID, returnID int64, request *dto.UpdateReturnStatusRequest) (*dto.ReturnResponse, error) {
+	const op = "service.UpdateReturnStatus"
+
+	var updated *domain.Return
+
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		ret, err := s.storage.GetReturnForUpdate(ctx, orderID, returnID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrReturnNotFound
+			}
+			return fmt.Errorf("failed to get return: %w", err)
+		}
+
+		if err := ret.TransitionTo(request.Status, s.dbNow()); err != nil {
+			return err
+		}
+
+		if err := s.storage.UpdateReturnStatus(ctx, ret); err != nil {
+			return fmt.Errorf("failed to update return status: %w", err)
+		}
+
+		// деньги возвращаются последним действием транзакции: если провайдер откажет,
+		// статус возврата откатится вместе с ней. Ключ возврата не даёт провайдеру вернуть деньги
+		// дважды, если транзакция повторится или не зафиксируется и клиент повторит запрос.
+		if ret.Status == domain.ReturnStatusRefunded {
+			payment, err := s.recordRefund(ctx, orderID, ret.TotalAmount)
+			if err != nil {
+				return err
+			}
+			if err := s.refundAtProvider(ctx, payment, ret.TotalAmount, fmt.Sprintf("return-%d", ret.ID)); err != nil {
+				return err
+			}
+		}
+
+		updated = ret
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("return status changed",
+		slog.Int64("order_id", orderID),
+		slog.Int64("return_id", updated.ID),
+		slog.String("status", updated.Status),
+	)
+
+	return mapper.MapToReturnResponseFromReturn(updated), nil
+}
+
+// buildReturnItems проверяет запрос возврата по позициям заказа; returned — уже возвращённые
+// количества по ID позиции
+func buildReturnItems(order *domain.Order, request *dto.CreateReturnRequest, returned map[int64]int64) ([]*domain.ReturnItem, error) {
+	if len(request.Items) == 0 {
+		return nil, domain.ErrInvalidReturnItems.WithFields(apperror.Field("items", "must contain at least one item"))
+	}
+
+	ordered := make(map[int64]*domain.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		ordered[item.ID] = item
+	}
+
+	items := make([]*domain.ReturnItem, 0, len(request.Items))
+	seen := make(map[int64]struct{}, len(request.Items))
+
+	for i, req := range request.Items {
+		orderItem, ok := ordered[req.OrderItemID]
+		if !ok {
+			return nil, domain.ErrInvalidReturnItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].order_item_id", i), fmt.Sprintf("item %d is not in order %d", req.OrderItemID, order.ID),
+			))
+		}
+		if _, dup := seen[req.OrderItemID]; dup {
+			return nil, domain.ErrInvalidReturnItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].order_item_id", i), fmt.Sprintf("item %d is listed more than once", req.OrderItemID),
+			))
+		}
+		seen[req.OrderItemID] = struct{}{}
+
+		if req.Quantity <= 0 {
+			return nil, domain.ErrInvalidReturnItems.WithFields(apperror.Field(fmt.Sprintf("items[%d].quantity", i), "must be positive"))
+		}
+		if left := orderItem.Quantity - returned[req.OrderItemID]; req.Quantity > left {
+			return nil, domain.ErrInvalidReturnItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].quantity", i),
+				fmt.Sprintf("item %d: ordered %d, already returned %d, requested %d",
+					req.OrderItemID, orderItem.Quantity, returned[req.OrderItemID], req.Quantity),
+			))
+		}
+
+		items = append(items, &domain.ReturnItem{OrderItemID: req.OrderItemID, Quantity: req.Quantity})
+	}
+
+	return items, nil
+}
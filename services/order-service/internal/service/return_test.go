@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var customer = domain.Actor{UserID: 42, Role: domain.RoleUser}
+
+// deliverOrder создаёт заказ и доводит его до delivered; оплата при этом списывается
+func deliverOrder(t *testing.T, svc *defaultOrderService, quantity int64) *dto.OrderResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(quantity))
+	require.NoError(t, err)
+
+	for _, status := range []string{domain.OrderStatusShipped, domain.OrderStatusDelivered} {
+		order, err = svc.UpdateOrderStatus(ctx, order.ID, &dto.UpdateOrderStatusRequest{Status: status, Version: order.Version})
+		require.NoError(t, err)
+	}
+	return order
+}
+
+func advanceReturn(t *testing.T, svc *defaultOrderService, orderID, returnID int64, statuses ...string) {
+	t.Helper()
+
+	for _, status := range statuses {
+		_, err := svc.UpdateReturnStatus(context.Background(), orderID, returnID, &dto.UpdateReturnStatusRequest{Status: status})
+		require.NoError(t, err)
+	}
+}
+
+func TestReturns_PartialReturnsRefundCapturedPayment(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+	order := deliverOrder(t, svc, 2)
+	itemID := order.Items[0].ID
+
+	first, err := svc.CreateReturn(ctx, order.ID, customer, &dto.CreateReturnRequest{
+		Reason: "damaged",
+		Items:  []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReturnStatusRequested, first.Status)
+	assert.Equal(t, money.MustParse("100.00", money.DefaultCurrency), first.TotalAmount)
+
+	advanceReturn(t, svc, order.ID, first.ID, domain.ReturnStatusApproved, domain.ReturnStatusReceived, domain.ReturnStatusRefunded)
+
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+	assert.Equal(t, first.TotalAmount, payment.Refunded)
+
+	second, err := svc.CreateReturn(ctx, order.ID, customer, &dto.CreateReturnRequest{
+		Items: []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	advanceReturn(t, svc, order.ID, second.ID, domain.ReturnStatusApproved, domain.ReturnStatusReceived, domain.ReturnStatusRefunded)
+
+	payment, err = storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusRefunded, payment.Status)
+	assert.Equal(t, order.TotalPrice, payment.Refunded)
+
+	returns, err := svc.ListReturns(ctx, order.ID, customer)
+	require.NoError(t, err)
+	require.Len(t, returns, 2)
+	assert.Equal(t, domain.ReturnStatusRefunded, returns[0].Status)
+}
+
+func TestUpdateReturnStatus_RetryAfterFailedCommitRefundsOnce(t *testing.T) {
+	ctx := context.Background()
+	storage := &conflictStorage{fakeOrderStorage: &fakeOrderStorage{}}
+	svc, _, _ := newRetryTestService(storage)
+	order := deliverOrder(t, svc, 2)
+	itemID := order.Items[0].ID
+
+	newReturn := func() *dto.ReturnResponse {
+		ret, err := svc.CreateReturn(ctx, order.ID, customer, &dto.CreateReturnRequest{
+			Items: []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		advanceReturn(t, svc, order.ID, ret.ID, domain.ReturnStatusApproved, domain.ReturnStatusReceived)
+		return ret
+	}
+	first := newReturn()
+
+	// провайдер вернул деньги, но транзакция не зафиксировалась: клиент повторяет запрос
+	txManager := svc.txManager
+	svc.txManager = &failCommitTxManager{storage: storage.fakeOrderStorage}
+	_, err := svc.UpdateReturnStatus(ctx, order.ID, first.ID, &dto.UpdateReturnStatusRequest{Status: domain.ReturnStatusRefunded})
+	require.Error(t, err)
+	_, err = svc.UpdateReturnStatus(ctx, order.ID, first.ID, &dto.UpdateReturnStatusRequest{Status: domain.ReturnStatusRefunded})
+	require.NoError(t, err)
+	svc.txManager = txManager
+
+	// повтор не вернул деньги второй раз: у провайдера осталось на второй возврат
+	second := newReturn()
+	advanceReturn(t, svc, order.ID, second.ID, domain.ReturnStatusRefunded)
+
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusRefunded, payment.Status)
+	assert.Equal(t, order.TotalPrice, payment.Refunded)
+}
+
+func TestCreateReturn_Rejected(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(&fakeOrderStorage{})
+	delivered := deliverOrder(t, svc, 2)
+	itemID := delivered.Items[0].ID
+
+	processing, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+
+	_, err = svc.CreateReturn(ctx, delivered.ID, customer, &dto.CreateReturnRequest{
+		Items: []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		orderID int64
+		actor   domain.Actor
+		items   []*dto.ReturnItemRequest
+		wantErr error
+	}{
+		{
+			name: "not delivered", orderID: processing.ID, actor: customer,
+			items:   []*dto.ReturnItemRequest{{OrderItemID: processing.Items[0].ID, Quantity: 1}},
+			wantErr: domain.ErrOrderNotReturnable,
+		},
+		{
+			name: "someone else's order", orderID: delivered.ID, actor: domain.Actor{UserID: 7, Role: domain.RoleUser},
+			items:   []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 1}},
+			wantErr: domain.ErrOrderAccessDenied,
+		},
+		{
+			name: "already returned", orderID: delivered.ID, actor: customer,
+			items:   []*dto.ReturnItemRequest{{OrderItemID: itemID, Quantity: 1}},
+			wantErr: domain.ErrInvalidReturnItems,
+		},
+		{
+			name: "unknown item", orderID: delivered.ID, actor: customer,
+			items:   []*dto.ReturnItemRequest{{OrderItemID: 99, Quantity: 1}},
+			wantErr: domain.ErrInvalidReturnItems,
+		},
+		{
+			name: "no items", orderID: delivered.ID, actor: customer,
+			wantErr: domain.ErrInvalidReturnItems,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateReturn(ctx, tt.orderID, tt.actor, &dto.CreateReturnRequest{Items: tt.items})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUpdateReturnStatus_CannotSkipSteps(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+	order := deliverOrder(t, svc, 1)
+
+	ret, err := svc.CreateReturn(ctx, order.ID, customer, &dto.CreateReturnRequest{
+		Items: []*dto.ReturnItemRequest{{OrderItemID: order.Items[0].ID, Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.UpdateReturnStatus(ctx, order.ID, ret.ID, &dto.UpdateReturnStatusRequest{Status: domain.ReturnStatusRefunded})
+	assert.ErrorIs(t, err, domain.ErrInvalidReturnTransition)
+
+	_, err = svc.UpdateReturnStatus(ctx, order.ID+1, ret.ID, &dto.UpdateReturnStatusRequest{Status: domain.ReturnStatusApproved})
+	assert.ErrorIs(t, err, ErrReturnNotFound)
+
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.True(t, payment.Refunded.IsZero())
+}
@@ -2,121 +2,444 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/defan6/market/services/order-service/internal/config"
 	"github.com/defan6/market/services/order-service/internal/domain"
 	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
 	"github.com/defan6/market/services/order-service/internal/mapper"
-	"github.com/defan6/market/services/order-service/internal/storage"
+	"github.com/defan6/market/services/order-service/internal/pricing"
+	"github.com/defan6/market/services/order-service/internal/saga"
 )
 
 var (
-	ErrOrderNotFound      = errors.New("order not found")
-	ErrInvalidQuantity    = errors.New("invalid quantity")
-	ErrInvalidProductID   = errors.New("invalid product id")
-	ErrEmptyOrderItems    = errors.New("order items is empty")
-	ErrPaymentMethodEmpty = errors.New("payment method is required")
+	ErrOrderNotFound      = apperror.New(apperror.CodeNotFound, "order not found")
+	ErrInvalidQuantity    = apperror.New(apperror.CodeInvalidArgument, "invalid quantity")
+	ErrInvalidProductID   = apperror.New(apperror.CodeInvalidArgument, "invalid product id")
+	ErrEmptyOrderItems    = apperror.New(apperror.CodeInvalidArgument, "order items is empty")
+	ErrPaymentMethodEmpty = apperror.New(apperror.CodeInvalidArgument, "payment method is required")
+	ErrInvalidListRequest = apperror.New(apperror.CodeInvalidArgument, "invalid list request")
+	ErrInsufficientStock  = apperror.New(apperror.CodeConflict, "not enough stock")
+)
+
+// TxManager выполняет fn в одной транзакции БД
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
 )
 
 type defaultOrderService struct {
 	log           *slog.Logger
 	storage       OrderStorage
-	txManager     *storage.TxManager
+	txManager     TxManager
 	productClient ProductClient
+	inventory     InventoryClient
+	payments      PaymentRegistry
+	carriers      CarrierRegistry
+	pricing       PricingEngine
+	idempotency   config.IdempotencyConfig
+	now           func() time.Time
+
+	createOrderSaga *saga.Orchestrator[createOrderSagaData]
+}
+
+type PricingEngine interface {
+	Calculate(ctx context.Context, quote pricing.Quote) (*pricing.Breakdown, error)
 }
 
 type OrderStorage interface {
+	saga.Store
+
+	NextOrderID(ctx context.Context) (int64, error)
 	CreateOrder(ctx context.Context, order *domain.Order) error
 	CreateOrderItems(ctx context.Context, items []*domain.OrderItem) error
+	CreateOrderPriceLines(ctx context.Context, lines []*domain.OrderPriceLine) error
+	GetOrderPriceLines(ctx context.Context, orderID int64) ([]*domain.OrderPriceLine, error)
 	GetOrder(ctx context.Context, id int64) (*domain.Order, error)
+	GetOrdersWithItems(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error)
+	GetOrderForUpdate(ctx context.Context, id int64) (*domain.Order, error)
+	UpdateOrderStatus(ctx context.Context, order *domain.Order) error
+	CreateOrderStatusHistory(ctx context.Context, history *domain.OrderStatusHistory) error
+	GetIdempotencyKey(ctx context.Context, userID int64, key string, now time.Time) (*domain.IdempotencyKey, error)
+	SaveIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error
+	CreateOutboxMessage(ctx context.Context, msg *domain.OutboxMessage) error
+	CreatePayment(ctx context.Context, payment *domain.Payment) error
+	UpdatePayment(ctx context.Context, payment *domain.Payment) error
+	GetLatestPayment(ctx context.Context, orderID int64) (*domain.Payment, error)
+	CreateReturn(ctx context.Context, ret *domain.Return) error
+	GetOrderReturns(ctx context.Context, orderID int64) ([]*domain.Return, error)
+	GetReturnForUpdate(ctx context.Context, orderID, returnID int64) (*domain.Return, error)
+	UpdateReturnStatus(ctx context.Context, ret *domain.Return) error
+	GetPromoCode(ctx context.Context, code string) (*domain.PromoCode, error)
+	GetPromoCodeForUpdate(ctx context.Context, code string) (*domain.PromoCode, error)
+	CountPromoRedemptions(ctx context.Context, promoCodeID, userID int64) (int64, error)
+	RedeemPromoCode(ctx context.Context, redemption *domain.PromoRedemption) error
+	GetCartItems(ctx context.Context, userID int64) ([]*domain.CartItem, error)
+	SaveCartItem(ctx context.Context, item *domain.CartItem) error
+	DeleteCartItem(ctx context.Context, userID, productID int64) error
+	ClearCart(ctx context.Context, userID int64, upTo time.Time) error
+	CreateAddress(ctx context.Context, address *domain.SavedAddress) error
+	GetUserAddresses(ctx context.Context, userID int64) ([]*domain.SavedAddress, error)
+	GetAddress(ctx context.Context, userID, id int64) (*domain.SavedAddress, error)
+	UpdateAddress(ctx context.Context, address *domain.SavedAddress) error
+	DeleteAddress(ctx context.Context, userID, id int64) error
+	CreateShipment(ctx context.Context, shipment *domain.Shipment) error
+	GetOrderShipments(ctx context.Context, orderID int64) ([]*domain.Shipment, error)
+	ListActiveShipments(ctx context.Context, limit int) ([]*domain.Shipment, error)
+	UpdateShipmentTracking(ctx context.Context, shipment *domain.Shipment) error
+	ListPendingShipments(ctx context.Context, createdBefore time.Time, limit int) ([]*domain.Shipment, error)
+	UpdateShipmentLabel(ctx context.Context, shipment *domain.Shipment) error
+	DeleteShipment(ctx context.Context, id int64) error
 }
 
 func NewDefaultOrderService(
 	log *slog.Logger,
 	storage OrderStorage,
-	txManager *storage.TxManager,
+	txManager TxManager,
 	productClient ProductClient,
+	inventory InventoryClient,
+	payments PaymentRegistry,
+	carriers CarrierRegistry,
+	pricing PricingEngine,
+	idempotency config.IdempotencyConfig,
 ) *defaultOrderService {
-	return &defaultOrderService{
+	s := &defaultOrderService{
 		log:           log,
 		storage:       storage,
 		txManager:     txManager,
 		productClient: productClient,
+		inventory:     inventory,
+		payments:      payments,
+		carriers:      carriers,
+		pricing:       pricing,
+		idempotency:   idempotency,
+		now:           time.Now,
 	}
+	s.createOrderSaga = s.newCreateOrderSaga(storage)
+
+	return s
 }
 
 func (s *defaultOrderService) CreateOrder(ctx context.Context, request *dto.CreateOrderRequest) (*dto.OrderResponse, error) {
 	const op = "service.CreateOrder"
 
-	// 1. Валидация запроса
+	return s.createOrder(ctx, request, op, nil)
+}
+
+// createOrder создаёт заказ сагой createOrderSaga; inTx, если задан, выполняется
+// в транзакции записи заказа после его сохранения
+func (s *defaultOrderService) createOrder(
+	ctx context.Context,
+	request *dto.CreateOrderRequest,
+	op string,
+	inTx func(ctx context.Context, response *dto.OrderResponse) error,
+) (*dto.OrderResponse, error) {
 	if err := validateCreateOrderReq(request); err != nil {
 		return nil, fmt.Errorf("%s: invalid request: %w", op, err)
 	}
+	if _, err := s.payments.Provider(request.PaymentMethod); err != nil {
+		return nil, fmt.Errorf("%s: invalid request: %w", op, err)
+	}
+
+	data := &createOrderSagaData{request: request, inTx: inTx}
+	if err := s.createOrderSaga.Execute(ctx, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return data.response, nil
+}
+
+// RunSagaRecovery до отмены ctx доводит саги, брошенные упавшими процессами
+func (s *defaultOrderService) RunSagaRecovery(ctx context.Context, cfg config.SagaConfig) {
+	s.createOrderSaga.RecoverLoop(ctx, cfg.RecoveryInterval, cfg.StaleAfter, cfg.BatchSize)
+}
+
+// GetOrder обычный пользователь видит только свои заказы, менеджер и администратор — любые
+func (s *defaultOrderService) GetOrder(ctx context.Context, id int64, actor domain.Actor) (*dto.OrderResponse, error) {
+	order, err := s.storage.GetOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("service.GetOrder: failed to get order: %w", err)
+	}
+
+	if !actor.CanAccessOrder(order) {
+		return nil, fmt.Errorf("service.GetOrder: %w", domain.ErrOrderAccessDenied)
+	}
+
+	order.PriceLines, err = s.storage.GetOrderPriceLines(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service.GetOrder: failed to get price lines: %w", err)
+	}
+
+	response := mapper.MapToOrderResponseFromOrder(order)
+	return response, nil
+}
+
+// ListOrders сотрудники видят все заказы, обычный пользователь — только свои
+func (s *defaultOrderService) ListOrders(ctx context.Context, request *dto.ListOrdersRequest, actor domain.Actor) (*dto.ListOrdersResponse, error) {
+	const op = "service.ListOrders"
+
+	if !actor.IsStaff() {
+		if request.UserID != nil && *request.UserID != actor.UserID {
+			return nil, fmt.Errorf("%s: %w", op, domain.ErrOrderAccessDenied)
+		}
+		request.UserID = &actor.UserID
+	}
+
+	filter, err := buildOrderFilter(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit = limit + 1
 
-	// 2. Получаем продукты из БД (цены не доверяем клиенту!)
-	domainItems, itemsPrice, err := s.processOrderRequest(ctx, request, op)
+	orders, err := s.storage.GetOrdersWithItems(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to process order request: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to list orders: %w", op, err)
+	}
+
+	response := &dto.ListOrdersResponse{
+		Orders: make([]*dto.OrderResponse, 0, min(len(orders), limit)),
+	}
+
+	if len(orders) > limit {
+		orders = orders[:limit]
+		response.NextCursor = encodeOrderCursor(filter, orders[len(orders)-1])
 	}
 
-	// 3. Рассчитываем цены
-	taxPrice, shippingPrice, totalPrice := calculateOrderPrices(itemsPrice)
+	for _, order := range orders {
+		response.Orders = append(response.Orders, mapper.MapToOrderResponseFromOrder(order))
+	}
+
+	return response, nil
+}
+
+func (s *defaultOrderService) UpdateOrderStatus(ctx context.Context, id int64, request *dto.UpdateOrderStatusRequest) (*dto.OrderResponse, error) {
+	const op = "service.UpdateOrderStatus"
 
-	// 4. Создаём заказ
-	order := &domain.Order{
-		PaymentMethod: request.PaymentMethod,
-		TaxPrice:      taxPrice,
-		ShippingPrice: shippingPrice,
-		TotalPrice:    totalPrice,
-		UserID:        request.UserID,
-		Status:        domain.OrderStatusPending,
-		CreatedAt:     time.Now(),
-		Items:         domainItems,
+	order, err := s.changeOrderStatus(ctx, id, request.Version, func(order *domain.Order) (*domain.OrderStatusHistory, error) {
+		return order.TransitionTo(request.Status, request.ChangedBy, request.Comment, s.dbNow())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var createdOrder *domain.Order
+	return mapper.MapToOrderResponseFromOrder(order), nil
+}
+
+func (s *defaultOrderService) CancelOrder(ctx context.Context, id int64, actor domain.Actor, request *dto.CancelOrderRequest) (*dto.OrderResponse, error) {
+	const op = "service.CancelOrder"
 
-	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
-		if err := s.storage.CreateOrder(ctx, order); err != nil {
-			return fmt.Errorf("failed to create order: %w", err)
+	order, err := s.changeOrderStatus(ctx, id, request.Version, func(order *domain.Order) (*domain.OrderStatusHistory, error) {
+		return order.Cancel(actor, request.Reason, s.dbNow())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mapper.MapToOrderResponseFromOrder(order), nil
+}
+
+// changeOrderStatus блокирует заказ, применяет переход и сохраняет его вместе с записью в истории.
+// version — версия заказа, которую видел клиент: если заказ с тех пор изменили, переход не применяется.
+// Внешние вызовы перехода делает prepareStatusChange до транзакции: транзакция может повториться
+// после конфликта, а подтверждение резерва и списание оплаты повторяться не должны. Если транзакция
+// переход отклонила, их последствия разбирает compensateStatusChange.
+func (s *defaultOrderService) changeOrderStatus(
+	ctx context.Context,
+	id int64,
+	version int64,
+	transition func(order *domain.Order) (*domain.OrderStatusHistory, error),
+) (*domain.Order, error) {
+	if err := s.prepareStatusChange(ctx, id, version, transition); err != nil {
+		return nil, err
+	}
+
+	var updated *domain.Order
+
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		order, err := s.storage.GetOrderForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		if err := checkOrderVersion(order, version); err != nil {
+			return err
+		}
+
+		history, err := transition(order)
+		if err != nil {
+			return err
 		}
 
-		// Проставляем OrderID для элементов
-		for _, item := range order.Items {
-			item.OrderID = order.ID
+		if order.Status == domain.OrderStatusCancelled {
+			if err := s.checkNoPendingShipment(ctx, order.ID); err != nil {
+				return err
+			}
 		}
 
-		if err := s.storage.CreateOrderItems(ctx, order.Items); err != nil {
-			return fmt.Errorf("failed to create order items: %w", err)
+		if err := s.applyStatusChange(ctx, order, history); err != nil {
+			return err
 		}
 
-		createdOrder = order
+		updated = order
 		return nil
 	})
-
 	if err != nil {
+		s.compensateStatusChange(ctx, id)
 		return nil, err
 	}
 
-	response := mapper.MapToOrderResponseFromOrder(createdOrder)
-	return response, nil
+	if updated.Status == domain.OrderStatusCancelled {
+		s.releaseOrder(ctx, updated.ID)
+	}
+
+	s.log.Info("order status changed",
+		slog.Int64("order_id", updated.ID),
+		slog.String("status", updated.Status),
+	)
+
+	return updated, nil
 }
 
-func (s *defaultOrderService) GetOrder(ctx context.Context, id int64) (*dto.OrderResponse, error) {
+// prepareStatusChange проверяет переход на заказе, прочитанном без блокировки, и выполняет его
+// внешние вызовы. Заказ в транзакции перечитывается, и переход проверяется заново.
+func (s *defaultOrderService) prepareStatusChange(
+	ctx context.Context,
+	id int64,
+	version int64,
+	transition func(order *domain.Order) (*domain.OrderStatusHistory, error),
+) error {
 	order, err := s.storage.GetOrder(ctx, id)
 	if err != nil {
-		return nil, ErrOrderNotFound
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("failed to get order: %w", err)
 	}
 
-	response := mapper.MapToOrderResponseFromOrder(order)
-	return response, nil
+	if err := checkOrderVersion(order, version); err != nil {
+		return err
+	}
+
+	draft := *order
+	if _, err := transition(&draft); err != nil {
+		return err
+	}
+
+	return s.statusChangeEffects(ctx, &draft)
+}
+
+// statusChangeEffects внешние вызовы перехода в статус order. В обработку заказ уходит только
+// с одобренной оплатой и подтверждённым резервом (дальше резерв бессрочный), при отгрузке оплата списывается.
+func (s *defaultOrderService) statusChangeEffects(ctx context.Context, order *domain.Order) error {
+	switch order.Status {
+	case domain.OrderStatusProcessing:
+		if _, err := s.requireAuthorizedPayment(ctx, order.ID); err != nil {
+			return err
+		}
+		if err := s.inventory.Confirm(ctx, order.ID); err != nil {
+			return fmt.Errorf("failed to confirm stock reservation: %w", err)
+		}
+	case domain.OrderStatusShipped:
+		if err := s.capturePayment(ctx, order.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compensateStatusChange вызывается, когда транзакция отклонила переход после его внешних вызовов.
+// Если заказ тем временем отменили, отмена могла не застать списание оплаты или подтверждение резерва:
+// деньги и резерв освобождаются ещё раз. Иначе они остаются за заказом, и повтор перехода их не повторит.
+func (s *defaultOrderService) compensateStatusChange(ctx context.Context, id int64) {
+	ctx = context.WithoutCancel(ctx)
+
+	order, err := s.storage.GetOrder(ctx, id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.log.Error("failed to get order after rejected status change",
+				slog.Int64("order_id", id),
+				slog.String("error", err.Error()),
+			)
+		}
+		return
+	}
+
+	if order.Status == domain.OrderStatusCancelled {
+		s.releaseOrder(ctx, id)
+	}
+}
+
+// checkOrderVersion ErrOrderVersionMismatch, если заказ изменили после того, как клиент видел version
+func checkOrderVersion(order *domain.Order, version int64) error {
+	if order.Version != version {
+		return domain.ErrOrderVersionMismatch.WithFields(
+			apperror.Field("If-Match", fmt.Sprintf("current order version is %d", order.Version)),
+		)
+	}
+	return nil
+}
+
+// applyStatusChange сохраняет переход статуса в текущей транзакции вместе с историей и событием
+func (s *defaultOrderService) applyStatusChange(ctx context.Context, order *domain.Order, history *domain.OrderStatusHistory) error {
+	// условие на версию страхует от записи заказа, прочитанного без блокировки
+	if err := s.storage.UpdateOrderStatus(ctx, order); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrOrderVersionMismatch
+		}
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if err := s.storage.CreateOrderStatusHistory(ctx, history); err != nil {
+		return fmt.Errorf("failed to save status history: %w", err)
+	}
+
+	msg, err := newOrderStatusMessage(order, history)
+	if err != nil {
+		return err
+	}
+	return s.writeOutbox(ctx, msg)
+}
+
+// releaseReservation снимает резерв заказа. Ошибка только логируется: неподтверждённый резерв
+// всё равно истечёт, а откатывать уже выполненное действие из-за неё нельзя.
+func (s *defaultOrderService) releaseReservation(ctx context.Context, orderID int64) {
+	if err := s.inventory.Release(context.WithoutCancel(ctx), orderID); err != nil {
+		s.log.Error("failed to release stock reservation",
+			slog.Int64("order_id", orderID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// writeOutbox сохраняет событие в текущей транзакции: оно будет опубликовано, только если транзакция зафиксируется
+func (s *defaultOrderService) writeOutbox(ctx context.Context, msg *domain.OutboxMessage) error {
+	if err := s.storage.CreateOutboxMessage(ctx, msg); err != nil {
+		return fmt.Errorf("failed to write %s to outbox: %w", msg.EventType, err)
+	}
+	return nil
 }
 
 // processOrderRequest получает продукты из product-service, проверяет наличие и создаёт элементы заказа
-func (s *defaultOrderService) processOrderRequest(ctx context.Context, req *dto.CreateOrderRequest, op string) ([]*domain.OrderItem, float64, error) {
+// вместе с позициями для расчёта цены
+func (s *defaultOrderService) processOrderRequest(ctx context.Context, req *dto.CreateOrderRequest, op string) ([]*domain.OrderItem, []pricing.Line, error) {
 	// Собираем уникальные ID продуктов
 	productIDs := make([]int64, 0, len(req.Items))
 	uniqueProductIDs := make(map[int64]struct{})
@@ -130,12 +453,12 @@ func (s *defaultOrderService) processOrderRequest(ctx context.Context, req *dto.
 	// Получаем продукты из product-service (с реальными ценами)
 	products, err := s.productClient.GetProductsByIDs(ctx, productIDs)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get products from product-service: %w", err)
+		return nil, nil, fmt.Errorf("failed to get products from product-service: %w", err)
 	}
 
 	// Проверяем, что все продукты найдены
 	if len(products) != len(uniqueProductIDs) {
-		return nil, 0, fmt.Errorf("%s: not all products found", op)
+		return nil, nil, fmt.Errorf("%s: %w: not all products found", op, ErrProductNotFound)
 	}
 
 	// Создаём мапу для быстрого доступа
@@ -146,18 +469,22 @@ func (s *defaultOrderService) processOrderRequest(ctx context.Context, req *dto.
 
 	// Создаём элементы заказа с реальными ценами
 	domainItems := make([]*domain.OrderItem, 0, len(req.Items))
-	var itemsPrice float64 = 0
+	priceLines := make([]pricing.Line, 0, len(req.Items))
 
-	for _, item := range req.Items {
+	for i, item := range req.Items {
 		product, exists := productMap[item.ProductID]
 		if !exists {
-			return nil, 0, fmt.Errorf("product %d not found", item.ProductID)
+			return nil, nil, ErrProductNotFound.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].product_id", i), fmt.Sprintf("product %d not found", item.ProductID),
+			))
 		}
 
-		// Проверяем наличие на складе
-		if product.CountInStock < item.Quantity {
-			return nil, 0, fmt.Errorf("not enough stock for product %d: requested %d, available %d",
-				item.ProductID, item.Quantity, product.CountInStock)
+		// Проверяем наличие на складе, если остаток известен
+		if product.CountInStock != dto.StockUnknown && product.CountInStock < item.Quantity {
+			return nil, nil, ErrInsufficientStock.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].quantity", i),
+				fmt.Sprintf("product %d: requested %d, available %d", item.ProductID, item.Quantity, product.CountInStock),
+			))
 		}
 
 		orderItem := &domain.OrderItem{
@@ -169,40 +496,91 @@ func (s *defaultOrderService) processOrderRequest(ctx context.Context, req *dto.
 		}
 		domainItems = append(domainItems, orderItem)
 
-		itemsPrice += product.Price * float64(item.Quantity)
+		priceLines = append(priceLines, pricing.Line{
+			ProductID:   product.ID,
+			Category:    product.Category,
+			Quantity:    item.Quantity,
+			UnitPrice:   product.Price,
+			WeightGrams: product.WeightGrams,
+		})
 	}
 
-	return domainItems, itemsPrice, nil
-}
-
-// calculateOrderPrices рассчитывает налог, доставку и итоговую сумму
-func calculateOrderPrices(itemsPrice float64) (float64, float64, float64) {
-	const taxRate = 0.10      // 10% налог
-	const shippingPrice = 150 // Фиксированная доставка
-
-	taxPrice := itemsPrice * taxRate
-	totalPrice := itemsPrice + taxPrice + shippingPrice
-
-	return taxPrice, shippingPrice, totalPrice
+	return domainItems, priceLines, nil
 }
 
 func validateCreateOrderReq(req *dto.CreateOrderRequest) error {
 	if req.PaymentMethod == "" {
-		return ErrPaymentMethodEmpty
+		return ErrPaymentMethodEmpty.WithFields(apperror.Field("payment_method", "must not be empty"))
 	}
 
 	if req.Items == nil || len(req.Items) == 0 {
-		return ErrEmptyOrderItems
+		return ErrEmptyOrderItems.WithFields(apperror.Field("items", "must contain at least one item"))
 	}
 
-	for _, item := range req.Items {
+	for i, item := range req.Items {
 		if item.Quantity <= 0 {
-			return ErrInvalidQuantity
+			return ErrInvalidQuantity.WithFields(apperror.Field(fmt.Sprintf("items[%d].quantity", i), "must be positive"))
 		}
 		if item.ProductID <= 0 {
-			return ErrInvalidProductID
+			return ErrInvalidProductID.WithFields(apperror.Field(fmt.Sprintf("items[%d].product_id", i), "must be positive"))
 		}
 	}
 
-	return nil
+	return validateOrderAddresses(&req.OrderAddressesRequest)
+}
+
+func buildOrderFilter(req *dto.ListOrdersRequest) (domain.OrderFilter, error) {
+	filter := domain.OrderFilter{
+		UserID:      req.UserID,
+		Status:      req.Status,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		SortBy:      domain.OrderSortByCreatedAt,
+		Desc:        true,
+		Limit:       defaultListLimit,
+	}
+
+	switch req.SortBy {
+	case "", domain.OrderSortByCreatedAt:
+	case domain.OrderSortByTotalPrice:
+		filter.SortBy = domain.OrderSortByTotalPrice
+	default:
+		return filter, ErrInvalidListRequest.WithFields(apperror.Field("sort", fmt.Sprintf("unknown sort field %q", req.SortBy)))
+	}
+
+	switch req.SortOrder {
+	case "", "desc":
+	case "asc":
+		filter.Desc = false
+	default:
+		return filter, ErrInvalidListRequest.WithFields(apperror.Field("order", fmt.Sprintf("unknown sort order %q", req.SortOrder)))
+	}
+
+	switch req.Status {
+	case "", domain.OrderStatusPending, domain.OrderStatusProcessing, domain.OrderStatusShipped,
+		domain.OrderStatusDelivered, domain.OrderStatusCancelled:
+	default:
+		return filter, ErrInvalidListRequest.WithFields(apperror.Field("status", fmt.Sprintf("unknown status %q", req.Status)))
+	}
+
+	if req.CreatedFrom != nil && req.CreatedTo != nil && !req.CreatedFrom.Before(*req.CreatedTo) {
+		return filter, ErrInvalidListRequest.WithFields(apperror.Field("created_from", "must be before created_to"))
+	}
+
+	if req.Limit < 0 || req.Limit > maxListLimit {
+		return filter, ErrInvalidListRequest.WithFields(apperror.Field("limit", fmt.Sprintf("must be between 1 and %d", maxListLimit)))
+	}
+	if req.Limit > 0 {
+		filter.Limit = req.Limit
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeOrderCursor(req.Cursor, filter)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
 }
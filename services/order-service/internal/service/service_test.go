@@ -0,0 +1,909 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/defan6/market/services/order-service/internal/payments"
+	"github.com/defan6/market/services/order-service/internal/pricing"
+	"github.com/defan6/market/services/shared/logger/handlers/slogdiscard"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderStorage хранит заказы в памяти и запоминает последний фильтр.
+// Позиции и строки цены хранятся отдельно от заказа, как в таблицах БД.
+type fakeOrderStorage struct {
+	orders          []*domain.Order
+	items           map[int64][]*domain.OrderItem
+	priceLines      map[int64][]*domain.OrderPriceLine
+	lastFilter      domain.OrderFilter
+	idempotencyKeys map[string]*domain.IdempotencyKey
+	outbox          []*domain.OutboxMessage
+	sagas           []domain.Saga
+	payments        []*domain.Payment
+	returns         []*domain.Return
+	promoCodes      []*domain.PromoCode
+	redemptions     []*domain.PromoRedemption
+	cart            []*domain.CartItem
+	addresses       []*domain.SavedAddress
+	shipments       []*domain.Shipment
+}
+
+func (f *fakeOrderStorage) NextOrderID(ctx context.Context) (int64, error) {
+	return int64(len(f.orders) + 1), nil
+}
+
+func (f *fakeOrderStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
+	row := *order
+	row.Items, row.PriceLines = nil, nil
+	f.orders = append(f.orders, &row)
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateOrderItems(ctx context.Context, items []*domain.OrderItem) error {
+	if f.items == nil {
+		f.items = make(map[int64][]*domain.OrderItem)
+	}
+	for _, item := range items {
+		item.ID = int64(len(f.items[item.OrderID]) + 1)
+		row := *item
+		f.items[item.OrderID] = append(f.items[item.OrderID], &row)
+	}
+	return nil
+}
+
+func (f *fakeOrderStorage) GetOrder(ctx context.Context, id int64) (*domain.Order, error) {
+	for _, order := range f.orders {
+		if order.ID == id {
+			order.Items = f.items[id]
+			return order, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeOrderStorage) GetOrdersWithItems(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error) {
+	f.lastFilter = filter
+	orders := f.orders
+	if len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+	}
+	for _, order := range orders {
+		order.Items = f.items[order.ID]
+	}
+	return orders, nil
+}
+
+func (f *fakeOrderStorage) GetOrderForUpdate(ctx context.Context, id int64) (*domain.Order, error) {
+	return f.GetOrder(ctx, id)
+}
+
+func (f *fakeOrderStorage) UpdateOrderStatus(ctx context.Context, order *domain.Order) error {
+	for _, row := range f.orders {
+		if row.ID == order.ID && row.Version == order.Version {
+			order.Version++
+			row.Status, row.UpdatedAt, row.CancellationReason, row.Version = order.Status, order.UpdatedAt, order.CancellationReason, order.Version
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (f *fakeOrderStorage) CreateOrderStatusHistory(ctx context.Context, history *domain.OrderStatusHistory) error {
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateOrderPriceLines(ctx context.Context, lines []*domain.OrderPriceLine) error {
+	if f.priceLines == nil {
+		f.priceLines = make(map[int64][]*domain.OrderPriceLine)
+	}
+	for _, line := range lines {
+		row := *line
+		f.priceLines[line.OrderID] = append(f.priceLines[line.OrderID], &row)
+	}
+	return nil
+}
+
+func (f *fakeOrderStorage) GetOrderPriceLines(ctx context.Context, orderID int64) ([]*domain.OrderPriceLine, error) {
+	return f.priceLines[orderID], nil
+}
+
+func (f *fakeOrderStorage) GetIdempotencyKey(ctx context.Context, userID int64, key string, now time.Time) (*domain.IdempotencyKey, error) {
+	record, ok := f.idempotencyKeys[fmt.Sprintf("%d/%s", userID, key)]
+	if !ok || !now.Before(record.ExpiresAt) {
+		return nil, sql.ErrNoRows
+	}
+	return record, nil
+}
+
+func (f *fakeOrderStorage) SaveIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error {
+	id := fmt.Sprintf("%d/%s", record.UserID, record.Key)
+	if existing, ok := f.idempotencyKeys[id]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		return sql.ErrNoRows
+	}
+	if f.idempotencyKeys == nil {
+		f.idempotencyKeys = make(map[string]*domain.IdempotencyKey)
+	}
+	f.idempotencyKeys[id] = record
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateOutboxMessage(ctx context.Context, msg *domain.OutboxMessage) error {
+	msg.ID = int64(len(f.outbox) + 1)
+	f.outbox = append(f.outbox, msg)
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateSaga(ctx context.Context, saga *domain.Saga) error {
+	saga.ID = int64(len(f.sagas) + 1)
+	f.sagas = append(f.sagas, *saga)
+	return nil
+}
+
+func (f *fakeOrderStorage) UpdateSaga(ctx context.Context, saga *domain.Saga) error {
+	f.sagas[saga.ID-1] = *saga
+	return nil
+}
+
+func (f *fakeOrderStorage) ListStaleSagas(ctx context.Context, name string, before time.Time, limit int) ([]*domain.Saga, error) {
+	var stale []*domain.Saga
+	for _, saga := range f.sagas {
+		if saga.Name == name && !saga.Finished() && saga.UpdatedAt.Before(before) {
+			stale = append(stale, &saga)
+		}
+	}
+	return stale, nil
+}
+
+func (f *fakeOrderStorage) ClaimSaga(ctx context.Context, saga *domain.Saga, at time.Time) (bool, error) {
+	saga.UpdatedAt = at
+	f.sagas[saga.ID-1].UpdatedAt = at
+	return true, nil
+}
+
+func (f *fakeOrderStorage) CreatePayment(ctx context.Context, payment *domain.Payment) error {
+	payment.ID = int64(len(f.payments) + 1)
+	payment.Attempt = 1
+	for _, p := range f.payments {
+		if p.OrderID == payment.OrderID {
+			payment.Attempt++
+		}
+	}
+	row := *payment
+	f.payments = append(f.payments, &row)
+	return nil
+}
+
+func (f *fakeOrderStorage) UpdatePayment(ctx context.Context, payment *domain.Payment) error {
+	row := *payment
+	f.payments[payment.ID-1] = &row
+	return nil
+}
+
+func (f *fakeOrderStorage) GetLatestPayment(ctx context.Context, orderID int64) (*domain.Payment, error) {
+	for i := len(f.payments) - 1; i >= 0; i-- {
+		if f.payments[i].OrderID == orderID {
+			payment := *f.payments[i]
+			return &payment, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeOrderStorage) CreateReturn(ctx context.Context, ret *domain.Return) error {
+	ret.ID = int64(len(f.returns) + 1)
+	for i, item := range ret.Items {
+		item.ID, item.ReturnID = int64(i+1), ret.ID
+	}
+	row := *ret
+	f.returns = append(f.returns, &row)
+	return nil
+}
+
+func (f *fakeOrderStorage) GetOrderReturns(ctx context.Context, orderID int64) ([]*domain.Return, error) {
+	var returns []*domain.Return
+	for _, ret := range f.returns {
+		if ret.OrderID == orderID {
+			row := *ret
+			returns = append(returns, &row)
+		}
+	}
+	return returns, nil
+}
+
+func (f *fakeOrderStorage) GetReturnForUpdate(ctx context.Context, orderID, returnID int64) (*domain.Return, error) {
+	for _, ret := range f.returns {
+		if ret.ID == returnID && ret.OrderID == orderID {
+			row := *ret
+			return &row, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeOrderStorage) UpdateReturnStatus(ctx context.Context, ret *domain.Return) error {
+	row := f.returns[ret.ID-1]
+	row.Status, row.UpdatedAt = ret.Status, ret.UpdatedAt
+	return nil
+}
+
+func (f *fakeOrderStorage) GetPromoCode(ctx context.Context, code string) (*domain.PromoCode, error) {
+	for _, promo := range f.promoCodes {
+		if promo.Code == code {
+			row := *promo
+			return &row, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeOrderStorage) GetPromoCodeForUpdate(ctx context.Context, code string) (*domain.PromoCode, error) {
+	return f.GetPromoCode(ctx, code)
+}
+
+func (f *fakeOrderStorage) CountPromoRedemptions(ctx context.Context, promoCodeID, userID int64) (int64, error) {
+	var count int64
+	for _, r := range f.redemptions {
+		if r.PromoCodeID == promoCodeID && r.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeOrderStorage) RedeemPromoCode(ctx context.Context, redemption *domain.PromoRedemption) error {
+	redemption.ID = int64(len(f.redemptions) + 1)
+	f.redemptions = append(f.redemptions, redemption)
+	for _, promo := range f.promoCodes {
+		if promo.ID == redemption.PromoCodeID {
+			promo.Uses++
+		}
+	}
+	return nil
+}
+
+func (f *fakeOrderStorage) GetCartItems(ctx context.Context, userID int64) ([]*domain.CartItem, error) {
+	var items []*domain.CartItem
+	for _, item := range f.cart {
+		if item.UserID == userID {
+			row := *item
+			items = append(items, &row)
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeOrderStorage) SaveCartItem(ctx context.Context, item *domain.CartItem) error {
+	row := *item
+	for i, existing := range f.cart {
+		if existing.UserID == item.UserID && existing.ProductID == item.ProductID {
+			row.CreatedAt = existing.CreatedAt
+			f.cart[i] = &row
+			return nil
+		}
+	}
+	f.cart = append(f.cart, &row)
+	return nil
+}
+
+func (f *fakeOrderStorage) DeleteCartItem(ctx context.Context, userID, productID int64) error {
+	n := len(f.cart)
+	f.cart = slices.DeleteFunc(f.cart, func(item *domain.CartItem) bool {
+		return item.UserID == userID && item.ProductID == productID
+	})
+	if len(f.cart) == n {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (f *fakeOrderStorage) ClearCart(ctx context.Context, userID int64, upTo time.Time) error {
+	f.cart = slices.DeleteFunc(f.cart, func(item *domain.CartItem) bool {
+		return item.UserID == userID && !item.UpdatedAt.After(upTo)
+	})
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateAddress(ctx context.Context, address *domain.SavedAddress) error {
+	address.ID = int64(len(f.addresses) + 1)
+	row := *address
+	f.addresses = append(f.addresses, &row)
+	return nil
+}
+
+func (f *fakeOrderStorage) GetUserAddresses(ctx context.Context, userID int64) ([]*domain.SavedAddress, error) {
+	var addresses []*domain.SavedAddress
+	for _, address := range f.addresses {
+		if address != nil && address.UserID == userID {
+			row := *address
+			addresses = append(addresses, &row)
+		}
+	}
+	return addresses, nil
+}
+
+func (f *fakeOrderStorage) GetAddress(ctx context.Context, userID, id int64) (*domain.SavedAddress, error) {
+	if id < 1 || id > int64(len(f.addresses)) || f.addresses[id-1] == nil || f.addresses[id-1].UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	row := *f.addresses[id-1]
+	return &row, nil
+}
+
+func (f *fakeOrderStorage) UpdateAddress(ctx context.Context, address *domain.SavedAddress) error {
+	if _, err := f.GetAddress(ctx, address.UserID, address.ID); err != nil {
+		return err
+	}
+	row := *address
+	f.addresses[address.ID-1] = &row
+	return nil
+}
+
+// DeleteAddress оставляет пустое место, чтобы ID остальных адресов совпадали с индексами
+func (f *fakeOrderStorage) DeleteAddress(ctx context.Context, userID, id int64) error {
+	if _, err := f.GetAddress(ctx, userID, id); err != nil {
+		return err
+	}
+	f.addresses[id-1] = nil
+	return nil
+}
+
+func (f *fakeOrderStorage) CreateShipment(ctx context.Context, shipment *domain.Shipment) error {
+	shipment.ID = int64(len(f.shipments) + 1)
+	for i, item := range shipment.Items {
+		item.ID, item.ShipmentID = int64(i+1), shipment.ID
+	}
+	f.shipments = append(f.shipments, copyShipment(shipment))
+	return nil
+}
+
+func (f *fakeOrderStorage) GetOrderShipments(ctx context.Context, orderID int64) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	for _, shipment := range f.shipments {
+		if shipment != nil && shipment.OrderID == orderID {
+			shipments = append(shipments, copyShipment(shipment))
+		}
+	}
+	return shipments, nil
+}
+
+func (f *fakeOrderStorage) ListActiveShipments(ctx context.Context, limit int) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	for _, shipment := range f.shipments {
+		if shipment == nil || shipment.Status == domain.ShipmentStatusDelivered || shipment.Status == domain.ShipmentStatusPending {
+			continue
+		}
+		if len(shipments) < limit {
+			shipments = append(shipments, copyShipment(shipment))
+		}
+	}
+	return shipments, nil
+}
+
+func (f *fakeOrderStorage) ListPendingShipments(ctx context.Context, createdBefore time.Time, limit int) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	for _, shipment := range f.shipments {
+		if shipment != nil && shipment.Status == domain.ShipmentStatusPending && shipment.CreatedAt.Before(createdBefore) && len(shipments) < limit {
+			shipments = append(shipments, copyShipment(shipment))
+		}
+	}
+	return shipments, nil
+}
+
+func (f *fakeOrderStorage) UpdateShipmentLabel(ctx context.Context, shipment *domain.Shipment) error {
+	row := f.shipments[shipment.ID-1]
+	row.TrackingNumber, row.LabelURL, row.Status, row.UpdatedAt = shipment.TrackingNumber, shipment.LabelURL, shipment.Status, shipment.UpdatedAt
+	return nil
+}
+
+func (f *fakeOrderStorage) DeleteShipment(ctx context.Context, id int64) error {
+	f.shipments[id-1] = nil
+	return nil
+}
+
+func (f *fakeOrderStorage) UpdateShipmentTracking(ctx context.Context, shipment *domain.Shipment) error {
+	row := f.shipments[shipment.ID-1]
+	row.Status, row.UpdatedAt, row.DeliveredAt, row.TrackedAt = shipment.Status, shipment.UpdatedAt, shipment.DeliveredAt, shipment.TrackedAt
+	return nil
+}
+
+// copyShipment отвязывает отправление от хранилища вместе с позициями
+func copyShipment(shipment *domain.Shipment) *domain.Shipment {
+	row := *shipment
+	row.Items = make([]*domain.ShipmentItem, 0, len(shipment.Items))
+	for _, item := range shipment.Items {
+		itemRow := *item
+		row.Items = append(row.Items, &itemRow)
+	}
+	return &row
+}
+
+// fakeTxManager выполняет fn без транзакции
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// snapshot копия хранилища для отката попытки транзакции; строки, которые меняются на месте, копируются
+func (f *fakeOrderStorage) snapshot() fakeOrderStorage {
+	snap := *f
+	snap.orders = copyRows(f.orders)
+	snap.items = maps.Clone(f.items)
+	snap.priceLines = maps.Clone(f.priceLines)
+	snap.idempotencyKeys = maps.Clone(f.idempotencyKeys)
+	snap.outbox = slices.Clone(f.outbox)
+	snap.sagas = slices.Clone(f.sagas)
+	snap.payments = slices.Clone(f.payments)
+	snap.returns = copyRows(f.returns)
+	snap.promoCodes = copyRows(f.promoCodes)
+	snap.redemptions = slices.Clone(f.redemptions)
+	snap.cart = slices.Clone(f.cart)
+	snap.addresses = slices.Clone(f.addresses)
+	snap.shipments = make([]*domain.Shipment, len(f.shipments))
+	for i, shipment := range f.shipments {
+		if shipment != nil {
+			snap.shipments[i] = copyShipment(shipment)
+		}
+	}
+	return snap
+}
+
+func copyRows[T any](rows []*T) []*T {
+	copied := make([]*T, len(rows))
+	for i, row := range rows {
+		if row != nil {
+			copied[i] = new(T)
+			*copied[i] = *row
+		}
+	}
+	return copied
+}
+
+// retryingTxManager как storage.TxManager повторяет fn после конфликта сериализации;
+// перед повтором хранилище откатывается к состоянию до попытки
+type retryingTxManager struct {
+	storage     *fakeOrderStorage
+	maxAttempts int
+}
+
+func (m retryingTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		snap := m.storage.snapshot()
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		*m.storage = snap
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "40001" || attempt == m.maxAttempts {
+			return err
+		}
+	}
+}
+
+// failCommitTxManager откатывает первую успешную транзакцию, как если бы не удался COMMIT
+type failCommitTxManager struct {
+	storage *fakeOrderStorage
+	failed  bool
+}
+
+func (m *failCommitTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	snap := m.storage.snapshot()
+	if err := fn(ctx); err != nil {
+		*m.storage = snap
+		return err
+	}
+	if !m.failed {
+		m.failed = true
+		*m.storage = snap
+		return errors.New("connection reset during commit")
+	}
+	return nil
+}
+
+// conflictStorage прерывает транзакцию конфликтом сериализации после успешной записи статуса заказа,
+// пока не исчерпает conflicts; versions — версии заказов, с которыми их пытались записать
+type conflictStorage struct {
+	*fakeOrderStorage
+	conflicts int
+	versions  []int64
+}
+
+func (s *conflictStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
+	s.versions = append(s.versions, order.Version)
+	return s.fakeOrderStorage.CreateOrder(ctx, order)
+}
+
+func (s *conflictStorage) UpdateOrderStatus(ctx context.Context, order *domain.Order) error {
+	if err := s.fakeOrderStorage.UpdateOrderStatus(ctx, order); err != nil {
+		return err
+	}
+	if s.conflicts > 0 {
+		s.conflicts--
+		return &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}
+	}
+	return nil
+}
+
+// countingInventory считает подтверждения резерва
+type countingInventory struct {
+	InventoryClient
+	confirms int
+}
+
+func (c *countingInventory) Confirm(ctx context.Context, orderID int64) error {
+	c.confirms++
+	return c.InventoryClient.Confirm(ctx, orderID)
+}
+
+// countingProvider считает списания оплаты; onCapture, если задан, вызывается после списания у провайдера
+type countingProvider struct {
+	payments.PaymentProvider
+	captures  int
+	onCapture func()
+}
+
+func (p *countingProvider) Capture(ctx context.Context, authorizationID string, amount money.Money) error {
+	p.captures++
+	if err := p.PaymentProvider.Capture(ctx, authorizationID, amount); err != nil {
+		return err
+	}
+	if p.onCapture != nil {
+		p.onCapture()
+	}
+	return nil
+}
+
+// newRetryTestService сервис, у которого транзакции повторяются после конфликта, как в storage.TxManager
+func newRetryTestService(storage *conflictStorage) (*defaultOrderService, *countingInventory, *countingProvider) {
+	inventory := &countingInventory{InventoryClient: newTestInventory()}
+	provider := &countingProvider{PaymentProvider: payments.NewFakeProvider("fake", []string{"card", "sbp"}, nil)}
+	registry, err := payments.NewRegistry(provider)
+	if err != nil {
+		panic(err)
+	}
+	txManager := retryingTxManager{storage: storage.fakeOrderStorage, maxAttempts: 3}
+	svc := NewDefaultOrderService(slogdiscard.NewDiscardLogger(), storage, txManager, NewStubProductClient(), inventory, registry, newTestCarriers(newFakeCarrier()), fakePricingEngine{}, config.IdempotencyConfig{TTL: time.Hour})
+	return svc, inventory, provider
+}
+
+// fakePricingEngine считает итог как сумму позиций за вычетом скидки, без налогов и доставки
+type fakePricingEngine struct{}
+
+func (fakePricingEngine) Calculate(ctx context.Context, quote pricing.Quote) (*pricing.Breakdown, error) {
+	items, discount := money.Zero(), money.Zero()
+	for _, line := range quote.Lines {
+		items = items.Add(line.Total())
+	}
+	if quote.Promo != nil {
+		discount = pricing.ApplyPromo(slices.Clone(quote.Lines), quote.Promo)
+	}
+	return &pricing.Breakdown{
+		ItemsPrice:    items,
+		DiscountPrice: discount,
+		TaxPrice:      money.Zero(),
+		ShippingPrice: money.Zero(),
+		TotalPrice:    items.Sub(discount),
+	}, nil
+}
+
+var staff = domain.Actor{UserID: 1, Role: domain.RoleManager}
+
+func newTestService(storage OrderStorage) *defaultOrderService {
+	return newTestServiceWithInventory(storage, newTestInventory())
+}
+
+func newTestServiceWithInventory(storage OrderStorage, inventory InventoryClient) *defaultOrderService {
+	return newTestServiceWith(storage, inventory, newTestPayments(nil))
+}
+
+func newTestServiceWith(storage OrderStorage, inventory InventoryClient, payments PaymentRegistry) *defaultOrderService {
+	return NewDefaultOrderService(slogdiscard.NewDiscardLogger(), storage, fakeTxManager{}, NewStubProductClient(), inventory, payments, newTestCarriers(newFakeCarrier()), fakePricingEngine{}, config.IdempotencyConfig{TTL: time.Hour})
+}
+
+func TestListOrders_Pagination(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+
+	storage := &fakeOrderStorage{}
+	for i := 3; i > 0; i-- {
+		storage.orders = append(storage.orders, &domain.Order{
+			ID:        int64(i),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+			Status:    domain.OrderStatusPending,
+		})
+	}
+	s := newTestService(storage)
+
+	first, err := s.ListOrders(ctx, &dto.ListOrdersRequest{Limit: 2}, staff)
+	require.NoError(t, err)
+	assert.Len(t, first.Orders, 2)
+	assert.NotEmpty(t, first.NextCursor)
+	assert.Equal(t, 3, storage.lastFilter.Limit, "storage must be asked for one extra row")
+	assert.True(t, storage.lastFilter.Desc)
+
+	_, err = s.ListOrders(ctx, &dto.ListOrdersRequest{Limit: 2, Cursor: first.NextCursor}, staff)
+	require.NoError(t, err)
+	require.NotNil(t, storage.lastFilter.After)
+	assert.Equal(t, int64(2), storage.lastFilter.After.ID)
+	assert.True(t, base.Add(2*time.Hour).Equal(storage.lastFilter.After.CreatedAt))
+
+	last, err := s.ListOrders(ctx, &dto.ListOrdersRequest{Limit: 5}, staff)
+	require.NoError(t, err)
+	assert.Len(t, last.Orders, 3)
+	assert.Empty(t, last.NextCursor)
+}
+
+func TestListOrders_InvalidRequest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(&fakeOrderStorage{})
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		req  *dto.ListOrdersRequest
+	}{
+		{name: "unknown sort", req: &dto.ListOrdersRequest{SortBy: "name"}},
+		{name: "unknown order", req: &dto.ListOrdersRequest{SortOrder: "up"}},
+		{name: "unknown status", req: &dto.ListOrdersRequest{Status: "lost"}},
+		{name: "limit too big", req: &dto.ListOrdersRequest{Limit: maxListLimit + 1}},
+		{name: "inverted range", req: &dto.ListOrdersRequest{CreatedFrom: &from, CreatedTo: &to}},
+		{name: "malformed cursor", req: &dto.ListOrdersRequest{Cursor: "!!!"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ListOrders(ctx, tt.req, staff)
+			assert.ErrorIs(t, err, ErrInvalidListRequest)
+		})
+	}
+}
+
+func TestListOrders_CursorBoundToSort(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{orders: []*domain.Order{
+		{ID: 2, TotalPrice: money.New(20000, money.DefaultCurrency)},
+		{ID: 1, TotalPrice: money.New(10000, money.DefaultCurrency)},
+	}}
+	s := newTestService(storage)
+
+	page, err := s.ListOrders(ctx, &dto.ListOrdersRequest{SortBy: domain.OrderSortByTotalPrice, Limit: 1}, staff)
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	_, err = s.ListOrders(ctx, &dto.ListOrdersRequest{Limit: 1, Cursor: page.NextCursor}, staff)
+	assert.ErrorIs(t, err, ErrInvalidListRequest)
+}
+
+func TestGetOrder_AccessControl(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{orders: []*domain.Order{{ID: 1, UserID: 42, Status: domain.OrderStatusPending}}}
+	svc := newTestService(storage)
+
+	resp, err := svc.GetOrder(ctx, 1, domain.Actor{UserID: 42, Role: domain.RoleUser})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.ID)
+
+	_, err = svc.GetOrder(ctx, 1, domain.Actor{UserID: 7, Role: domain.RoleUser})
+	assert.ErrorIs(t, err, domain.ErrOrderAccessDenied)
+
+	_, err = svc.GetOrder(ctx, 1, domain.Actor{UserID: 7, Role: domain.RoleManager})
+	require.NoError(t, err)
+}
+
+func TestGetOrder_RoundTripsCreatedOrder(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	req := &dto.CreateOrderRequest{
+		PaymentMethod:         "card",
+		UserID:                42,
+		OrderAddressesRequest: dto.OrderAddressesRequest{ShippingAddress: newTestAddress()},
+		Items: []*dto.CreateOrderItemRequest{
+			{ProductID: 1, Quantity: 2},
+			{ProductID: 5, Quantity: 1},
+		},
+	}
+	created, err := svc.CreateOrder(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, created.Items, 2)
+
+	got, err := svc.GetOrder(ctx, created.ID, domain.Actor{UserID: 42, Role: domain.RoleUser})
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	list, err := svc.ListOrders(ctx, &dto.ListOrdersRequest{}, domain.Actor{UserID: 42, Role: domain.RoleUser})
+	require.NoError(t, err)
+	require.Len(t, list.Orders, 1)
+	assert.Equal(t, created.Items, list.Orders[0].Items)
+}
+
+func TestListOrders_RegularUserSeesOnlyOwnOrders(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	s := newTestService(storage)
+	user := domain.Actor{UserID: 42, Role: domain.RoleUser}
+
+	_, err := s.ListOrders(ctx, &dto.ListOrdersRequest{}, user)
+	require.NoError(t, err)
+	require.NotNil(t, storage.lastFilter.UserID)
+	assert.Equal(t, int64(42), *storage.lastFilter.UserID)
+
+	other := int64(7)
+	_, err = s.ListOrders(ctx, &dto.ListOrdersRequest{UserID: &other}, user)
+	assert.ErrorIs(t, err, domain.ErrOrderAccessDenied)
+
+	_, err = s.ListOrders(ctx, &dto.ListOrdersRequest{UserID: &other}, staff)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *storage.lastFilter.UserID)
+}
+
+func TestGetOrder_StorageErrorIsNotNotFound(t *testing.T) {
+	storage := &failingGetStorage{err: errors.New("connection refused")}
+	svc := newTestService(storage)
+
+	_, err := svc.GetOrder(context.Background(), 1, staff)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrOrderNotFound)
+
+	_, err = newTestService(&fakeOrderStorage{}).GetOrder(context.Background(), 1, staff)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+// failingGetStorage имитирует недоступную БД при чтении заказа
+type failingGetStorage struct {
+	fakeOrderStorage
+	err error
+}
+
+func (f *failingGetStorage) GetOrder(ctx context.Context, id int64) (*domain.Order, error) {
+	return nil, f.err
+}
+
+func TestValidateCreateOrderReq_FieldDetails(t *testing.T) {
+	err := validateCreateOrderReq(&dto.CreateOrderRequest{
+		PaymentMethod: "card",
+		Items:         []*dto.CreateOrderItemRequest{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 0}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	appErr, ok := apperror.As(err)
+	require.True(t, ok)
+	assert.Equal(t, apperror.CodeInvalidArgument, appErr.Code)
+	assert.Equal(t, []apperror.FieldError{{Field: "items[1].quantity", Message: "must be positive"}}, appErr.Fields)
+}
+
+func TestUpdateOrderStatus_RejectsStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	seen := order.Version
+
+	shipped, err := svc.UpdateOrderStatus(ctx, order.ID, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusShipped, Version: seen})
+	require.NoError(t, err)
+	assert.Equal(t, seen+1, shipped.Version)
+
+	// второй оператор видел заказ до отгрузки
+	_, err = svc.CancelOrder(ctx, order.ID, staff, &dto.CancelOrderRequest{Reason: "duplicate", Version: seen})
+	assert.ErrorIs(t, err, domain.ErrOrderVersionMismatch)
+
+	got, err := svc.GetOrder(ctx, order.ID, staff)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+	assert.Equal(t, shipped.Version, got.Version)
+}
+
+func TestStatusChanges_UseServiceClock(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc := newTestService(storage)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+
+	shipped, err := svc.UpdateOrderStatus(ctx, order.ID, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusShipped, Version: order.Version})
+	require.NoError(t, err)
+	require.NotNil(t, shipped.UpdatedAt)
+	assert.Equal(t, now, *shipped.UpdatedAt)
+
+	second, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+
+	cancelled, err := svc.CancelOrder(ctx, second.ID, customer, &dto.CancelOrderRequest{Reason: "changed mind", Version: second.Version})
+	require.NoError(t, err)
+	require.NotNil(t, cancelled.UpdatedAt)
+	assert.Equal(t, now, *cancelled.UpdatedAt)
+}
+
+func TestCreateOrder_SerializationRetryStartsFromPendingOrder(t *testing.T) {
+	ctx := context.Background()
+	storage := &conflictStorage{fakeOrderStorage: &fakeOrderStorage{}, conflicts: 1}
+	svc, inventory, _ := newRetryTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusProcessing, order.Status)
+
+	// вторая попытка записывает заказ в pending версии 1, а не изменённый первой попыткой
+	assert.Equal(t, []int64{1, 1}, storage.versions)
+	assert.Equal(t, 1, inventory.confirms)
+
+	require.Len(t, storage.orders, 1)
+	assert.Equal(t, domain.OrderStatusProcessing, storage.orders[0].Status)
+	assert.Equal(t, order.Version, storage.orders[0].Version)
+	assert.Len(t, storage.outbox, 2)
+}
+
+func TestUpdateOrderStatus_SerializationRetryCapturesOnce(t *testing.T) {
+	ctx := context.Background()
+	storage := &conflictStorage{fakeOrderStorage: &fakeOrderStorage{}}
+	svc, _, provider := newRetryTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+
+	storage.conflicts = 1
+	shipped, err := svc.UpdateOrderStatus(ctx, order.ID, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusShipped, Version: order.Version})
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, shipped.Status)
+	assert.Equal(t, order.Version+1, shipped.Version)
+	assert.Equal(t, 1, provider.captures)
+
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+}
+
+func TestUpdateOrderStatus_CancelDuringCaptureRefundsPayment(t *testing.T) {
+	ctx := context.Background()
+	storage := &conflictStorage{fakeOrderStorage: &fakeOrderStorage{}}
+	svc, inventory, provider := newRetryTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+
+	// заказ отменяют, пока отгрузка списывает оплату: отмена застаёт ещё авторизованную оплату
+	provider.onCapture = func() {
+		provider.onCapture = nil
+		_, err := svc.CancelOrder(ctx, order.ID, staff, &dto.CancelOrderRequest{Reason: "duplicate", Version: order.Version})
+		require.NoError(t, err)
+	}
+
+	_, err = svc.UpdateOrderStatus(ctx, order.ID, &dto.UpdateOrderStatusRequest{Status: domain.OrderStatusShipped, Version: order.Version})
+	assert.ErrorIs(t, err, domain.ErrOrderVersionMismatch)
+
+	got, err := svc.GetOrder(ctx, order.ID, staff)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusCancelled, got.Status)
+
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusRefunded, payment.Status)
+	assert.Equal(t, order.TotalPrice, payment.Refunded)
+
+	assert.Equal(t, int64(100), inventory.InventoryClient.(*MemoryInventoryClient).available(1))
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/carriers"
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/defan6/market/services/order-service/internal/lib/apperror"
+	"github.com/defan6/market/services/order-service/internal/mapper"
+)
+
+// CarrierRegistry перевозчики по имени
+type CarrierRegistry interface {
+	Carrier(name string) (carriers.Carrier, error)
+}
+
+// CreateShipment отправляет позиции заказа через перевозчика. Отправить можно не больше, чем заказано,
+// за вычетом уже отправленного; первое отправление переводит заказ из processing в shipped.
+// Позиции резервируются за отправлением в статусе pending короткой транзакцией, а списание оплаты
+// и создание этикетки идут уже без блокировки заказа (completeShipment).
+func (s *defaultOrderService) CreateShipment(ctx context.Context, orderID int64, actor domain.Actor, request *dto.CreateShipmentRequest) (*dto.ShipmentResponse, error) {
+	const op = "service.CreateShipment"
+
+	carrier, err := s.carriers.Carrier(request.Carrier)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid request: %w", op, err)
+	}
+
+	var pending *domain.Shipment
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// блокировка заказа выстраивает в очередь параллельные отправления одного заказа
+		order, err := s.storage.GetOrderForUpdate(ctx, orderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		if err := checkOrderVersion(order, request.Version); err != nil {
+			return err
+		}
+		if err := checkShippable(order); err != nil {
+			return err
+		}
+
+		previous, err := s.storage.GetOrderShipments(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order shipments: %w", err)
+		}
+
+		items, err := buildShipmentItems(order, request, domain.ShippedQuantities(previous))
+		if err != nil {
+			return err
+		}
+
+		now := s.dbNow()
+		pending = &domain.Shipment{
+			OrderID:   order.ID,
+			Carrier:   carrier.Name(),
+			Status:    domain.ShipmentStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Items:     items,
+		}
+		if err := s.storage.CreateShipment(ctx, pending); err != nil {
+			return fmt.Errorf("failed to create shipment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, version, err := s.completeShipment(ctx, carrier, pending, &actor.UserID)
+	if err != nil {
+		// этикетки нет: освобождаем позиции, чтобы отправление можно было создать заново
+		s.discardShipment(ctx, pending)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("shipment created",
+		slog.Int64("order_id", orderID),
+		slog.Int64("shipment_id", created.ID),
+		slog.String("carrier", created.Carrier),
+		slog.String("tracking_number", created.TrackingNumber),
+	)
+
+	response := mapper.MapToShipmentResponseFromShipment(created)
+	response.OrderVersion = version
+	return response, nil
+}
+
+// completeShipment создаёт этикетку отправления в статусе pending и записывает её; changedBy — кто
+// переводит заказ в shipped (nil для фонового опроса). Возвращает отправление и версию заказа после записи. Провайдер оплаты и перевозчик вызываются вне
+// транзакции, и оба вызова повторно безопасны: списанная оплата не списывается снова, а ключ этикетки
+// зависит от ID отправления, так что повтор получит ту же этикетку.
+func (s *defaultOrderService) completeShipment(ctx context.Context, carrier carriers.Carrier, shipment *domain.Shipment, changedBy *int64) (*domain.Shipment, int64, error) {
+	order, err := s.storage.GetOrder(ctx, shipment.OrderID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get order: %w", err)
+	}
+	if err := checkShippable(order); err != nil {
+		return nil, 0, err
+	}
+
+	// оплата списывается до создания этикетки: при отказе провайдера у перевозчика не остаётся этикетки
+	if order.Status == domain.OrderStatusProcessing {
+		if err := s.capturePayment(ctx, order.ID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	label, err := carrier.CreateLabel(ctx, carriers.LabelRequest{
+		OrderID:        order.ID,
+		Address:        *order.ShippingAddress,
+		Items:          shipmentLabelItems(order, shipment.Items),
+		IdempotencyKey: fmt.Sprintf("order-%d-shipment-%d", order.ID, shipment.ID),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create shipping label: %w", err)
+	}
+
+	var (
+		recorded *domain.Shipment
+		version  int64
+	)
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		order, err := s.storage.GetOrderForUpdate(ctx, shipment.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+		if err := checkShippable(order); err != nil {
+			return err
+		}
+
+		shipments, err := s.storage.GetOrderShipments(ctx, shipment.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order shipments: %w", err)
+		}
+		recorded = findShipment(shipments, shipment.ID)
+		if recorded == nil {
+			return fmt.Errorf("shipment %d not found", shipment.ID)
+		}
+		version = order.Version
+		if recorded.Status != domain.ShipmentStatusPending {
+			// этикетку уже записал параллельный вызов с тем же ключом
+			return nil
+		}
+
+		now := s.dbNow()
+		recorded.TrackingNumber = label.TrackingNumber
+		recorded.LabelURL = label.LabelURL
+		recorded.Status = domain.ShipmentStatusLabelCreated
+		recorded.UpdatedAt = now
+		if err := s.storage.UpdateShipmentLabel(ctx, recorded); err != nil {
+			return fmt.Errorf("failed to update shipment: %w", err)
+		}
+
+		if order.Status == domain.OrderStatusProcessing {
+			history, err := order.TransitionTo(domain.OrderStatusShipped, changedBy, "", now)
+			if err != nil {
+				return err
+			}
+			if err := s.applyStatusChange(ctx, order, history); err != nil {
+				return err
+			}
+			version = order.Version
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return recorded, version, nil
+}
+
+// discardShipment удаляет отправление, для которого не удалось создать этикетку.
+// Ошибка только логируется: такое отправление достроит фоновый опрос.
+func (s *defaultOrderService) discardShipment(ctx context.Context, shipment *domain.Shipment) {
+	if err := s.storage.DeleteShipment(context.WithoutCancel(ctx), shipment.ID); err != nil {
+		s.log.Error("failed to delete pending shipment",
+			slog.Int64("order_id", shipment.OrderID),
+			slog.Int64("shipment_id", shipment.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// CompletePendingShipments достраивает до limit отправлений, оставшихся в pending дольше olderThan,
+// и возвращает, сколько из них получили этикетку. Отправление заказа, который больше нельзя отправить,
+// удаляется; остальные ошибки только логируются, и отправление пробуется снова при следующем опросе.
+func (s *defaultOrderService) CompletePendingShipments(ctx context.Context, olderThan time.Duration, limit int) (int, error) {
+	const op = "service.CompletePendingShipments"
+
+	shipments, err := s.storage.ListPendingShipments(ctx, s.dbNow().Add(-olderThan), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to list shipments: %w", op, err)
+	}
+
+	completed := 0
+	for _, shipment := range shipments {
+		if ctx.Err() != nil {
+			return completed, ctx.Err()
+		}
+
+		carrier, err := s.carriers.Carrier(shipment.Carrier)
+		if err == nil {
+			_, _, err = s.completeShipment(ctx, carrier, shipment, nil)
+		}
+		if err != nil {
+			s.log.Error("failed to complete pending shipment",
+				slog.Int64("order_id", shipment.OrderID),
+				slog.Int64("shipment_id", shipment.ID),
+				slog.String("error", err.Error()),
+			)
+			if errors.Is(err, domain.ErrOrderNotShippable) {
+				s.discardShipment(ctx, shipment)
+			}
+			continue
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+// ListShipments отправления заказа; доступ как к самому заказу
+func (s *defaultOrderService) ListShipments(ctx context.Context, orderID int64, actor domain.Actor) ([]*dto.ShipmentResponse, error) {
+	const op = "service.ListShipments"
+
+	order, err := s.storage.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get order: %w", op, err)
+	}
+
+	if !actor.CanAccessOrder(order) {
+		return nil, fmt.Errorf("%s: %w", op, domain.ErrOrderAccessDenied)
+	}
+
+	shipments, err := s.storage.GetOrderShipments(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get order shipments: %w", op, err)
+	}
+
+	response := make([]*dto.ShipmentResponse, 0, len(shipments))
+	for _, shipment := range shipments {
+		response = append(response, mapper.MapToShipmentResponseFromShipment(shipment))
+	}
+	return response, nil
+}
+
+// RunShipmentTracking до отмены ctx опрашивает перевозчиков о недоставленных отправлениях
+// и достраивает отправления, оставшиеся без этикетки
+func (s *defaultOrderService) RunShipmentTracking(ctx context.Context, cfg config.ShippingConfig) {
+	for {
+		if _, err := s.CompletePendingShipments(ctx, cfg.PendingTimeout, cfg.BatchSize); err != nil && ctx.Err() == nil {
+			s.log.Error("pending shipments completion failed", slog.String("error", err.Error()))
+		}
+		if _, err := s.TrackShipments(ctx, cfg.BatchSize); err != nil && ctx.Err() == nil {
+			s.log.Error("shipment tracking failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.PollInterval):
+		}
+	}
+}
+
+// TrackShipments опрашивает до limit недоставленных отправлений, дольше всех ждущих опроса,
+// и возвращает, у скольких изменился статус. Ошибка по одному отправлению только логируется.
+func (s *defaultOrderService) TrackShipments(ctx context.Context, limit int) (int, error) {
+	const op = "service.TrackShipments"
+
+	shipments, err := s.storage.ListActiveShipments(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to list shipments: %w", op, err)
+	}
+
+	changed := 0
+	for _, shipment := range shipments {
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+
+		ok, err := s.trackShipment(ctx, shipment)
+		if err != nil {
+			s.log.Error("failed to track shipment",
+				slog.Int64("order_id", shipment.OrderID),
+				slog.Int64("shipment_id", shipment.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// trackShipment запрашивает статус у перевозчика и сохраняет его. Время опроса записывается
+// и при ошибке перевозчика, чтобы одно сломанное отправление не задерживало очередь.
+// Когда доставлены все отправления и отправлены все позиции, заказ переходит в delivered.
+func (s *defaultOrderService) trackShipment(ctx context.Context, shipment *domain.Shipment) (bool, error) {
+	carrier, err := s.carriers.Carrier(shipment.Carrier)
+	if err != nil {
+		return false, err
+	}
+
+	// перевозчик опрашивается вне транзакции, чтобы не держать блокировку заказа на время запроса
+	tracking, trackErr := carrier.Track(ctx, shipment.TrackingNumber)
+
+	var changed bool
+	var statusErr error
+
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// повтор транзакции начинает с ответа перевозчика, а не с результата прошлой попытки
+		changed, statusErr = false, trackErr
+
+		order, err := s.storage.GetOrderForUpdate(ctx, shipment.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		shipments, err := s.storage.GetOrderShipments(ctx, shipment.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order shipments: %w", err)
+		}
+
+		current := findShipment(shipments, shipment.ID)
+		if current == nil {
+			return fmt.Errorf("shipment %d not found", shipment.ID)
+		}
+
+		now := s.dbNow()
+		current.TrackedAt = &now
+		if statusErr == nil {
+			changed, statusErr = current.Advance(tracking.Status, tracking.UpdatedAt.UTC().Truncate(time.Microsecond))
+		}
+
+		if err := s.storage.UpdateShipmentTracking(ctx, current); err != nil {
+			return fmt.Errorf("failed to update shipment: %w", err)
+		}
+
+		if !changed || order.Status != domain.OrderStatusShipped || !domain.OrderDelivered(order, shipments) {
+			return nil
+		}
+
+		// системный переход: changedBy не задан
+		history, err := order.TransitionTo(domain.OrderStatusDelivered, nil, "all shipments delivered", now)
+		if err != nil {
+			return err
+		}
+		if err := s.applyStatusChange(ctx, order, history); err != nil {
+			return err
+		}
+
+		s.log.Info("order delivered", slog.Int64("order_id", order.ID))
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if statusErr != nil {
+		return changed, fmt.Errorf("failed to track shipment: %w", statusErr)
+	}
+	return changed, nil
+}
+
+// checkNoPendingShipment заказ нельзя отменить, пока для его отправления создаётся этикетка:
+// оплата к этому моменту уже может быть списана, а этикетка — создана у перевозчика
+func (s *defaultOrderService) checkNoPendingShipment(ctx context.Context, orderID int64) error {
+	shipments, err := s.storage.GetOrderShipments(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order shipments: %w", err)
+	}
+	if domain.HasPendingShipment(shipments) {
+		return domain.ErrShipmentInProgress.WithFields(
+			apperror.Field("status", "wait until the shipment gets its label"),
+		)
+	}
+	return nil
+}
+
+// checkShippable отправлять можно заказ в processing или shipped с адресом доставки
+func checkShippable(order *domain.Order) error {
+	if order.Status != domain.OrderStatusProcessing && order.Status != domain.OrderStatusShipped {
+		return domain.ErrOrderNotShippable.WithFields(
+			apperror.Field("status", fmt.Sprintf("order is %s", order.Status)),
+		)
+	}
+	if order.ShippingAddress == nil {
+		return domain.ErrOrderNotShippable.WithFields(
+			apperror.Field("shipping_address", "order has no shipping address"),
+		)
+	}
+	return nil
+}
+
+func findShipment(shipments []*domain.Shipment, id int64) *domain.Shipment {
+	for _, shipment := range shipments {
+		if shipment.ID == id {
+			return shipment
+		}
+	}
+	return nil
+}
+
+// buildShipmentItems проверяет запрос отправления по позициям заказа; shipped — уже отправленные
+// количества по ID позиции
+func buildShipmentItems(order *domain.Order, request *dto.CreateShipmentRequest, shipped map[int64]int64) ([]*domain.ShipmentItem, error) {
+	if len(request.Items) == 0 {
+		return nil, domain.ErrInvalidShipmentItems.WithFields(apperror.Field("items", "must contain at least one item"))
+	}
+
+	ordered := make(map[int64]*domain.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		ordered[item.ID] = item
+	}
+
+	items := make([]*domain.ShipmentItem, 0, len(request.Items))
+	seen := make(map[int64]struct{}, len(request.Items))
+
+	for i, req := range request.Items {
+		orderItem, ok := ordered[req.OrderItemID]
+		if !ok {
+			return nil, domain.ErrInvalidShipmentItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].order_item_id", i), fmt.Sprintf("item %d is not in order %d", req.OrderItemID, order.ID),
+			))
+		}
+		if _, dup := seen[req.OrderItemID]; dup {
+			return nil, domain.ErrInvalidShipmentItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].order_item_id", i), fmt.Sprintf("item %d is listed more than once", req.OrderItemID),
+			))
+		}
+		seen[req.OrderItemID] = struct{}{}
+
+		if req.Quantity <= 0 {
+			return nil, domain.ErrInvalidShipmentItems.WithFields(apperror.Field(fmt.Sprintf("items[%d].quantity", i), "must be positive"))
+		}
+		if left := orderItem.Quantity - shipped[req.OrderItemID]; req.Quantity > left {
+			return nil, domain.ErrInvalidShipmentItems.WithFields(apperror.Field(
+				fmt.Sprintf("items[%d].quantity", i),
+				fmt.Sprintf("item %d: ordered %d, already shipped %d, requested %d",
+					req.OrderItemID, orderItem.Quantity, shipped[req.OrderItemID], req.Quantity),
+			))
+		}
+
+		items = append(items, &domain.ShipmentItem{OrderItemID: req.OrderItemID, Quantity: req.Quantity})
+	}
+
+	return items, nil
+}
+
+// shipmentLabelItems позиции отправления в том виде, в котором они нужны перевозчику
+func shipmentLabelItems(order *domain.Order, items []*domain.ShipmentItem) []carriers.LabelItem {
+	ordered := make(map[int64]*domain.OrderItem, len(order.Items))
+	for _, item := range order.Items {
+		ordered[item.ID] = item
+	}
+
+	labelItems := make([]carriers.LabelItem, 0, len(items))
+	for _, item := range items {
+		orderItem := ordered[item.OrderItemID]
+		labelItems = append(labelItems, carriers.LabelItem{
+			ProductID: orderItem.ProductID,
+			Name:      orderItem.Name,
+			Quantity:  item.Quantity,
+		})
+	}
+	return labelItems
+}
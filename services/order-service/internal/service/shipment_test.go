@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/carriers"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCarrier выдаёт трек-номера по порядку и отвечает на Track статусом из statuses
+type fakeCarrier struct {
+	labels   []carriers.LabelRequest
+	statuses map[string]string
+	labelErr error
+	trackErr error
+	// onLabel вызывается до создания этикетки
+	onLabel func()
+}
+
+func newFakeCarrier() *fakeCarrier {
+	return &fakeCarrier{statuses: make(map[string]string)}
+}
+
+func (c *fakeCarrier) Name() string {
+	return "fake"
+}
+
+func (c *fakeCarrier) CreateLabel(ctx context.Context, req carriers.LabelRequest) (*carriers.Label, error) {
+	if c.onLabel != nil {
+		c.onLabel()
+	}
+	if c.labelErr != nil {
+		return nil, c.labelErr
+	}
+	c.labels = append(c.labels, req)
+	number := fmt.Sprintf("TRK-%d", len(c.labels))
+	c.statuses[number] = domain.ShipmentStatusLabelCreated
+	return &carriers.Label{TrackingNumber: number, LabelURL: "https://labels.invalid/" + number}, nil
+}
+
+func (c *fakeCarrier) Track(ctx context.Context, trackingNumber string) (*carriers.Tracking, error) {
+	if c.trackErr != nil {
+		return nil, c.trackErr
+	}
+	return &carriers.Tracking{Status: c.statuses[trackingNumber], UpdatedAt: time.Now()}, nil
+}
+
+func newTestCarriers(carrier carriers.Carrier) *carriers.Registry {
+	registry, err := carriers.NewRegistry(carrier)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func newShipmentTestService(storage *fakeOrderStorage) (*defaultOrderService, *fakeCarrier) {
+	carrier := newFakeCarrier()
+	svc := newTestService(storage)
+	svc.carriers = newTestCarriers(carrier)
+	return svc, carrier
+}
+
+// shipItems запрос отправления с текущей версией заказа, как её передал бы клиент в If-Match
+func shipItems(storage *fakeOrderStorage, orderID, orderItemID, quantity int64) *dto.CreateShipmentRequest {
+	request := &dto.CreateShipmentRequest{
+		Carrier: "fake",
+		Items:   []*dto.ShipmentItemRequest{{OrderItemID: orderItemID, Quantity: quantity}},
+	}
+	if order, err := storage.GetOrder(context.Background(), orderID); err == nil {
+		request.Version = order.Version
+	}
+	return request
+}
+
+func TestShipments_OrderDeliveredWhenAllItemsDelivered(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+	itemID := order.Items[0].ID
+
+	first, err := svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, itemID, 1))
+	require.NoError(t, err)
+	assert.Equal(t, order.Version+1, first.OrderVersion)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, first.Status)
+	assert.Equal(t, "TRK-1", first.TrackingNumber)
+	require.Len(t, carrier.labels, 1)
+	assert.Equal(t, "Москва", carrier.labels[0].Address.City)
+
+	// первое отправление переводит заказ в shipped и списывает оплату
+	got, err := svc.GetOrder(ctx, order.ID, customer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+
+	// первое отправление доставлено, но одна единица ещё не отправлена
+	carrier.statuses[first.TrackingNumber] = domain.ShipmentStatusDelivered
+	changed, err := svc.TrackShipments(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	got, err = svc.GetOrder(ctx, order.ID, customer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+
+	second, err := svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, itemID, 1))
+	require.NoError(t, err)
+
+	carrier.statuses[second.TrackingNumber] = domain.ShipmentStatusInTransit
+	_, err = svc.TrackShipments(ctx, 10)
+	require.NoError(t, err)
+	got, err = svc.GetOrder(ctx, order.ID, customer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+
+	carrier.statuses[second.TrackingNumber] = domain.ShipmentStatusDelivered
+	_, err = svc.TrackShipments(ctx, 10)
+	require.NoError(t, err)
+	got, err = svc.GetOrder(ctx, order.ID, customer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusDelivered, got.Status)
+
+	shipments, err := svc.ListShipments(ctx, order.ID, customer)
+	require.NoError(t, err)
+	require.Len(t, shipments, 2)
+	for _, shipment := range shipments {
+		assert.Equal(t, domain.ShipmentStatusDelivered, shipment.Status)
+		assert.NotNil(t, shipment.DeliveredAt)
+	}
+}
+
+func TestCreateShipment_Rejected(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+	itemID := order.Items[0].ID
+
+	tests := []struct {
+		name    string
+		request *dto.CreateShipmentRequest
+		wantErr error
+	}{
+		{"unknown carrier", &dto.CreateShipmentRequest{Carrier: "post", Items: shipItems(storage, order.ID, itemID, 1).Items, Version: order.Version}, carriers.ErrUnknownCarrier},
+		{"stale version", &dto.CreateShipmentRequest{Carrier: "fake", Items: shipItems(storage, order.ID, itemID, 1).Items, Version: order.Version - 1}, domain.ErrOrderVersionMismatch},
+		{"no items", &dto.CreateShipmentRequest{Carrier: "fake", Version: order.Version}, domain.ErrInvalidShipmentItems},
+		{"unknown item", shipItems(storage, order.ID, 999, 1), domain.ErrInvalidShipmentItems},
+		{"more than ordered", shipItems(storage, order.ID, itemID, 3), domain.ErrInvalidShipmentItems},
+		{"zero quantity", shipItems(storage, order.ID, itemID, 0), domain.ErrInvalidShipmentItems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateShipment(ctx, order.ID, staff, tt.request)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	assert.Empty(t, carrier.labels)
+
+	_, err = svc.CancelOrder(ctx, order.ID, customer, &dto.CancelOrderRequest{Reason: "changed mind", Version: order.Version})
+	require.NoError(t, err)
+	_, err = svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, itemID, 1))
+	assert.ErrorIs(t, err, domain.ErrOrderNotShippable)
+}
+
+func TestTrackShipments_CarrierErrorStillRecordsPoll(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+	_, err = svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, order.Items[0].ID, 1))
+	require.NoError(t, err)
+
+	carrier.trackErr = errors.New("carrier unavailable")
+	changed, err := svc.TrackShipments(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, changed)
+
+	require.Len(t, storage.shipments, 1)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, storage.shipments[0].Status)
+	assert.NotNil(t, storage.shipments[0].TrackedAt)
+}
+
+func TestCreateShipment_LabelErrorReleasesItems(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+	itemID := order.Items[0].ID
+
+	carrier.labelErr = errors.New("carrier unavailable")
+	_, err = svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, itemID, 2))
+	require.Error(t, err)
+
+	shipments, err := storage.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Empty(t, shipments)
+
+	// позиции отправления без этикетки снова можно отправить целиком
+	carrier.labelErr = nil
+	created, err := svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, itemID, 2))
+	require.NoError(t, err)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, created.Status)
+	assert.Equal(t, fmt.Sprintf("order-%d-shipment-%d", order.ID, created.ID), carrier.labels[0].IdempotencyKey)
+}
+
+func TestCreateShipment_CancelWhileLabelIsCreated(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+	// отклонённая отмена должна откатить заказ, изменённый на месте
+	svc.txManager = retryingTxManager{storage: storage, maxAttempts: 1}
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(1))
+	require.NoError(t, err)
+
+	// отмена приходит, когда оплата уже списана, а этикетка ещё создаётся
+	var cancelErr error
+	carrier.onLabel = func() {
+		_, cancelErr = svc.CancelOrder(ctx, order.ID, staff, &dto.CancelOrderRequest{Reason: "changed mind", Version: order.Version})
+	}
+
+	_, err = svc.CreateShipment(ctx, order.ID, staff, shipItems(storage, order.ID, order.Items[0].ID, 1))
+	require.NoError(t, err)
+	assert.ErrorIs(t, cancelErr, domain.ErrShipmentInProgress)
+
+	got, err := storage.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, got.Status)
+	payment, err := storage.GetLatestPayment(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCaptured, payment.Status)
+	shipments, err := storage.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, shipments[0].Status)
+}
+
+func TestCompletePendingShipments(t *testing.T) {
+	ctx := context.Background()
+	storage := &fakeOrderStorage{}
+	svc, carrier := newShipmentTestService(storage)
+
+	order, err := svc.CreateOrder(ctx, newCreateOrderRequest(2))
+	require.NoError(t, err)
+
+	// процесс упал между резервом позиций и созданием этикетки
+	createdAt := time.Now().Add(-time.Hour)
+	require.NoError(t, storage.CreateShipment(ctx, &domain.Shipment{
+		OrderID:   order.ID,
+		Carrier:   "fake",
+		Status:    domain.ShipmentStatusPending,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Items:     []*domain.ShipmentItem{{OrderItemID: order.Items[0].ID, Quantity: 1}},
+	}))
+
+	completed, err := svc.CompletePendingShipments(ctx, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	require.Len(t, carrier.labels, 1)
+
+	shipments, err := storage.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, shipments[0].Status)
+	assert.Equal(t, "TRK-1", shipments[0].TrackingNumber)
+
+	updated, err := storage.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusShipped, updated.Status)
+
+	// уже достроенное отправление повторно не трогаем
+	completed, err = svc.CompletePendingShipments(ctx, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Zero(t, completed)
+}
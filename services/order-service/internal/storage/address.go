@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+const addressColumns = `id, user_id, label, address, created_at, updated_at`
+
+func (r *defaultOrderStorage) CreateAddress(ctx context.Context, address *domain.SavedAddress) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO addresses (user_id, label, address, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+		address.UserID, address.Label, address.Address, address.CreatedAt, address.UpdatedAt,
+	).Scan(&address.ID)
+}
+
+// GetUserAddresses адресная книга пользователя в порядке добавления
+func (r *defaultOrderStorage) GetUserAddresses(ctx context.Context, userID int64) ([]*domain.SavedAddress, error) {
+	var addresses []*domain.SavedAddress
+	err := querierExec(ctx, r.db).SelectContext(ctx, &addresses,
+		`SELECT `+addressColumns+` FROM addresses WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	return addresses, err
+}
+
+// GetAddress адрес пользователя; sql.ErrNoRows, если его нет или он принадлежит другому пользователю
+func (r *defaultOrderStorage) GetAddress(ctx context.Context, userID, id int64) (*domain.SavedAddress, error) {
+	address := &domain.SavedAddress{}
+	err := querierExec(ctx, r.db).GetContext(ctx, address,
+		`SELECT `+addressColumns+` FROM addresses WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// UpdateAddress sql.ErrNoRows, если адреса нет у пользователя
+func (r *defaultOrderStorage) UpdateAddress(ctx context.Context, address *domain.SavedAddress) error {
+	res, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE addresses SET label = $1, address = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		address.Label, address.Address, address.UpdatedAt, address.ID, address.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// DeleteAddress sql.ErrNoRows, если адреса нет у пользователя. Заказы хранят копию адреса и не меняются.
+func (r *defaultOrderStorage) DeleteAddress(ctx context.Context, userID, id int64) error {
+	res, err := executor(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM addresses WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// requireAffected sql.ErrNoRows, если запрос не затронул ни одной строки
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
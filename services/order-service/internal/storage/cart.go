@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// GetCartItems корзина пользователя в порядке добавления товаров
+func (r *defaultOrderStorage) GetCartItems(ctx context.Context, userID int64) ([]*domain.CartItem, error) {
+	var items []*domain.CartItem
+	err := querierExec(ctx, r.db).SelectContext(ctx, &items,
+		`SELECT user_id, product_id, quantity, price, created_at, updated_at
+		 FROM cart_items WHERE user_id = $1 ORDER BY created_at, product_id`,
+		userID,
+	)
+	return items, err
+}
+
+// SaveCartItem добавляет товар в корзину или заменяет количество и цену уже добавленного;
+// created_at сохраняется от первого добавления
+func (r *defaultOrderStorage) SaveCartItem(ctx context.Context, item *domain.CartItem) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO cart_items (user_id, product_id, quantity, price, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6)
+		 ON CONFLICT (user_id, product_id)
+		 DO UPDATE SET quantity = EXCLUDED.quantity, price = EXCLUDED.price, updated_at = EXCLUDED.updated_at`,
+		item.UserID, item.ProductID, item.Quantity, item.Price, item.CreatedAt, item.UpdatedAt,
+	)
+	return err
+}
+
+// DeleteCartItem убирает товар из корзины; sql.ErrNoRows, если его там нет
+func (r *defaultOrderStorage) DeleteCartItem(ctx context.Context, userID, productID int64) error {
+	res, err := executor(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`,
+		userID, productID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// ClearCart убирает из корзины товары, не менявшиеся после upTo: изменённые параллельно
+// с оформлением заказа остаются в корзине
+func (r *defaultOrderStorage) ClearCart(ctx context.Context, userID int64, upTo time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM cart_items WHERE user_id = $1 AND updated_at <= $2`,
+		userID, upTo,
+	)
+	return err
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+// GetIdempotencyKey возвращает действующий на момент now ключ или sql.ErrNoRows
+func (r *defaultOrderStorage) GetIdempotencyKey(ctx context.Context, userID int64, key string, now time.Time) (*domain.IdempotencyKey, error) {
+	record := &domain.IdempotencyKey{}
+	err := querierExec(ctx, r.db).GetContext(ctx, record,
+		`SELECT user_id, key, request_hash, order_id, status_code, response_body, created_at, expires_at
+		 FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at > $3`,
+		userID, key, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// SaveIdempotencyKey сохраняет ключ, заменяя просроченную запись с тем же ключом.
+// Если действующий ключ уже есть (в том числе сохранён параллельной транзакцией), возвращает sql.ErrNoRows.
+func (r *defaultOrderStorage) SaveIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO idempotency_keys
+		     (user_id, key, request_hash, order_id, status_code, response_body, created_at, expires_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		 ON CONFLICT (user_id, key) DO UPDATE SET
+		     request_hash = EXCLUDED.request_hash,
+		     order_id = EXCLUDED.order_id,
+		     status_code = EXCLUDED.status_code,
+		     response_body = EXCLUDED.response_body,
+		     created_at = EXCLUDED.created_at,
+		     expires_at = EXCLUDED.expires_at
+		 WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		 RETURNING created_at`,
+		record.UserID, record.Key, record.RequestHash, record.OrderID,
+		record.StatusCode, record.ResponseBody, record.CreatedAt, record.ExpiresAt,
+	).Scan(&record.CreatedAt)
+}
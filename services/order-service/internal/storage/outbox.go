@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+func (r *defaultOrderStorage) CreateOutboxMessage(ctx context.Context, msg *domain.OutboxMessage) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, created_at)
+		 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+		msg.AggregateType, msg.AggregateID, msg.EventType, msg.Payload, msg.CreatedAt,
+	).Scan(&msg.ID)
+}
+
+// FetchUnpublishedOutbox блокирует до limit неопубликованных событий, которым пора отправляться,
+// в порядке записи. От каждого агрегата выбирается только самое раннее неопубликованное живое событие:
+// следующее не выбирается, даже если раннее ждёт повтора или его уже заблокировал другой экземпляр
+// релея, поэтому события агрегата не обгоняют друг друга. SKIP LOCKED позволяет нескольким
+// экземплярам релея работать параллельно, не публикуя одно и то же.
+func (r *defaultOrderStorage) FetchUnpublishedOutbox(ctx context.Context, limit int, now time.Time) ([]*domain.OutboxMessage, error) {
+	var messages []*domain.OutboxMessage
+	err := querierExec(ctx, r.db).SelectContext(ctx, &messages,
+		`SELECT o.id, o.aggregate_type, o.aggregate_id, o.event_type, o.payload, o.created_at,
+		        o.published_at, o.attempts, o.last_error, o.next_attempt_at, o.dead_at
+		 FROM outbox o
+		 WHERE o.published_at IS NULL AND o.dead_at IS NULL
+		   AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= $2)
+		   AND NOT EXISTS (
+		       SELECT 1 FROM outbox e
+		       WHERE e.aggregate_type = o.aggregate_type AND e.aggregate_id = o.aggregate_id AND e.id < o.id
+		         AND e.published_at IS NULL AND e.dead_at IS NULL
+		   )
+		 ORDER BY o.id LIMIT $1 FOR UPDATE OF o SKIP LOCKED`,
+		limit, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *defaultOrderStorage) MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE outbox SET published_at = $1, attempts = attempts + 1, last_error = NULL WHERE id = ANY($2)`,
+		at, pq.Array(ids),
+	)
+	return err
+}
+
+// MarkOutboxFailed записывает неудачную попытку; следующая — не раньше retryAt
+func (r *defaultOrderStorage) MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2 WHERE id = $3`,
+		reason, retryAt, id,
+	)
+	return err
+}
+
+// MarkOutboxDead записывает последнюю неудачную попытку: событие больше не отправляется
+func (r *defaultOrderStorage) MarkOutboxDead(ctx context.Context, id int64, reason string, at time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = NULL, dead_at = $2 WHERE id = $3`,
+		reason, at, id,
+	)
+	return err
+}
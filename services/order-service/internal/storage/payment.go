@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+const paymentColumns = `id, order_id, attempt, provider, method, amount, refunded, status,
+	authorization_id, last_error, created_at, updated_at`
+
+// CreatePayment записывает новую попытку оплаты со следующим номером для заказа
+func (r *defaultOrderStorage) CreatePayment(ctx context.Context, payment *domain.Payment) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO payments (order_id, attempt, provider, method, amount, refunded, status,
+		                       authorization_id, last_error, created_at, updated_at)
+		 VALUES ($1, COALESCE((SELECT MAX(attempt) FROM payments WHERE order_id = $1), 0) + 1,
+		         $2,$3,$4,$5,$6,$7,$8,$9,$10)
+		 RETURNING id, attempt`,
+		payment.OrderID, payment.Provider, payment.Method, payment.Amount, payment.Refunded, payment.Status,
+		payment.AuthorizationID, payment.LastError, payment.CreatedAt, payment.UpdatedAt,
+	).Scan(&payment.ID, &payment.Attempt)
+}
+
+func (r *defaultOrderStorage) UpdatePayment(ctx context.Context, payment *domain.Payment) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE payments SET status = $1, refunded = $2, authorization_id = $3, last_error = $4, updated_at = $5
+		 WHERE id = $6`,
+		payment.Status, payment.Refunded, payment.AuthorizationID, payment.LastError, payment.UpdatedAt, payment.ID,
+	)
+	return err
+}
+
+// GetLatestPayment последняя попытка оплаты заказа; sql.ErrNoRows, если оплаты не было
+func (r *defaultOrderStorage) GetLatestPayment(ctx context.Context, orderID int64) (*domain.Payment, error) {
+	payment := &domain.Payment{}
+	err := querierExec(ctx, r.db).GetContext(ctx, payment,
+		`SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY attempt DESC LIMIT 1`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+const promoCodeColumns = `id, code, kind, rate, amount, min_order_total, product_ids, starts_at, ends_at,
+	max_uses, max_uses_per_user, uses, created_at`
+
+// promoCodeRow product_ids хранится массивом Postgres
+type promoCodeRow struct {
+	domain.PromoCode
+	ProductIDs pq.Int64Array `db:"product_ids"`
+}
+
+// GetPromoCode код по значению; sql.ErrNoRows, если такого кода нет
+func (r *defaultOrderStorage) GetPromoCode(ctx context.Context, code string) (*domain.PromoCode, error) {
+	return r.getPromoCode(ctx, code, "")
+}
+
+// GetPromoCodeForUpdate блокирует строку кода до конца транзакции: погашения кода выстраиваются
+// в очередь, и лимиты не превышаются параллельными заказами
+func (r *defaultOrderStorage) GetPromoCodeForUpdate(ctx context.Context, code string) (*domain.PromoCode, error) {
+	return r.getPromoCode(ctx, code, " FOR UPDATE")
+}
+
+func (r *defaultOrderStorage) getPromoCode(ctx context.Context, code, lock string) (*domain.PromoCode, error) {
+	row := &promoCodeRow{}
+	err := querierExec(ctx, r.db).GetContext(ctx, row,
+		`SELECT `+promoCodeColumns+` FROM promo_codes WHERE code = $1`+lock,
+		code,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	promo := row.PromoCode
+	promo.ProductIDs = row.ProductIDs
+	return &promo, nil
+}
+
+// CountPromoRedemptions сколько раз пользователь погасил код
+func (r *defaultOrderStorage) CountPromoRedemptions(ctx context.Context, promoCodeID, userID int64) (int64, error) {
+	var count int64
+	err := querierExec(ctx, r.db).GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM promo_redemptions WHERE promo_code_id = $1 AND user_id = $2`,
+		promoCodeID, userID,
+	)
+	return count, err
+}
+
+// RedeemPromoCode записывает погашение и увеличивает счётчик использований кода
+func (r *defaultOrderStorage) RedeemPromoCode(ctx context.Context, redemption *domain.PromoRedemption) error {
+	err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO promo_redemptions (promo_code_id, order_id, user_id, discount, created_at)
+		 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+		redemption.PromoCodeID, redemption.OrderID, redemption.UserID, redemption.Discount, redemption.CreatedAt,
+	).Scan(&redemption.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE promo_codes SET uses = uses + 1 WHERE id = $1`,
+		redemption.PromoCodeID,
+	)
+	return err
+}
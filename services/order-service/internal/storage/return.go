@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+const returnColumns = `id, order_id, status, reason, requested_by, items_amount, tax_amount, shipping_amount,
+	total_amount, created_at, updated_at`
+
+// CreateReturn записывает возврат вместе с позициями
+func (r *defaultOrderStorage) CreateReturn(ctx context.Context, ret *domain.Return) error {
+	err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO returns (order_id, status, reason, requested_by, items_amount, tax_amount, shipping_amount,
+		                      total_amount, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`,
+		ret.OrderID, ret.Status, ret.Reason, ret.RequestedBy, ret.ItemsAmount, ret.TaxAmount, ret.ShippingAmount,
+		ret.TotalAmount, ret.CreatedAt, ret.UpdatedAt,
+	).Scan(&ret.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range ret.Items {
+		item.ReturnID = ret.ID
+		err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+			`INSERT INTO return_items (return_id, order_item_id, quantity, amount)
+			 VALUES ($1,$2,$3,$4) RETURNING id`,
+			item.ReturnID, item.OrderItemID, item.Quantity, item.Amount,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOrderReturns возвраты заказа с позициями в порядке создания
+func (r *defaultOrderStorage) GetOrderReturns(ctx context.Context, orderID int64) ([]*domain.Return, error) {
+	var returns []*domain.Return
+	err := querierExec(ctx, r.db).SelectContext(ctx, &returns,
+		`SELECT `+returnColumns+` FROM returns WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadReturnItems(ctx, returns...); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// GetReturnForUpdate блокирует возврат заказа до конца транзакции; sql.ErrNoRows, если у заказа его нет
+func (r *defaultOrderStorage) GetReturnForUpdate(ctx context.Context, orderID, returnID int64) (*domain.Return, error) {
+	ret := &domain.Return{}
+	err := querierExec(ctx, r.db).GetContext(ctx, ret,
+		`SELECT `+returnColumns+` FROM returns WHERE id = $1 AND order_id = $2 FOR UPDATE`,
+		returnID, orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadReturnItems(ctx, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (r *defaultOrderStorage) UpdateReturnStatus(ctx context.Context, ret *domain.Return) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE returns SET status = $1, updated_at = $2 WHERE id = $3`,
+		ret.Status, ret.UpdatedAt, ret.ID,
+	)
+	return err
+}
+
+// loadReturnItems заполняет Items у переданных возвратов одним запросом
+func (r *defaultOrderStorage) loadReturnItems(ctx context.Context, returns ...*domain.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(returns))
+	byID := make(map[int64]*domain.Return, len(returns))
+	for _, ret := range returns {
+		ids = append(ids, ret.ID)
+		byID[ret.ID] = ret
+	}
+
+	var items []*domain.ReturnItem
+	err := querierExec(ctx, r.db).SelectContext(ctx, &items,
+		`SELECT id, return_id, order_item_id, quantity, amount
+		 FROM return_items WHERE return_id = ANY($1) ORDER BY return_id, id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		ret := byID[item.ReturnID]
+		ret.Items = append(ret.Items, item)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+)
+
+const sagaColumns = `id, name, status, step, data, last_error, created_at, updated_at`
+
+func (r *defaultOrderStorage) CreateSaga(ctx context.Context, saga *domain.Saga) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO sagas (name, status, step, data, last_error, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
+		saga.Name, saga.Status, saga.Step, saga.Data, saga.LastError, saga.CreatedAt, saga.UpdatedAt,
+	).Scan(&saga.ID)
+}
+
+func (r *defaultOrderStorage) UpdateSaga(ctx context.Context, saga *domain.Saga) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE sagas SET status = $1, step = $2, data = $3, last_error = $4, updated_at = $5 WHERE id = $6`,
+		saga.Status, saga.Step, saga.Data, saga.LastError, saga.UpdatedAt, saga.ID,
+	)
+	return err
+}
+
+// ListStaleSagas незавершённые саги, которые не обновлялись с before: их процесс, скорее всего, упал
+func (r *defaultOrderStorage) ListStaleSagas(ctx context.Context, name string, before time.Time, limit int) ([]*domain.Saga, error) {
+	var sagas []*domain.Saga
+	err := querierExec(ctx, r.db).SelectContext(ctx, &sagas,
+		`SELECT `+sagaColumns+` FROM sagas
+		 WHERE name = $1 AND status IN ($2, $3) AND updated_at < $4
+		 ORDER BY updated_at LIMIT $5`,
+		name, domain.SagaStatusRunning, domain.SagaStatusCompensating, before, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
+
+// ClaimSaga забирает зависшую сагу себе, сдвигая updated_at. Условие на прежний updated_at
+// не даёт двум экземплярам сервиса восстанавливать одну и ту же сагу.
+func (r *defaultOrderStorage) ClaimSaga(ctx context.Context, saga *domain.Saga, at time.Time) (bool, error) {
+	res, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE sagas SET updated_at = $1 WHERE id = $2 AND updated_at = $3`,
+		at, saga.ID, saga.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 1 {
+		saga.UpdatedAt = at
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+const shipmentColumns = `id, order_id, carrier, tracking_number, label_url, status, created_at, updated_at,
+	delivered_at, tracked_at`
+
+// CreateShipment записывает отправление вместе с позициями
+func (r *defaultOrderStorage) CreateShipment(ctx context.Context, shipment *domain.Shipment) error {
+	err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO shipments (order_id, carrier, tracking_number, label_url, status, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
+		shipment.OrderID, shipment.Carrier, shipment.TrackingNumber, shipment.LabelURL, shipment.Status,
+		shipment.CreatedAt, shipment.UpdatedAt,
+	).Scan(&shipment.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range shipment.Items {
+		item.ShipmentID = shipment.ID
+		err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+			`INSERT INTO shipment_items (shipment_id, order_item_id, quantity)
+			 VALUES ($1,$2,$3) RETURNING id`,
+			item.ShipmentID, item.OrderItemID, item.Quantity,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOrderShipments отправления заказа с позициями в порядке создания
+func (r *defaultOrderStorage) GetOrderShipments(ctx context.Context, orderID int64) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	err := querierExec(ctx, r.db).SelectContext(ctx, &shipments,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadShipmentItems(ctx, shipments...); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// ListActiveShipments недоставленные отправления с этикеткой, дольше всех не опрашивавшиеся, без позиций
+func (r *defaultOrderStorage) ListActiveShipments(ctx context.Context, limit int) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	err := querierExec(ctx, r.db).SelectContext(ctx, &shipments,
+		`SELECT `+shipmentColumns+` FROM shipments
+		 WHERE status <> $1 AND status <> $2
+		 ORDER BY tracked_at NULLS FIRST, id
+		 LIMIT $3`,
+		domain.ShipmentStatusDelivered, domain.ShipmentStatusPending, limit,
+	)
+	return shipments, err
+}
+
+// ListPendingShipments отправления, оставшиеся в pending с момента до createdBefore, с позициями
+func (r *defaultOrderStorage) ListPendingShipments(ctx context.Context, createdBefore time.Time, limit int) ([]*domain.Shipment, error) {
+	var shipments []*domain.Shipment
+	err := querierExec(ctx, r.db).SelectContext(ctx, &shipments,
+		`SELECT `+shipmentColumns+` FROM shipments
+		 WHERE status = $1 AND created_at < $2
+		 ORDER BY created_at, id
+		 LIMIT $3`,
+		domain.ShipmentStatusPending, createdBefore, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadShipmentItems(ctx, shipments...); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// UpdateShipmentLabel записывает этикетку отправления вместе с его новым статусом
+func (r *defaultOrderStorage) UpdateShipmentLabel(ctx context.Context, shipment *domain.Shipment) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE shipments SET tracking_number = $1, label_url = $2, status = $3, updated_at = $4 WHERE id = $5`,
+		shipment.TrackingNumber, shipment.LabelURL, shipment.Status, shipment.UpdatedAt, shipment.ID,
+	)
+	return err
+}
+
+// DeleteShipment удаляет отправление вместе с позициями
+func (r *defaultOrderStorage) DeleteShipment(ctx context.Context, id int64) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `DELETE FROM shipments WHERE id = $1`, id)
+	return err
+}
+
+// UpdateShipmentTracking сохраняет статус отправления и время последнего опроса перевозчика
+func (r *defaultOrderStorage) UpdateShipmentTracking(ctx context.Context, shipment *domain.Shipment) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE shipments SET status = $1, updated_at = $2, delivered_at = $3, tracked_at = $4 WHERE id = $5`,
+		shipment.Status, shipment.UpdatedAt, shipment.DeliveredAt, shipment.TrackedAt, shipment.ID,
+	)
+	return err
+}
+
+// loadShipmentItems заполняет Items у переданных отправлений одним запросом
+func (r *defaultOrderStorage) loadShipmentItems(ctx context.Context, shipments ...*domain.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(shipments))
+	byID := make(map[int64]*domain.Shipment, len(shipments))
+	for _, shipment := range shipments {
+		ids = append(ids, shipment.ID)
+		byID[shipment.ID] = shipment
+	}
+
+	var items []*domain.ShipmentItem
+	err := querierExec(ctx, r.db).SelectContext(ctx, &items,
+		`SELECT id, shipment_id, order_item_id, quantity
+		 FROM shipment_items WHERE shipment_id = ANY($1) ORDER BY shipment_id, id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		shipment := byID[item.ShipmentID]
+		shipment.Items = append(shipment.Items, item)
+	}
+	return nil
+}
@@ -3,11 +3,19 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/defan6/market/services/order-service/internal/domain"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+const orderColumns = `id, payment_method, discount_price, tax_price, shipping_price, total_price, user_id, status,
+	created_at, updated_at, cancellation_reason, promo_code, shipping_address, billing_address, version`
+
+const orderItemColumns = `id, order_id, product_id, quantity, price, name, COALESCE(image, '') AS image, tax_rule`
+
 type defaultOrderStorage struct {
 	db *sqlx.DB
 }
@@ -24,6 +32,8 @@ type execer interface {
 
 type querier interface {
 	QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
 }
 
 func executor(ctx context.Context, db *sqlx.DB) execer {
@@ -40,23 +50,102 @@ func querierExec(ctx context.Context, db *sqlx.DB) querier {
 	return db
 }
 
+// NextOrderID выделяет ID заказа заранее, до записи заказа: под него резервируются остатки
+func (r *defaultOrderStorage) NextOrderID(ctx context.Context) (int64, error) {
+	var id int64
+	err := querierExec(ctx, r.db).GetContext(ctx, &id, `SELECT nextval(pg_get_serial_sequence('orders', 'id'))`)
+	return id, err
+}
+
+// CreateOrder записывает заказ с ID, выделенным NextOrderID
 func (r *defaultOrderStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
-	err := querierExec(ctx, r.db).QueryRowxContext(ctx,
-		`INSERT INTO orders (payment_method, tax_price, shipping_price, total_price, user_id, status, created_at)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		order.PaymentMethod, order.TaxPrice, order.ShippingPrice,
-		order.TotalPrice, order.UserID, order.Status, order.CreatedAt,
-	).Scan(&order.ID)
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO orders (id, payment_method, discount_price, tax_price, shipping_price, total_price, user_id,
+		                     status, created_at, promo_code, shipping_address, billing_address, version)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
+		order.ID, order.PaymentMethod, order.DiscountPrice, order.TaxPrice, order.ShippingPrice,
+		order.TotalPrice, order.UserID, order.Status, order.CreatedAt, order.PromoCode,
+		order.ShippingAddress, order.BillingAddress, order.Version,
+	)
 	return err
 }
 
+// orderItemsBatchSize строк в одном INSERT: 8 параметров на строку, лимит Postgres — 65535 параметров
+const orderItemsBatchSize = 1000
+
+// CreateOrderItems вставляет позиции многострочными INSERT. ID берутся из последовательности
+// заранее одним запросом: порядок строк в RETURNING не гарантирован, а так каждой позиции
+// достаётся свой ID независимо от порядка вставки.
 func (r *defaultOrderStorage) CreateOrderItems(ctx context.Context, items []*domain.OrderItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	err := querierExec(ctx, r.db).SelectContext(ctx, &ids,
+		`SELECT nextval(pg_get_serial_sequence('order_items', 'id')) FROM generate_series(1, $1)`,
+		len(items),
+	)
+	if err != nil {
+		return err
+	}
+	if len(ids) != len(items) {
+		return fmt.Errorf("allocated %d order item ids for %d items", len(ids), len(items))
+	}
+
+	for start := 0; start < len(items); start += orderItemsBatchSize {
+		end := min(start+orderItemsBatchSize, len(items))
+		if err := r.insertOrderItems(ctx, items[start:end], ids[start:end]); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range items {
+		item.ID = ids[i]
+	}
+	return nil
+}
+
+func (r *defaultOrderStorage) insertOrderItems(ctx context.Context, items []*domain.OrderItem, ids []int64) error {
+	var query strings.Builder
+	query.WriteString(`INSERT INTO order_items (id, order_id, product_id, quantity, price, name, image, tax_rule) VALUES `)
+
+	args := make([]any, 0, len(items)*8)
 	for i, item := range items {
+		if i > 0 {
+			query.WriteString(",")
+		}
+		n := len(args)
+		fmt.Fprintf(&query, "($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+		args = append(args, ids[i], item.OrderID, item.ProductID, item.Quantity, item.Price, item.Name, item.Image, item.TaxRule)
+	}
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+func (r *defaultOrderStorage) GetOrder(ctx context.Context, id int64) (*domain.Order, error) {
+	order := &domain.Order{}
+	err := querierExec(ctx, r.db).GetContext(ctx, order,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadOrderItems(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (r *defaultOrderStorage) CreateOrderPriceLines(ctx context.Context, lines []*domain.OrderPriceLine) error {
+	for _, line := range lines {
 		err := querierExec(ctx, r.db).QueryRowxContext(ctx,
-			`INSERT INTO order_items (order_id, product_id, quantity, price, name, image) 
-			 VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
-			item.OrderID, item.ProductID, item.Quantity, item.Price, item.Name, item.Image,
-		).Scan(&items[i].ID)
+			`INSERT INTO order_price_lines (order_id, kind, rule, description, amount)
+			 VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+			line.OrderID, line.Kind, line.Rule, line.Description, line.Amount,
+		).Scan(&line.ID)
 		if err != nil {
 			return err
 		}
@@ -64,15 +153,155 @@ func (r *defaultOrderStorage) CreateOrderItems(ctx context.Context, items []*dom
 	return nil
 }
 
-func (r *defaultOrderStorage) GetOrder(ctx context.Context, id int64) (*domain.Order, error) {
+func (r *defaultOrderStorage) GetOrderPriceLines(ctx context.Context, orderID int64) ([]*domain.OrderPriceLine, error) {
+	var lines []*domain.OrderPriceLine
+	err := querierExec(ctx, r.db).SelectContext(ctx, &lines,
+		`SELECT id, order_id, kind, rule, description, amount
+		 FROM order_price_lines WHERE order_id = $1 ORDER BY id`,
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// GetOrderForUpdate блокирует строку заказа до конца транзакции
+func (r *defaultOrderStorage) GetOrderForUpdate(ctx context.Context, id int64) (*domain.Order, error) {
 	order := &domain.Order{}
-	err := r.db.GetContext(ctx, order,
-		`SELECT id, payment_method, tax_price, shipping_price, total_price, user_id, status, created_at, updated_at
-		 FROM orders WHERE id = $1`,
+	err := querierExec(ctx, r.db).GetContext(ctx, order,
+		`SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`,
 		id,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.loadOrderItems(ctx, order); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
+
+// UpdateOrderStatus записывает заказ, только если его версия не изменилась с чтения, и увеличивает
+// order.Version; sql.ErrNoRows — заказ успели изменить
+func (r *defaultOrderStorage) UpdateOrderStatus(ctx context.Context, order *domain.Order) error {
+	res, err := executor(ctx, r.db).ExecContext(ctx,
+		`UPDATE orders SET status = $1, updated_at = $2, cancellation_reason = $3, version = version + 1
+		 WHERE id = $4 AND version = $5`,
+		order.Status, order.UpdatedAt, order.CancellationReason, order.ID, order.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	order.Version++
+	return nil
+}
+
+func (r *defaultOrderStorage) CreateOrderStatusHistory(ctx context.Context, history *domain.OrderStatusHistory) error {
+	return querierExec(ctx, r.db).QueryRowxContext(ctx,
+		`INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, comment, changed_at)
+		 VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
+		history.OrderID, history.FromStatus, history.ToStatus, history.ChangedBy, history.Comment, history.ChangedAt,
+	).Scan(&history.ID)
+}
+
+func (r *defaultOrderStorage) ListOrders(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserID != nil {
+		conds = append(conds, "user_id = "+arg(*filter.UserID))
+	}
+	if filter.Status != "" {
+		conds = append(conds, "status = "+arg(filter.Status))
+	}
+	if filter.CreatedFrom != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conds = append(conds, "created_at < "+arg(*filter.CreatedTo))
+	}
+
+	// имя колонки подставляется в запрос, поэтому берём его только из белого списка
+	sortColumn := domain.OrderSortByCreatedAt
+	if filter.SortBy == domain.OrderSortByTotalPrice {
+		sortColumn = domain.OrderSortByTotalPrice
+	}
+
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	// keyset: (sort_column, id) строго после курсора; id разрешает равенство значений
+	if filter.After != nil {
+		var value any = filter.After.CreatedAt
+		if sortColumn == domain.OrderSortByTotalPrice {
+			value = filter.After.TotalPrice
+		}
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", sortColumn, cmp, arg(value), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", sortColumn, direction, direction, arg(filter.Limit))
+
+	var orders []*domain.Order
+	if err := querierExec(ctx, r.db).SelectContext(ctx, &orders, query, args...); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetOrdersWithItems то же, что ListOrders, но с позициями заказов:
+// позиции всей страницы загружаются одним дополнительным запросом
+func (r *defaultOrderStorage) GetOrdersWithItems(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error) {
+	orders, err := r.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadOrderItems(ctx, orders...); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// loadOrderItems заполняет Items у переданных заказов одним запросом по всем order_id
+func (r *defaultOrderStorage) loadOrderItems(ctx context.Context, orders ...*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(orders))
+	byID := make(map[int64]*domain.Order, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+		byID[order.ID] = order
+	}
+
+	var items []*domain.OrderItem
+	err := querierExec(ctx, r.db).SelectContext(ctx, &items,
+		`SELECT `+orderItemColumns+` FROM order_items WHERE order_id = ANY($1) ORDER BY order_id, id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		order := byID[item.OrderID]
+		order.Items = append(order.Items, item)
+	}
+	return nil
+}
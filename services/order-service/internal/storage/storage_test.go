@@ -0,0 +1,638 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/domain"
+	"github.com/defan6/market/services/order-service/internal/lib/money"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSNEnv база с применёнными миграциями из cmd/migrations; без неё тесты пропускаются
+const testDSNEnv = "ORDER_SERVICE_TEST_DSN"
+
+func newTestStorage(t testing.TB) (*defaultOrderStorage, *TxManager) {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewDefaultOrderStorage(db), NewTxManager(db, config.TxRetryConfig{MaxAttempts: 3})
+}
+
+func createTestOrder(t *testing.T, s *defaultOrderStorage, tx *TxManager, userID int64) *domain.Order {
+	t.Helper()
+
+	order := &domain.Order{
+		PaymentMethod: "card",
+		DiscountPrice: money.Zero(),
+		TaxPrice:      money.MustParse("1.20", money.DefaultCurrency),
+		ShippingPrice: money.MustParse("5.00", money.DefaultCurrency),
+		TotalPrice:    money.MustParse("36.20", money.DefaultCurrency),
+		UserID:        userID,
+		Status:        domain.OrderStatusPending,
+		CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+		Version:       1,
+		ShippingAddress: &domain.Address{
+			Recipient: "Test", Country: "RU", City: "Moscow", PostalCode: "101000", Line1: "Tverskaya 1",
+		},
+		Items: []*domain.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: money.MustParse("10.00", money.DefaultCurrency), Name: "Product-1", Image: "1.png", TaxRule: "vat"},
+			{ProductID: 5, Quantity: 1, Price: money.MustParse("10.00", money.DefaultCurrency), Name: "Product-5"},
+		},
+	}
+
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		id, err := s.NextOrderID(ctx)
+		if err != nil {
+			return err
+		}
+		order.ID = id
+
+		if err := s.CreateOrder(ctx, order); err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			item.OrderID = order.ID
+		}
+		return s.CreateOrderItems(ctx, order.Items)
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		s.db.Exec(`DELETE FROM orders WHERE id = $1`, order.ID)
+	})
+	return order
+}
+
+func TestGetOrder_RoundTripsItems(t *testing.T) {
+	s, tx := newTestStorage(t)
+	created := createTestOrder(t, s, tx, 900001)
+
+	got, err := s.GetOrder(context.Background(), created.ID)
+	require.NoError(t, err)
+
+	assert.True(t, created.CreatedAt.Equal(got.CreatedAt))
+	got.CreatedAt = created.CreatedAt
+	assert.Equal(t, created, got)
+}
+
+func TestGetOrdersWithItems(t *testing.T) {
+	s, tx := newTestStorage(t)
+	userID := int64(900002)
+	first := createTestOrder(t, s, tx, userID)
+	second := createTestOrder(t, s, tx, userID)
+
+	orders, err := s.GetOrdersWithItems(context.Background(), domain.OrderFilter{
+		UserID: &userID,
+		SortBy: domain.OrderSortByCreatedAt,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	assert.Equal(t, first.Items, orders[0].Items)
+	assert.Equal(t, second.Items, orders[1].Items)
+}
+
+func TestCreateOrderItems_AssignsIDsAcrossBatches(t *testing.T) {
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900003)
+
+	items := newTestOrderItems(order.ID, orderItemsBatchSize+1)
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		return s.CreateOrderItems(ctx, items)
+	})
+	require.NoError(t, err)
+
+	got, err := s.GetOrder(context.Background(), order.ID)
+	require.NoError(t, err)
+
+	byID := make(map[int64]*domain.OrderItem, len(got.Items))
+	for _, item := range got.Items {
+		byID[item.ID] = item
+	}
+	for _, item := range items {
+		require.Contains(t, byID, item.ID)
+		assert.Equal(t, item, byID[item.ID])
+	}
+}
+
+func TestGetOrderReturns_RoundTripsItems(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900004)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	ret := &domain.Return{
+		OrderID:        order.ID,
+		Status:         domain.ReturnStatusRequested,
+		Reason:         "damaged",
+		RequestedBy:    order.UserID,
+		ItemsAmount:    money.MustParse("10.00", money.DefaultCurrency),
+		TaxAmount:      money.MustParse("0.40", money.DefaultCurrency),
+		ShippingAmount: money.MustParse("0.00", money.DefaultCurrency),
+		TotalAmount:    money.MustParse("10.40", money.DefaultCurrency),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Items: []*domain.ReturnItem{
+			{OrderItemID: order.Items[0].ID, Quantity: 1, Amount: money.MustParse("10.00", money.DefaultCurrency)},
+		},
+	}
+	require.NoError(t, s.CreateReturn(ctx, ret))
+
+	returns, err := s.GetOrderReturns(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, returns, 1)
+
+	got := returns[0]
+	assert.True(t, now.Equal(got.CreatedAt))
+	got.CreatedAt, got.UpdatedAt = ret.CreatedAt, ret.UpdatedAt
+	assert.Equal(t, ret, got)
+}
+
+func TestRedeemPromoCode_CountsUses(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900005)
+
+	code := fmt.Sprintf("TEST-%d", order.ID)
+	_, err := s.db.Exec(
+		`INSERT INTO promo_codes (code, kind, rate, product_ids, max_uses_per_user) VALUES ($1, 'percent', 1000, '{1,5}', 1)`,
+		code,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		s.db.Exec(`DELETE FROM promo_redemptions WHERE order_id = $1`, order.ID)
+		s.db.Exec(`DELETE FROM promo_codes WHERE code = $1`, code)
+	})
+
+	promo, err := s.GetPromoCode(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, money.Percent(10), promo.Rate)
+	assert.Equal(t, []int64{1, 5}, promo.ProductIDs)
+	require.NotNil(t, promo.MaxUsesPerUser)
+	assert.Nil(t, promo.MaxUses)
+
+	err = tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		locked, err := s.GetPromoCodeForUpdate(ctx, code)
+		if err != nil {
+			return err
+		}
+		return s.RedeemPromoCode(ctx, &domain.PromoRedemption{
+			PromoCodeID: locked.ID,
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			Discount:    money.MustParse("2.00", money.DefaultCurrency),
+			CreatedAt:   time.Now(),
+		})
+	})
+	require.NoError(t, err)
+
+	promo, err = s.GetPromoCode(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), promo.Uses)
+
+	uses, err := s.CountPromoRedemptions(ctx, promo.ID, order.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), uses)
+}
+
+func newTestOrderItems(orderID int64, n int) []*domain.OrderItem {
+	items := make([]*domain.OrderItem, n)
+	for i := range items {
+		items[i] = &domain.OrderItem{
+			OrderID:   orderID,
+			ProductID: int64(i + 1),
+			Quantity:  int64(i%5 + 1),
+			Price:     money.New(int64(100*(i+1)), money.DefaultCurrency),
+			Name:      fmt.Sprintf("Product-%d", i+1),
+		}
+	}
+	return items
+}
+
+// createOrderItemsOneByOne прежняя вставка по одной строке, для сравнения в бенчмарке
+func (r *defaultOrderStorage) createOrderItemsOneByOne(ctx context.Context, items []*domain.OrderItem) error {
+	for _, item := range items {
+		err := querierExec(ctx, r.db).QueryRowxContext(ctx,
+			`INSERT INTO order_items (order_id, product_id, quantity, price, name, image)
+			 VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
+			item.OrderID, item.ProductID, item.Quantity, item.Price, item.Name, item.Image,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BenchmarkCreateOrderItems каждая итерация вставляет позиции в транзакции и откатывает её
+func BenchmarkCreateOrderItems(b *testing.B) {
+	s, _ := newTestStorage(b)
+
+	var orderID int64
+	err := s.db.QueryRowx(
+		`INSERT INTO orders (payment_method, user_id, status) VALUES ('card', 900004, 'pending') RETURNING id`,
+	).Scan(&orderID)
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		s.db.Exec(`DELETE FROM orders WHERE id = $1`, orderID)
+	})
+
+	inserts := map[string]func(ctx context.Context, items []*domain.OrderItem) error{
+		"loop":  s.createOrderItemsOneByOne,
+		"batch": s.CreateOrderItems,
+	}
+
+	for _, n := range []int{10, 100, 500} {
+		for _, name := range []string{"loop", "batch"} {
+			insert := inserts[name]
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				for b.Loop() {
+					items := newTestOrderItems(orderID, n)
+
+					tx, err := s.db.Beginx()
+					require.NoError(b, err)
+					ctx := context.WithValue(context.Background(), txKey{}, tx)
+
+					require.NoError(b, insert(ctx, items))
+					require.NoError(b, tx.Rollback())
+				}
+			})
+		}
+	}
+}
+
+func TestClearCart_KeepsItemsChangedAfterSnapshot(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStorage(t)
+
+	const userID = 900006
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM cart_items WHERE user_id = $1`, userID) })
+
+	snapshot := time.Now().UTC().Truncate(time.Microsecond)
+	for productID, updatedAt := range map[int64]time.Time{1: snapshot, 2: snapshot.Add(time.Second)} {
+		err := s.SaveCartItem(ctx, &domain.CartItem{
+			UserID:    userID,
+			ProductID: productID,
+			Quantity:  1,
+			Price:     money.MustParse("10.00", money.DefaultCurrency),
+			CreatedAt: snapshot,
+			UpdatedAt: updatedAt,
+		})
+		require.NoError(t, err)
+	}
+
+	// повторное сохранение заменяет количество
+	err := s.SaveCartItem(ctx, &domain.CartItem{
+		UserID: userID, ProductID: 1, Quantity: 3,
+		Price:     money.MustParse("10.00", money.DefaultCurrency),
+		CreatedAt: snapshot, UpdatedAt: snapshot,
+	})
+	require.NoError(t, err)
+
+	items, err := s.GetCartItems(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(3), items[0].Quantity)
+
+	require.NoError(t, s.ClearCart(ctx, userID, snapshot))
+
+	items, err = s.GetCartItems(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(2), items[0].ProductID)
+
+	assert.ErrorIs(t, s.DeleteCartItem(ctx, userID, 1), sql.ErrNoRows)
+}
+
+func TestAddresses_RoundTripAsSnapshot(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900007)
+
+	got, err := s.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.ShippingAddress, got.ShippingAddress)
+	assert.Nil(t, got.BillingAddress)
+
+	saved := &domain.SavedAddress{
+		UserID:    order.UserID,
+		Label:     "home",
+		Address:   *order.ShippingAddress,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.CreatedAt,
+	}
+	require.NoError(t, s.CreateAddress(ctx, saved))
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM addresses WHERE id = $1`, saved.ID) })
+
+	_, err = s.GetAddress(ctx, order.UserID+1, saved.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	saved.Address.City = "Tver"
+	require.NoError(t, s.UpdateAddress(ctx, saved))
+
+	addresses, err := s.GetUserAddresses(ctx, order.UserID)
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "Tver", addresses[0].Address.City)
+
+	got, err = s.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Moscow", got.ShippingAddress.City)
+
+	require.NoError(t, s.DeleteAddress(ctx, order.UserID, saved.ID))
+	assert.ErrorIs(t, s.DeleteAddress(ctx, order.UserID, saved.ID), sql.ErrNoRows)
+}
+
+func TestShipments_TrackingRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900008)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	shipment := &domain.Shipment{
+		OrderID:        order.ID,
+		Carrier:        "test",
+		TrackingNumber: fmt.Sprintf("test-%d", order.ID),
+		Status:         domain.ShipmentStatusLabelCreated,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Items: []*domain.ShipmentItem{
+			{OrderItemID: order.Items[0].ID, Quantity: 2},
+			{OrderItemID: order.Items[1].ID, Quantity: 1},
+		},
+	}
+	require.NoError(t, s.CreateShipment(ctx, shipment))
+
+	shipments, err := s.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	assert.Equal(t, shipment.Items, shipments[0].Items)
+
+	active, err := s.ListActiveShipments(ctx, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, shipmentIDs(active), shipment.ID)
+
+	_, err = shipment.Advance(domain.ShipmentStatusDelivered, now)
+	require.NoError(t, err)
+	shipment.TrackedAt = &now
+	require.NoError(t, s.UpdateShipmentTracking(ctx, shipment))
+
+	shipments, err = s.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ShipmentStatusDelivered, shipments[0].Status)
+	require.NotNil(t, shipments[0].DeliveredAt)
+	assert.True(t, now.Equal(*shipments[0].DeliveredAt))
+
+	active, err = s.ListActiveShipments(ctx, 1000)
+	require.NoError(t, err)
+	assert.NotContains(t, shipmentIDs(active), shipment.ID)
+}
+
+func TestShipments_PendingLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900012)
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond).Add(-time.Hour)
+	newPending := func() *domain.Shipment {
+		return &domain.Shipment{
+			OrderID:   order.ID,
+			Carrier:   "test",
+			Status:    domain.ShipmentStatusPending,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Items:     []*domain.ShipmentItem{{OrderItemID: order.Items[0].ID, Quantity: 1}},
+		}
+	}
+	// у отправлений без этикетки трек-номер пустой, и уникальность его не проверяется
+	labelled, discarded := newPending(), newPending()
+	require.NoError(t, s.CreateShipment(ctx, labelled))
+	require.NoError(t, s.CreateShipment(ctx, discarded))
+
+	active, err := s.ListActiveShipments(ctx, 1000)
+	require.NoError(t, err)
+	assert.NotContains(t, shipmentIDs(active), labelled.ID)
+
+	pending, err := s.ListPendingShipments(ctx, createdAt.Add(time.Second), 1000)
+	require.NoError(t, err)
+	assert.Subset(t, shipmentIDs(pending), []int64{labelled.ID, discarded.ID})
+	pending, err = s.ListPendingShipments(ctx, createdAt, 1000)
+	require.NoError(t, err)
+	assert.NotContains(t, shipmentIDs(pending), labelled.ID)
+
+	labelled.TrackingNumber = fmt.Sprintf("test-%d", order.ID)
+	labelled.LabelURL = "https://labels.invalid/" + labelled.TrackingNumber
+	labelled.Status = domain.ShipmentStatusLabelCreated
+	require.NoError(t, s.UpdateShipmentLabel(ctx, labelled))
+	require.NoError(t, s.DeleteShipment(ctx, discarded.ID))
+
+	shipments, err := s.GetOrderShipments(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	assert.Equal(t, labelled.TrackingNumber, shipments[0].TrackingNumber)
+	assert.Equal(t, domain.ShipmentStatusLabelCreated, shipments[0].Status)
+
+	active, err = s.ListActiveShipments(ctx, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, shipmentIDs(active), labelled.ID)
+}
+
+func shipmentIDs(shipments []*domain.Shipment) []int64 {
+	ids := make([]int64, 0, len(shipments))
+	for _, shipment := range shipments {
+		ids = append(ids, shipment.ID)
+	}
+	return ids
+}
+
+func TestUpdateOrderStatus_ConditionalOnVersion(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+	order := createTestOrder(t, s, tx, 900009)
+
+	stale := *order
+	order.Status = domain.OrderStatusProcessing
+	require.NoError(t, tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.UpdateOrderStatus(ctx, order)
+	}))
+	assert.Equal(t, int64(2), order.Version)
+
+	stale.Status = domain.OrderStatusCancelled
+	err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.UpdateOrderStatus(ctx, &stale)
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	got, err := s.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusProcessing, got.Status)
+	assert.Equal(t, int64(2), got.Version)
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	assert.True(t, isRetryableTxError(fmt.Errorf("failed to update order: %w", &pq.Error{Code: "40001"})))
+	assert.True(t, isRetryableTxError(&pq.Error{Code: "40P01"}))
+	assert.False(t, isRetryableTxError(&pq.Error{Code: "23505"}))
+	assert.False(t, isRetryableTxError(sql.ErrNoRows))
+}
+
+func TestCheckNestedOptions(t *testing.T) {
+	serializable := sql.TxOptions{Isolation: sql.LevelSerializable}
+	readOnly := sql.TxOptions{ReadOnly: true}
+
+	assert.NoError(t, checkNestedOptions(serializable, sql.TxOptions{}))
+	assert.NoError(t, checkNestedOptions(sql.TxOptions{}, sql.TxOptions{Isolation: sql.LevelReadCommitted}))
+	assert.NoError(t, checkNestedOptions(sql.TxOptions{}, readOnly))
+	assert.ErrorIs(t, checkNestedOptions(sql.TxOptions{}, serializable), ErrTxOptionsConflict)
+	assert.ErrorIs(t, checkNestedOptions(readOnly, sql.TxOptions{}), ErrTxOptionsConflict)
+}
+
+func TestWithinTransaction_RetriesSerializationFailure(t *testing.T) {
+	ctx := context.Background()
+	_, tx := newTestStorage(t)
+
+	attempts := 0
+	err := tx.WithinTransactionOptions(ctx, sql.TxOptions{Isolation: sql.LevelSerializable}, func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("failed to update order: %w", &pq.Error{Code: "40001"})
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// остальные ошибки не повторяются
+	attempts = 0
+	err = tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		attempts++
+		return sql.ErrNoRows
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestWithinTransaction_NestedCallUsesSavepoint(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+
+	const userID = 900010
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM cart_items WHERE user_id = $1`, userID) })
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	item := func(productID int64) *domain.CartItem {
+		return &domain.CartItem{
+			UserID: userID, ProductID: productID, Quantity: 1,
+			Price:     money.MustParse("10.00", money.DefaultCurrency),
+			CreatedAt: now, UpdatedAt: now,
+		}
+	}
+	errNested := errors.New("nested failed")
+
+	err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.SaveCartItem(ctx, item(1)); err != nil {
+			return err
+		}
+
+		err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := s.SaveCartItem(ctx, item(2)); err != nil {
+				return err
+			}
+			return errNested
+		})
+		assert.ErrorIs(t, err, errNested)
+
+		// вложенная транзакция не может быть строже внешней
+		err = tx.WithinTransactionOptions(ctx, sql.TxOptions{Isolation: sql.LevelSerializable}, func(ctx context.Context) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrTxOptionsConflict)
+
+		return tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			return s.SaveCartItem(ctx, item(3))
+		})
+	})
+	require.NoError(t, err)
+
+	items, err := s.GetCartItems(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(1), items[0].ProductID)
+	assert.Equal(t, int64(3), items[1].ProductID)
+}
+
+func TestWithinTransaction_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	s, tx := newTestStorage(t)
+
+	err := tx.WithinTransactionOptions(ctx, sql.TxOptions{ReadOnly: true}, func(ctx context.Context) error {
+		return s.ClearCart(ctx, 900011, time.Now())
+	})
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	assert.Equal(t, pq.ErrorCode("25006"), pqErr.Code) // read_only_sql_transaction
+}
+
+func TestFetchUnpublishedOutbox_RetryAndDead(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStorage(t)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	// первые два события одного агрегата, третье — другого
+	var messages []*domain.OutboxMessage
+	for _, aggregateID := range []int64{990001, 990001, 990002} {
+		msg := &domain.OutboxMessage{
+			AggregateType: domain.AggregateTypeOrder,
+			AggregateID:   aggregateID,
+			EventType:     domain.EventOrderCreated,
+			Payload:       []byte(`{}`),
+			CreatedAt:     now,
+		}
+		require.NoError(t, s.CreateOutboxMessage(ctx, msg))
+		messages = append(messages, msg)
+	}
+	t.Cleanup(func() {
+		s.db.Exec(`DELETE FROM outbox WHERE aggregate_id IN (990001, 990002)`)
+	})
+
+	fetched := func() []int64 {
+		got, err := s.FetchUnpublishedOutbox(ctx, 1000, now)
+		require.NoError(t, err)
+		var ids []int64
+		for _, msg := range got {
+			if msg.AggregateID == 990001 || msg.AggregateID == 990002 {
+				ids = append(ids, msg.ID)
+			}
+		}
+		return ids
+	}
+
+	// второе событие агрегата не выбирается, пока не опубликовано первое
+	assert.Equal(t, []int64{messages[0].ID, messages[2].ID}, fetched())
+
+	// первое событие ждёт повтора и держит второе событие своего агрегата
+	require.NoError(t, s.MarkOutboxFailed(ctx, messages[0].ID, "broker unavailable", now.Add(time.Hour)))
+	assert.Equal(t, []int64{messages[2].ID}, fetched())
+
+	require.NoError(t, s.MarkOutboxDead(ctx, messages[0].ID, "broker unavailable", now))
+	assert.Equal(t, []int64{messages[1].ID, messages[2].ID}, fetched())
+}
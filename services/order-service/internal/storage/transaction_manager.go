@@ -2,27 +2,80 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
+	"github.com/defan6/market/services/order-service/internal/config"
+	"github.com/defan6/market/services/order-service/internal/lib/resilience"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// коды ошибок Postgres, после которых транзакцию можно просто повторить
+const (
+	pqSerializationFailure pq.ErrorCode = "40001"
+	pqDeadlockDetected     pq.ErrorCode = "40P01"
+)
+
+// ErrTxOptionsConflict вложенный вызов требует больше, чем даёт уже открытая транзакция
+var ErrTxOptionsConflict = errors.New("transaction options conflict with the enclosing transaction")
+
+// TxManager открывает транзакции и кладёт их в контекст, откуда их берут методы хранилища.
+// Транзакция, прерванная Postgres из-за конфликта сериализации или взаимоблокировки,
+// повторяется целиком вместе с fn, поэтому fn может выполниться несколько раз.
 type TxManager struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	retry resilience.Retry
 }
 
-func NewTxManager(db *sqlx.DB) *TxManager {
-	return &TxManager{db: db}
+func NewTxManager(db *sqlx.DB, cfg config.TxRetryConfig) *TxManager {
+	return &TxManager{
+		db: db,
+		retry: resilience.Retry{
+			MaxAttempts: cfg.MaxAttempts,
+			BaseDelay:   cfg.BaseDelay,
+			MaxDelay:    cfg.MaxDelay,
+			Retryable:   isRetryableTxError,
+		},
+	}
 }
 
 type txKey struct{}
 
+// txState опции открытой транзакции и глубина вложенных вызовов
+type txState struct {
+	opts  sql.TxOptions
+	depth int
+}
+
+type txStateKey struct{}
+
 func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	tx, err := m.db.BeginTx(ctx, nil)
+	return m.WithinTransactionOptions(ctx, sql.TxOptions{}, fn)
+}
+
+// WithinTransactionOptions выполняет fn в транзакции с уровнем изоляции и режимом только чтения из opts.
+// Если в ctx уже есть транзакция, fn выполняется в ней под точкой сохранения: ошибка fn откатывает
+// только её изменения, а повтор при конфликте делает внешний вызов.
+func (m *TxManager) WithinTransactionOptions(ctx context.Context, opts sql.TxOptions, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txStateKey{}).(txState); ok {
+		return m.withinSavepoint(ctx, state, opts, fn)
+	}
+
+	return m.retry.Do(ctx, func(ctx context.Context) error {
+		return m.withinTransaction(ctx, opts, fn)
+	})
+}
+
+func (m *TxManager) withinTransaction(ctx context.Context, opts sql.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTxx(ctx, &opts)
 	if err != nil {
 		return err
 	}
 
 	ctx = context.WithValue(ctx, txKey{}, tx)
+	ctx = context.WithValue(ctx, txStateKey{}, txState{opts: opts})
 
 	defer func() {
 		if p := recover(); p != nil {
@@ -38,3 +91,66 @@ func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.C
 
 	return tx.Commit()
 }
+
+func (m *TxManager) withinSavepoint(ctx context.Context, state txState, opts sql.TxOptions, fn func(ctx context.Context) error) error {
+	if err := checkNestedOptions(state.opts, opts); err != nil {
+		return err
+	}
+
+	tx := ctx.Value(txKey{}).(*sqlx.Tx)
+	state.depth++
+	savepoint := fmt.Sprintf("sp_%d", state.depth)
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return err
+	}
+
+	ctx = context.WithValue(ctx, txStateKey{}, state)
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		// откат к точке сохранения возвращает транзакцию в рабочее состояние, даже если fn упала на ошибке SQL
+		if _, rbErr := tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// checkNestedOptions вложенный вызов не может требовать изоляцию строже внешней транзакции
+// или запись внутри транзакции только для чтения
+func checkNestedOptions(outer, nested sql.TxOptions) error {
+	if outer.ReadOnly && !nested.ReadOnly {
+		return fmt.Errorf("%w: read-write call inside read-only transaction", ErrTxOptionsConflict)
+	}
+	if isolationRank(nested.Isolation) > isolationRank(outer.Isolation) {
+		return fmt.Errorf("%w: %s requested inside %s transaction",
+			ErrTxOptionsConflict, nested.Isolation, outer.Isolation)
+	}
+	return nil
+}
+
+// isolationRank уровень по умолчанию в Postgres — read committed
+func isolationRank(level sql.IsolationLevel) sql.IsolationLevel {
+	if level == sql.LevelDefault {
+		return sql.LevelReadCommitted
+	}
+	return level
+}
+
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+}